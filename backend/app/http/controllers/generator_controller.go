@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"fmt"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/storage"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// generatorName is what a script line can refer a generator by
+var generatorName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// SetGenerators godoc
+// @Summary Replace the generators of a problem
+// @Description Set the test generators of a problem; each one is compiled to make sure it builds
+// @Tags Problems
+// @Accept json
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param generators body []models.Generator true "Generators"
+// @Success 200 {array} models.Generator
+// @Router /problems/{id}/generators [put]
+func SetGenerators(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.Preload("Generators").First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var generators []models.Generator
+	if err := c.BodyParser(&generators); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	current := map[string]models.Generator{}
+	for _, gen := range problem.Generators {
+		current[gen.Name] = gen
+	}
+
+	seen := map[string]bool{}
+	for i := range generators {
+		gen := &generators[i]
+		if !generatorName.MatchString(gen.Name) || seen[gen.Name] {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Generator name %q is invalid or duplicated", gen.Name)})
+		}
+		if gen.SourceCode == "" {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Generator %s has no source code", gen.Name)})
+		}
+		// Only recompile generators that changed
+		if old, ok := current[gen.Name]; !ok || old.SourceCode != gen.SourceCode || old.Language != gen.Language {
+			if err := validateJuryProgram(gen.SourceCode, gen.Language, "generator"); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Generator %s: %v", gen.Name, err)})
+			}
+		}
+		seen[gen.Name] = true
+		gen.ID = 0
+		gen.ProblemID = problem.ID
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", problem.ID).Delete(&models.Generator{}).Error; err != nil {
+			return err
+		}
+		if len(generators) > 0 {
+			if err := tx.Create(&generators).Error; err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, problem.ID, userID, "Generators updated")
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if generators == nil {
+		generators = []models.Generator{}
+	}
+	return c.JSON(generators)
+}
+
+// GenerateTests godoc
+// @Summary Generate tests with generators
+// @Description Run a script of generator commands, one test per line (e.g. `gen 100000 7`, `#` starts a comment).
+// @Description Inputs are checked by the validator and answers are produced by the author solution;
+// @Description all tests are added in one transaction.
+// @Tags Problems
+// @Accept json
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param request body object true "Script, sample flag and subtask"
+// @Success 200 {object} ImportReport
+// @Router /problems/{id}/testcases/generate [post]
+func GenerateTests(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.Preload("Generators").First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	type Request struct {
+		Script   string `json:"script"`
+		IsSample bool   `json:"is_sample"`
+		Subtask  int    `json:"subtask"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if err := validateSubtask(problem.ID, req.Subtask); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if problem.AuthorSourceCode == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Author solution is missing. Please save author solution first."})
+	}
+	if !compiler.IsSupported(problem.AuthorLanguage) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unsupported author language: " + problem.AuthorLanguage})
+	}
+
+	sources := map[string]models.Generator{}
+	for _, gen := range problem.Generators {
+		sources[gen.Name] = gen
+	}
+
+	var tests []*importedTest
+	for _, line := range strings.Split(req.Script, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, ok := sources[fields[0]]; !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "Unknown generator: " + fields[0]})
+		}
+		script := strings.Join(fields, " ")
+		tests = append(tests, &importedTest{Name: script, Script: script, isSample: req.IsSample, subtask: req.Subtask})
+	}
+	if len(tests) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Script contains no tests"})
+	}
+	if len(tests) > maxImportTests {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Script contains %d tests, at most %d are allowed", len(tests), maxImportTests)})
+	}
+
+	// Generated data that ends up unused is released again
+	defer releaseImportedData(tests)
+
+	if status, err := generateInputs(problem.Generators, tests); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	report := newImportReport()
+	if status, err := importTests(problem, tests, true, userID, "Tests generated", &report); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// generateInputs runs the script line of every test and stores the input it prints. Each
+// generator is compiled once, however many tests it makes. The status is the HTTP status of a failure.
+func generateInputs(generators []models.Generator, tests []*importedTest) (int, error) {
+	sources := map[string]models.Generator{}
+	for _, gen := range generators {
+		sources[gen.Name] = gen
+	}
+
+	compiled := map[string]*compiler.Generator{}
+	defer func() {
+		for _, gen := range compiled {
+			gen.Close()
+		}
+	}()
+
+	for _, test := range tests {
+		fields := strings.Fields(test.Script)
+		if len(fields) == 0 {
+			return 400, fmt.Errorf("Test %s has no generator command", test.Name)
+		}
+		gen, ok := compiled[fields[0]]
+		if !ok {
+			source, ok := sources[fields[0]]
+			if !ok {
+				return 400, fmt.Errorf("Unknown generator: %s", fields[0])
+			}
+			var err error
+			if gen, err = compiler.NewGenerator(source.SourceCode, source.Language); err != nil {
+				return 500, fmt.Errorf("Generator %s: %v", source.Name, err)
+			}
+			compiled[fields[0]] = gen
+		}
+
+		input, err := gen.Generate(fields[1:])
+		if err != nil {
+			return 400, fmt.Errorf("Test %s: %v", test.Name, err)
+		}
+		if test.inputObject, err = storage.PutString(input); err != nil {
+			return 500, err
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"onlineJudge/backend/services/compiler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetLanguages godoc
+// @Summary List supported languages
+// @Description Get the languages submissions can be written in
+// @Tags Languages
+// @Produce json
+// @Success 200 {array} compiler.Language
+// @Router /languages [get]
+func GetLanguages(c *fiber.Ctx) error {
+	return c.JSON(compiler.Languages())
+}
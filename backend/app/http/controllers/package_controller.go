@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/config"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/judge"
+	"onlineJudge/backend/services/problempkg"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ExportProblem godoc
+// @Summary Export a problem package
+// @Description Download the statement, limits, checker, jury programs, reference solutions, subtasks
+// @Description and all tests of a problem as a versioned ZIP package that ImportProblem accepts
+// @Tags Problems
+// @Produce application/zip
+// @Param id path int true "Problem ID"
+// @Success 200 {file} file
+// @Router /problems/{id}/package [get]
+func ExportProblem(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	byID := func(db *gorm.DB) *gorm.DB { return db.Order("id") }
+	var problem models.Problem
+	err := database.DB.Preload("TestCases", byID).Preload("Subtasks", byID).
+		Preload("Generators", byID).Preload("Solutions", byID).First(&problem, problemID).Error
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// Test data can be large, so the package is streamed as it is written
+	reader, writer := io.Pipe()
+	go func() {
+		err := problempkg.Export(writer, problem)
+		if err != nil {
+			log.Printf("Export of problem %d failed: %v", problem.ID, err)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="problem-%d.zip"`, problem.ID))
+	// Fiber closes the reader once it is sent, which stops the export if the client goes away
+	return c.SendStream(reader)
+}
+
+// ImportProblem godoc
+// @Summary Import a problem package
+// @Description Create a draft problem from a package exported by ExportProblem or from a Polygon package
+// @Description with problem.xml. Inputs missing from the package are made by its generators and missing
+// @Description answers by the author solution. With `dry_run` the package is only read, and the summary
+// @Description tells what would be created.
+// @Tags Problems
+// @Accept mpfd
+// @Produce json
+// @Param package formData file true "ZIP package"
+// @Param dry_run formData bool false "Only report what would be created"
+// @Success 200 {object} map[string]interface{}
+// @Router /problems/import [post]
+func ImportProblem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(float64)
+
+	header, err := c.FormFile("package")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Package is missing"})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ZIP archive: " + err.Error()})
+	}
+
+	pkg, err := problempkg.Read(archive)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid package: " + err.Error()})
+	}
+	if len(pkg.Tests) > maxImportTests {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Package contains %d tests, at most %d are allowed", len(pkg.Tests), maxImportTests)})
+	}
+
+	var unpacked uint64
+	for _, test := range pkg.Tests {
+		if test.Input != nil {
+			unpacked += test.Input.UncompressedSize64
+		}
+		if test.Output != nil {
+			unpacked += test.Output.UncompressedSize64
+		}
+	}
+	if unpacked > uint64(config.TestArchiveUnpackedLimit)*1024*1024 {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Package unpacks to more than %d MB", config.TestArchiveUnpackedLimit)})
+	}
+
+	problem := pkg.Problem
+	for _, gen := range problem.Generators {
+		if !generatorName.MatchString(gen.Name) {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Generator name %q is invalid", gen.Name)})
+		}
+	}
+	problem.Status = "draft"
+	problem.Visibility = "private"
+	problem.AuthorID = uint(userID)
+	if errs := validateProblemFields(&problem); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	interactive := problem.InteractorSourceCode != ""
+	needAnswers := false
+	for _, test := range pkg.Tests {
+		needAnswers = needAnswers || (test.Output == nil && !interactive)
+	}
+	if needAnswers && problem.AuthorSourceCode == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Package has tests without answers and no author solution to produce them"})
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.FormValue("dry_run")); dryRun {
+		return c.JSON(fiber.Map{"dry_run": true, "summary": pkg.Summary()})
+	}
+
+	// Jury programs are compiled only now: a dry run just reads the package
+	if errs := validateJuryPrograms(problem, models.Problem{}); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	for i := range problem.Subtasks {
+		if problem.Subtasks[i].Scoring == "" {
+			problem.Subtasks[i].Scoring = judge.ScoringAll
+		}
+	}
+
+	// Created with its subtasks, generators and solutions; a failure below deletes it again
+	if err := database.DB.Create(&problem).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to create problem"})
+	}
+
+	report := newImportReport()
+	change := "Problem imported from " + pkg.Format + " package"
+	if status, err := importPackageTests(problem, pkg.Tests, userID, change, &report); err != nil {
+		database.DB.Delete(&problem)
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"problem": problem, "summary": pkg.Summary(), "tests": report})
+}
+
+// importPackageTests stores the tests of a package, generating inputs and answers that it lacks,
+// and adds them to the newly created problem, recording its first revision
+func importPackageTests(problem models.Problem, packageTests []problempkg.Test, userID float64, change string, report *ImportReport) (int, error) {
+	interactive := problem.InteractorSourceCode != ""
+
+	tests := make([]*importedTest, 0, len(packageTests))
+	var generated []*importedTest
+	for _, pt := range packageTests {
+		test := &importedTest{Name: pt.Name, Script: pt.Script, Input: pt.Input, Output: pt.Output, isSample: pt.IsSample, subtask: pt.Subtask}
+		tests = append(tests, test)
+		if test.Input == nil {
+			generated = append(generated, test)
+		}
+	}
+
+	// Data is stored before the transaction; whatever ends up unused is released again
+	defer releaseImportedData(tests)
+
+	var err error
+	for _, test := range tests {
+		if test.Input != nil {
+			if test.inputObject, err = putZipFile(test.Input); err != nil {
+				return 500, fmt.Errorf("Test %s: %v", test.Name, err)
+			}
+		}
+		if test.Output != nil && !interactive {
+			if test.outputObject, err = putZipFile(test.Output); err != nil {
+				return 500, fmt.Errorf("Test %s: %v", test.Name, err)
+			}
+		}
+	}
+	if len(generated) > 0 {
+		if status, err := generateInputs(problem.Generators, generated); err != nil {
+			return status, err
+		}
+	}
+
+	// The author solution produces missing answers, and checks an interactive problem end to end
+	return importTests(problem, tests, problem.AuthorSourceCode != "", userID, change, report)
+}
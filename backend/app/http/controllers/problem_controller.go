@@ -3,14 +3,26 @@ package controllers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
 	"onlineJudge/backend/app/models"
 	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/comparator"
 	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/judge"
+	"onlineJudge/backend/services/storage"
+	"onlineJudge/backend/services/testgc"
+	"regexp"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 	"os"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 // Helper to get user ID from token (if present)
@@ -32,31 +44,30 @@ func getUserIDFromToken(c *fiber.Ctx) (float64, string) {
 
 // GetProblems godoc
 // @Summary Get all problems
-// @Description Retrieve a list of problems with filtering
+// @Description Retrieve a page of problems with filtering. Tags are comma-separated and a problem must
+// @Description have all of them; a difficulty range leaves out unrated problems; `solved` keeps the
+// @Description problems the current user has (true) or has not (false) solved.
 // @Tags Problems
 // @Produce json
 // @Param search query string false "Search by title"
 // @Param filter query string false "Filter: all, my, public, private"
-// @Success 200 {array} models.Problem
+// @Param tags query string false "Comma-separated tags, all required"
+// @Param difficulty_min query int false "Lowest difficulty"
+// @Param difficulty_max query int false "Highest difficulty"
+// @Param solved query bool false "Solved by the current user"
+// @Param sort query string false "Sort: newest (default), difficulty, solved"
+// @Param order query string false "Order: asc, desc; newest and solved default to desc, difficulty to asc"
+// @Param page query int false "Page, from 1"
+// @Param page_size query int false "Problems per page, at most 100"
+// @Success 200 {object} ProblemPage
+// @Failure 400 {object} ValidationErrorResponse
 // @Router /problems [get]
 func GetProblems(c *fiber.Ctx) error {
 	userID, role := getUserIDFromToken(c)
 	search := c.Query("search")
 	filter := c.Query("filter")
 
-	var problems []models.Problem
-	query := database.DB.Model(&models.Problem{})
-
-	// Base visibility logic
-	if role == "admin" {
-		// Admin sees everything
-	} else if userID > 0 {
-		// User sees public + own problems + shared with them
-		query = query.Where("visibility = 'public' OR author_id = ? OR id IN (SELECT problem_id FROM problem_accesses WHERE user_id = ?)", userID, userID)
-	} else {
-		// Guest sees only public
-		query = query.Where("visibility = 'public'")
-	}
+	query := visibleProblems(userID, role)
 
 	// Additional Filters
 	switch filter {
@@ -82,19 +93,202 @@ func GetProblems(c *fiber.Ctx) error {
 		query = query.Where("title ILIKE ?", "%"+search+"%")
 	}
 
-	query.Order("created_at desc").Find(&problems)
+	params, errs := parseProblemListParams(c)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
 
-	// Calculate SolvedCount
+	if len(params.tags) > 0 {
+		tags, _ := json.Marshal(params.tags)
+		query = query.Where("tags @> ?", string(tags))
+	}
+	if params.difficultyMin > 0 {
+		query = query.Where("difficulty >= ?", params.difficultyMin)
+	}
+	if params.difficultyMax > 0 {
+		query = query.Where("difficulty BETWEEN 1 AND ?", params.difficultyMax)
+	}
+
+	solvedBy := func(userID float64) *gorm.DB {
+		return database.DB.Model(&models.Submission{}).Select("problem_id").
+			Where("user_id = ? AND status = 'Accepted'", uint(userID))
+	}
+	if params.solved != nil {
+		if userID == 0 {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		if *params.solved {
+			query = query.Where("id IN (?)", solvedBy(userID))
+		} else {
+			query = query.Where("id NOT IN (?)", solvedBy(userID))
+		}
+	}
+
+	// The count and the page are separate queries on the same filters
+	query = query.Session(&gorm.Session{})
+	var total int64
+	query.Count(&total)
+
+	page := query
+	switch params.sort {
+	case "difficulty":
+		// Unrated problems come last either way
+		page = page.Order("difficulty = 0").Order("difficulty " + params.order)
+	case "solved":
+		page = page.Order("(SELECT COUNT(DISTINCT user_id) FROM submissions WHERE submissions.problem_id = problems.id AND status = 'Accepted') " + params.order)
+	}
+	// Ties, and the newest sort itself, go by creation time
+	createdOrder := "desc"
+	if params.sort == "newest" {
+		createdOrder = params.order
+	}
+	problems := []models.Problem{}
+	page.Order("created_at " + createdOrder).Order("id " + createdOrder).
+		Offset((params.page - 1) * params.pageSize).Limit(params.pageSize).Find(&problems)
+
+	// Solved counts of the whole page at once
+	ids := make([]uint, len(problems))
+	for i, p := range problems {
+		ids[i] = p.ID
+	}
+	var counts []struct {
+		ProblemID uint
+		Count     int64
+	}
+	database.DB.Model(&models.Submission{}).Select("problem_id, COUNT(DISTINCT user_id) AS count").
+		Where("problem_id IN ? AND status = 'Accepted'", ids).Group("problem_id").Scan(&counts)
+	var solved []uint
+	if userID > 0 {
+		solvedBy(userID).Where("problem_id IN ?", ids).Pluck("problem_id", &solved)
+	}
 	for i := range problems {
-		var count int64
-		database.DB.Model(&models.Submission{}).
-			Where("problem_id = ? AND status = 'Accepted'", problems[i].ID).
-			Distinct("user_id").
-			Count(&count)
-		problems[i].SolvedCount = count
+		for _, count := range counts {
+			if count.ProblemID == problems[i].ID {
+				problems[i].SolvedCount = count.Count
+			}
+		}
+		problems[i].Solved = slices.Contains(solved, problems[i].ID)
+	}
+
+	return c.JSON(ProblemPage{Problems: problems, Total: total, Page: params.page, PageSize: params.pageSize})
+}
+
+// visibleProblems selects the problems the user can see
+func visibleProblems(userID float64, role string) *gorm.DB {
+	query := database.DB.Model(&models.Problem{})
+	if role == "admin" {
+		// Admin sees everything
+	} else if userID > 0 {
+		// User sees public + own problems + shared with them
+		query = query.Where("visibility = 'public' OR author_id = ? OR id IN (SELECT problem_id FROM problem_accesses WHERE user_id = ?)", userID, userID)
+	} else {
+		// Guest sees only public
+		query = query.Where("visibility = 'public'")
 	}
+	return query
+}
+
+// TagCount is a tag with the number of problems that have it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// GetProblemTags godoc
+// @Summary List problem tags
+// @Description Tags of the problems visible to the user with how many problems have each, most used first
+// @Tags Problems
+// @Produce json
+// @Success 200 {array} TagCount
+// @Router /problems/tags [get]
+func GetProblemTags(c *fiber.Ctx) error {
+	userID, role := getUserIDFromToken(c)
+
+	tags := []TagCount{}
+	database.DB.Table("(?) AS visible", visibleProblems(userID, role).Select("tags")).
+		Select("tag, COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(visible.tags, '[]')) AS tag").
+		Group("tag").Order("count DESC, tag").Scan(&tags)
+	return c.JSON(tags)
+}
 
-	return c.JSON(problems)
+// ProblemPage is a page of the problem list with the number of problems matching the filters
+type ProblemPage struct {
+	Problems []models.Problem `json:"problems"`
+	Total    int64            `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+}
+
+const (
+	// defaultPageSize and maxPageSize bound the problems in a page of the list
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// problemListParams are the faceted filters, sorting and page of GetProblems
+type problemListParams struct {
+	tags                         []string
+	difficultyMin, difficultyMax int
+	solved                       *bool
+	sort, order                  string
+	page, pageSize               int
+}
+
+// parseProblemListParams reads the faceted filters, sorting and page of the problem list
+func parseProblemListParams(c *fiber.Ctx) (problemListParams, []FieldError) {
+	params := problemListParams{sort: c.Query("sort", "newest"), order: c.Query("order"), page: 1, pageSize: defaultPageSize}
+	var errs []FieldError
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	number := func(field string, value *int, min, max int) {
+		raw := c.Query(field)
+		if raw == "" {
+			return
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < min || n > max {
+			invalid(field, "must be a number between %d and %d", min, max)
+			return
+		}
+		*value = n
+	}
+
+	if tags := c.Query("tags"); tags != "" {
+		params.tags = normalizeTags(strings.Split(tags, ","))
+	}
+	number("difficulty_min", &params.difficultyMin, 0, maxDifficulty)
+	number("difficulty_max", &params.difficultyMax, 0, maxDifficulty)
+	if params.difficultyMax > 0 && params.difficultyMin > params.difficultyMax {
+		invalid("difficulty_min", "must not exceed difficulty_max")
+	}
+	if raw := c.Query("solved"); raw != "" {
+		solved, err := strconv.ParseBool(raw)
+		if err != nil {
+			invalid("solved", "must be true or false")
+		}
+		params.solved = &solved
+	}
+	number("page", &params.page, 1, math.MaxInt32)
+	number("page_size", &params.pageSize, 1, maxPageSize)
+
+	switch params.sort {
+	case "newest", "solved":
+		if params.order == "" {
+			params.order = "desc"
+		}
+	case "difficulty":
+		if params.order == "" {
+			params.order = "asc"
+		}
+	default:
+		invalid("sort", "must be one of newest, difficulty, solved")
+	}
+	if params.order != "" && params.order != "asc" && params.order != "desc" {
+		invalid("order", "must be asc or desc")
+	}
+	return params, errs
 }
 
 // GetProblem godoc
@@ -142,10 +336,37 @@ func GetProblem(c *fiber.Ctx) error {
 
 	// If user is author or admin, load ALL test cases
 	if problem.AuthorID == uint(userID) || role == "admin" {
-		database.DB.Preload("TestCases").First(&problem, id)
+		byID := func(db *gorm.DB) *gorm.DB { return db.Order("id") }
+		database.DB.Preload("TestCases", byID).Preload("Generators", byID).Preload("Solutions", byID).First(&problem, id)
+		fillTestPreviews(problem.TestCases, testPreviewLimit)
 	} else {
 		// Otherwise, load only SAMPLE test cases
-		database.DB.Preload("TestCases", "is_sample = ?", true).First(&problem, id)
+		database.DB.Preload("TestCases", func(db *gorm.DB) *gorm.DB { return db.Where("is_sample = ?", true).Order("id") }).First(&problem, id)
+		fillTestPreviews(problem.TestCases, samplePreviewLimit)
+		// Jury programs are for the author's eyes only
+		problem.AuthorSourceCode = ""
+		problem.CheckerSourceCode = ""
+		problem.InteractorSourceCode = ""
+		problem.ValidatorSourceCode = ""
+	}
+
+	// Scored problems also report the points available and the user's best result
+	database.DB.Where("problem_id = ?", problem.ID).Order("id").Find(&problem.Subtasks)
+	if len(problem.Subtasks) > 0 {
+		for _, st := range problem.Subtasks {
+			problem.MaxScore += st.Points
+		}
+		if userID > 0 {
+			// MAX is NULL when the user has not submitted, which leaves the best score unset
+			var best struct{ Score *float64 }
+			err := database.DB.Model(&models.Submission{}).Select("MAX(score) AS score").
+				Where("problem_id = ? AND user_id = ?", problem.ID, uint(userID)).
+				Scan(&best).Error
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": "Failed to load best score"})
+			}
+			problem.BestScore = best.Score
+		}
 	}
 
 	return c.JSON(problem)
@@ -159,7 +380,7 @@ func GetProblem(c *fiber.Ctx) error {
 // @Produce json
 // @Param problem body models.Problem true "Problem Data"
 // @Success 200 {object} models.Problem
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} ValidationErrorResponse
 // @Router /problems [post]
 func CreateProblem(c *fiber.Ctx) error {
 	problem := new(models.Problem)
@@ -170,23 +391,42 @@ func CreateProblem(c *fiber.Ctx) error {
 	// Set defaults
 	problem.Status = "draft"
 	problem.Visibility = "private"
+
+	if errs := validateProblemFields(problem); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if errs := validateJuryPrograms(*problem, models.Problem{}); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
 	// Get AuthorID from JWT (middleware)
 	userID := c.Locals("user_id").(float64)
 	problem.AuthorID = uint(userID)
 
-	database.DB.Create(&problem)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(problem).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, problem.ID, userID, "Problem created")
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to create problem"})
+	}
 	return c.JSON(problem)
 }
 
 // UpdateProblem godoc
 // @Summary Update a problem
-// @Description Update an existing problem
+// @Description Update the fields of a problem that are present in the request; the others are kept.
+// @Description Limits are range-checked and authors can only move a problem between draft and review;
+// @Description invalid fields are listed in `fields` of the 400 response.
 // @Tags Problems
 // @Accept json
 // @Produce json
 // @Param id path int true "Problem ID"
-// @Param problem body models.Problem true "Problem Data"
+// @Param problem body problemPatch true "Fields to change"
 // @Success 200 {object} models.Problem
+// @Failure 400 {object} ValidationErrorResponse
+// @Router /problems/{id} [patch]
 // @Router /problems/{id} [put]
 func UpdateProblem(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -203,33 +443,38 @@ func UpdateProblem(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
 	}
 
-	// Parse updates
-	type UpdateRequest struct {
-		Title            string  `json:"title"`
-		Description      string  `json:"description"`
-		TimeLimit        float64 `json:"time_limit"`
-		MemoryLimit      int     `json:"memory_limit"`
-		Visibility       string  `json:"visibility"`
-		Status           string  `json:"status"`
-		AuthorSourceCode string  `json:"author_source_code"`
-		AuthorLanguage   string  `json:"author_language"`
-	}
-	var req UpdateRequest
+	var req problemPatch
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	// Apply updates
-	problem.Title = req.Title
-	problem.Description = req.Description
-	problem.TimeLimit = req.TimeLimit
-	problem.MemoryLimit = req.MemoryLimit
-	problem.Visibility = req.Visibility
-	problem.Status = req.Status
-	problem.AuthorSourceCode = req.AuthorSourceCode
-	problem.AuthorLanguage = req.AuthorLanguage
+	old := problem
+	req.apply(&problem)
+	// Withdrawing a published problem takes it out of the public list as well
+	if old.Status == "published" && problem.Status != "published" && req.Visibility == nil {
+		problem.Visibility = "private"
+	}
 
-	database.DB.Save(&problem)
+	errs := validateProblemFields(&problem)
+	errs = append(errs, validateProblemTransition(old, problem, role)...)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	// Only jury programs that changed are recompiled, saving the form is frequent
+	if errs := validateJuryPrograms(problem, old); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&problem).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, problem.ID, userID, "Problem updated")
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update problem"})
+	}
 	return c.JSON(problem)
 }
 
@@ -255,7 +500,17 @@ func DeleteProblem(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
 	}
 
+	// Test cases and revisions go with the problem, their data has to be released separately
+	var testCases []models.TestCase
+	database.DB.Where("problem_id = ?", problem.ID).Find(&testCases)
+	var revisionTests []models.TestCase
+	database.DB.Model(&models.RevisionTest{}).Distinct("input_hash", "output_hash").
+		Where("revision_id IN (?)", database.DB.Model(&models.ProblemRevision{}).Select("id").Where("problem_id = ?", problem.ID)).
+		Find(&revisionTests)
+	testCases = append(testCases, revisionTests...)
+
 	database.DB.Delete(&problem)
+	releaseTestData(testCases...)
 	return c.JSON(fiber.Map{"message": "Problem deleted"})
 }
 
@@ -288,58 +543,83 @@ func AddTestCase(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
+	if err := validateSubtask(problem.ID, testCase.Subtask); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	// Check for duplicates
 	var count int64
 	database.DB.Model(&models.TestCase{}).
-		Where("problem_id = ? AND input = ?", problem.ID, testCase.Input).
+		Where("problem_id = ? AND input_hash = ?", problem.ID, storage.Hash(testCase.Input)).
 		Count(&count)
 
 	if count > 0 {
 		return c.Status(409).JSON(fiber.Map{"error": "Duplicate test case (input already exists)"})
 	}
 
+	// The input must satisfy the constraints before anything is generated from it
+	validator, err := newValidator(problem)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if validator != nil {
+		status, err := validateInput(validator, strings.NewReader(testCase.Input))
+		validator.Close()
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
 	// Auto-generate Output using Author's Solution
 	if problem.AuthorSourceCode == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Author solution is missing. Please save author solution first."})
 	}
-
-	langID := 0
-	switch problem.AuthorLanguage {
-	case "python":
-		langID = 71
-	case "cpp":
-		langID = 54
-	case "java":
-		langID = 62
-	case "go":
-		langID = 60
-	case "javascript":
-		langID = 63
-	default:
-		langID = 71
+	if !compiler.IsSupported(problem.AuthorLanguage) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unsupported author language: " + problem.AuthorLanguage})
 	}
 
 	compSubmission := compiler.CompilerSubmission{
 		SourceCode:  problem.AuthorSourceCode,
-		LanguageID:  langID,
+		Language:    problem.AuthorLanguage,
 		Stdin:       testCase.Input,
 		TimeLimit:   5.0,
-		MemoryLimit: 256,
+		MemoryLimit: problem.MemoryLimit,
+		OutputLimit: problem.OutputLimit,
+	}
+
+	// Interactive tests have no expected output: the author solution only has to pass the interactor
+	if problem.InteractorSourceCode != "" {
+		result, check, err := compiler.ExecuteInteractive(compSubmission, problem.InteractorSourceCode, problem.InteractorLanguage)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Execution failed: " + err.Error()})
+		}
+		if result.Outcome != compiler.OutcomeOK {
+			return c.Status(400).JSON(fiber.Map{"error": "Author solution " + describeFailure(result)})
+		}
+		if check.Verdict != compiler.CheckOK {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Interactor rejected author solution (%s): %s", check.Verdict, check.Message)})
+		}
+
+		testCase.ProblemID = problem.ID
+		if err := storeTestCase(testCase, testCase.Input, "", userID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(testCase)
 	}
 
 	result, err := compiler.ExecuteCode(compSubmission)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Execution failed: " + err.Error()})
 	}
-	if result.Stderr != "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Author solution Runtime Error: " + result.Stderr})
+	if result.Outcome != compiler.OutcomeOK {
+		return c.Status(400).JSON(fiber.Map{"error": "Author solution " + describeFailure(result)})
 	}
 
 	// Set the generated output
-	testCase.ExpectedOutput = strings.TrimSpace(result.Stdout)
 	testCase.ProblemID = problem.ID
-
-	database.DB.Create(&testCase)
+	if err := storeTestCase(testCase, testCase.Input, strings.TrimSpace(result.Stdout), userID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
 	return c.JSON(testCase)
 }
 
@@ -366,10 +646,132 @@ func DeleteTestCase(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
 	}
 
-	database.DB.Delete(&models.TestCase{}, testCaseID)
+	var testCase models.TestCase
+	if err := database.DB.Where("problem_id = ?", problem.ID).First(&testCase, testCaseID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Test case not found"})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&testCase).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, problem.ID, userID, fmt.Sprintf("Test %d deleted", testCase.ID))
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete test case"})
+	}
+	releaseTestData(testCase)
 	return c.JSON(fiber.Map{"message": "Test case deleted"})
 }
 
+// GetTestData godoc
+// @Summary Download test data
+// @Description Stream the full input or expected output of a test case (author and admin only)
+// @Tags Problems
+// @Produce plain
+// @Param id path int true "Problem ID"
+// @Param testcase_id path int true "Test Case ID"
+// @Param kind path string true "input or output"
+// @Success 200 {string} string
+// @Router /problems/{id}/testcases/{testcase_id}/{kind} [get]
+func GetTestData(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	testCaseID := c.Params("testcase_id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var testCase models.TestCase
+	if err := database.DB.Where("problem_id = ?", problem.ID).First(&testCase, testCaseID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Test case not found"})
+	}
+
+	hash, size := testCase.InputHash, testCase.InputSize
+	switch c.Params("kind") {
+	case "input":
+	case "output":
+		hash, size = testCase.OutputHash, testCase.OutputSize
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown test data: " + c.Params("kind")})
+	}
+
+	reader, err := storage.Open(hash)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	// Fiber closes the reader once it is sent
+	return c.SendStream(reader, int(size))
+}
+
+// UpdateTestCase godoc
+// @Summary Update a test case
+// @Description Change the subtask and sample flag of a test case (input and output stay as generated)
+// @Tags Problems
+// @Accept json
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param testcase_id path int true "Test Case ID"
+// @Param testcase body models.TestCase true "Subtask and sample flag"
+// @Success 200 {object} models.TestCase
+// @Router /problems/{id}/testcases/{testcase_id} [put]
+func UpdateTestCase(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	testCaseID := c.Params("testcase_id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var testCase models.TestCase
+	if err := database.DB.Where("problem_id = ?", problem.ID).First(&testCase, testCaseID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Test case not found"})
+	}
+
+	type UpdateRequest struct {
+		IsSample bool `json:"is_sample"`
+		Subtask  int  `json:"subtask"`
+	}
+	var req UpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if err := validateSubtask(problem.ID, req.Subtask); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	testCase.IsSample = req.IsSample
+	testCase.Subtask = req.Subtask
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&testCase).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, problem.ID, userID, fmt.Sprintf("Test %d updated", testCase.ID))
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update test case"})
+	}
+
+	updated := []models.TestCase{testCase}
+	fillTestPreviews(updated, testPreviewLimit)
+	return c.JSON(updated[0])
+}
+
 // GenerateOutput godoc
 // @Summary Generate output for a test case
 // @Description Run author's code against input to generate output
@@ -390,23 +792,13 @@ func GenerateOutput(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	langID := 0
-	switch req.Language {
-	case "python":
-		langID = 71
-	case "cpp":
-		langID = 54
-	case "java":
-		langID = 62
-	case "go":
-		langID = 60
-	case "javascript":
-		langID = 63
+	if !compiler.IsSupported(req.Language) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unsupported language: " + req.Language})
 	}
 
 	compSubmission := compiler.CompilerSubmission{
 		SourceCode:  req.SourceCode,
-		LanguageID:  langID,
+		Language:    req.Language,
 		Stdin:       req.Input,
 		TimeLimit:   5.0, // Default limit for generation
 		MemoryLimit: 256,
@@ -416,11 +808,339 @@ func GenerateOutput(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Execution failed: " + err.Error()})
 	}
-	if result.Stderr != "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Runtime Error: " + result.Stderr})
+	if result.Outcome != compiler.OutcomeOK {
+		return c.Status(400).JSON(fiber.Map{"error": describeFailure(result)})
+	}
+
+	// This is only a preview for the author; tests store the full output of AddTestCase
+	return c.JSON(fiber.Map{"output": compiler.Preview(result.Stdout, outputPreviewLimit)})
+}
+
+// validateJuryProgram makes sure an uploaded checker, interactor, validator or generator compiles;
+// empty source means none
+func validateJuryProgram(source, language, role string) error {
+	if source == "" {
+		return nil
+	}
+	if !compiler.IsSupported(language) {
+		return fmt.Errorf("Unsupported %s language: %s", role, language)
+	}
+
+	var program interface{ Close() }
+	var err error
+	switch role {
+	case "interactor":
+		program, err = compiler.NewInteractor(source, language)
+	case "validator":
+		program, err = compiler.NewValidator(source, language)
+	case "generator":
+		program, err = compiler.NewGenerator(source, language)
+	default:
+		program, err = compiler.NewChecker(source, language)
+	}
+	if err != nil {
+		return err
+	}
+	program.Close()
+	return nil
+}
+
+const (
+	// outputPreviewLimit caps program output echoed back in responses
+	outputPreviewLimit = 16 * 1024
+	// maxOutputLimit is the largest output limit of a problem, in MB
+	maxOutputLimit = 256
+	// Accepted range of the memory limit of a problem, in MB; the time limit's is judge.MinTimeLimit to judge.MaxTimeLimit
+	minMemoryLimit = 16
+	maxMemoryLimit = 2048
+	// maxTitleLength is the longest problem title, in characters
+	maxTitleLength = 200
+	// A problem has at most maxTags tags of up to maxTagLength characters
+	maxTags      = 10
+	maxTagLength = 32
+	// Accepted range of the difficulty rating, 0 leaves a problem unrated
+	minDifficulty = 800
+	maxDifficulty = 3500
+	// testPreviewLimit and samplePreviewLimit cap test data shown to authors and contestants
+	testPreviewLimit   = 1024
+	samplePreviewLimit = 64 * 1024
+)
+
+// problemPatch is the body of UpdateProblem: only the fields present in the request are changed
+type problemPatch struct {
+	Title            *string  `json:"title"`
+	Description      *string  `json:"description"`
+	TimeLimit        *float64 `json:"time_limit"`
+	MemoryLimit      *int     `json:"memory_limit"`
+	OutputLimit      *int     `json:"output_limit"`
+	Visibility       *string  `json:"visibility"`
+	Status           *string  `json:"status"`
+	AuthorSourceCode *string  `json:"author_source_code"`
+	AuthorLanguage   *string  `json:"author_language"`
+
+	CheckerMode       *string  `json:"checker_mode"`
+	CheckerEpsilon    *float64 `json:"checker_epsilon"`
+	CheckerSourceCode *string  `json:"checker_source_code"`
+	CheckerLanguage   *string  `json:"checker_language"`
+
+	InteractorSourceCode *string `json:"interactor_source_code"`
+	InteractorLanguage   *string `json:"interactor_language"`
+
+	ValidatorSourceCode *string `json:"validator_source_code"`
+	ValidatorLanguage   *string `json:"validator_language"`
+
+	Tags       *[]string `json:"tags"`
+	Difficulty *int      `json:"difficulty"`
+}
+
+// apply copies the fields present in the patch onto the problem
+func (p problemPatch) apply(problem *models.Problem) {
+	patchField(&problem.Title, p.Title)
+	patchField(&problem.Description, p.Description)
+	patchField(&problem.TimeLimit, p.TimeLimit)
+	patchField(&problem.MemoryLimit, p.MemoryLimit)
+	patchField(&problem.OutputLimit, p.OutputLimit)
+	patchField(&problem.Visibility, p.Visibility)
+	patchField(&problem.Status, p.Status)
+	patchField(&problem.AuthorSourceCode, p.AuthorSourceCode)
+	patchField(&problem.AuthorLanguage, p.AuthorLanguage)
+	patchField(&problem.CheckerMode, p.CheckerMode)
+	if problem.CheckerMode == "" {
+		problem.CheckerMode = string(comparator.DefaultMode)
+	}
+	patchField(&problem.CheckerEpsilon, p.CheckerEpsilon)
+	patchField(&problem.CheckerSourceCode, p.CheckerSourceCode)
+	patchField(&problem.CheckerLanguage, p.CheckerLanguage)
+	patchField(&problem.InteractorSourceCode, p.InteractorSourceCode)
+	patchField(&problem.InteractorLanguage, p.InteractorLanguage)
+	patchField(&problem.ValidatorSourceCode, p.ValidatorSourceCode)
+	patchField(&problem.ValidatorLanguage, p.ValidatorLanguage)
+	patchField(&problem.Tags, p.Tags)
+	patchField(&problem.Difficulty, p.Difficulty)
+}
+
+func patchField[T any](field *T, value *T) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+// FieldError is an invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is the body of a 400 response listing every invalid field
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// validationFailed responds with the invalid fields; error also describes them for clients
+// that only show the message
+func validationFailed(c *fiber.Ctx, errs []FieldError) error {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Field+": "+e.Message)
+	}
+	return c.Status(400).JSON(ValidationErrorResponse{Error: strings.Join(messages, "; "), Fields: errs})
+}
+
+// problemStatuses and problemVisibilities are the values a problem can have
+var (
+	problemStatuses     = []string{"draft", "pending_review", "published", "rejected"}
+	problemVisibilities = []string{"private", "public"}
+)
+
+// authorTransitions are the status changes open to authors; publishing and rejecting
+// is up to moderators, see ApproveProblem and RejectProblem
+var authorTransitions = map[string][]string{
+	"draft":          {"pending_review"},
+	"pending_review": {"draft"},
+	"rejected":       {"draft", "pending_review"},
+	"published":      {"draft"},
+}
+
+// tagPattern is a tag after normalization: lowercase words separated by single spaces or hyphens
+var tagPattern = regexp.MustCompile(`^[\p{Ll}\p{Nd}]+([ -][\p{Ll}\p{Nd}]+)*$`)
+
+// normalizeTags lowercases and trims tags, and sorts them without duplicates
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+		if tag != "" {
+			normalized = append(normalized, tag)
+		}
 	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// validateProblemFields checks the statement, limits and settings of a problem, and normalizes its tags
+func validateProblemFields(problem *models.Problem) []FieldError {
+	var errs []FieldError
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(problem.Title) == "" {
+		invalid("title", "must not be empty")
+	} else if utf8.RuneCountInString(problem.Title) > maxTitleLength {
+		invalid("title", "must be at most %d characters", maxTitleLength)
+	}
+	if problem.TimeLimit < judge.MinTimeLimit || problem.TimeLimit > judge.MaxTimeLimit {
+		invalid("time_limit", "must be between %g and %g seconds", judge.MinTimeLimit, judge.MaxTimeLimit)
+	}
+	if problem.MemoryLimit < minMemoryLimit || problem.MemoryLimit > maxMemoryLimit {
+		invalid("memory_limit", "must be between %d and %d MB", minMemoryLimit, maxMemoryLimit)
+	}
+	// Zero on creation means the default
+	if problem.OutputLimit < 0 || problem.OutputLimit > maxOutputLimit {
+		invalid("output_limit", "must be between 1 and %d MB", maxOutputLimit)
+	}
+	if problem.AuthorLanguage != "" && !compiler.IsSupported(problem.AuthorLanguage) {
+		invalid("author_language", "unsupported language %q", problem.AuthorLanguage)
+	}
+	if !comparator.Valid(comparator.Mode(problem.CheckerMode)) {
+		invalid("checker_mode", "unknown mode %q", problem.CheckerMode)
+	}
+	if problem.CheckerEpsilon < 0 {
+		invalid("checker_epsilon", "must not be negative")
+	}
+	if !slices.Contains(problemStatuses, problem.Status) {
+		invalid("status", "must be one of %s", strings.Join(problemStatuses, ", "))
+	}
+	if !slices.Contains(problemVisibilities, problem.Visibility) {
+		invalid("visibility", "must be one of %s", strings.Join(problemVisibilities, ", "))
+	}
+	problem.Tags = normalizeTags(problem.Tags)
+	if len(problem.Tags) > maxTags {
+		invalid("tags", "at most %d tags are allowed", maxTags)
+	}
+	for _, tag := range problem.Tags {
+		if utf8.RuneCountInString(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			invalid("tags", "tag %q must be up to %d letters and digits, words separated by spaces or hyphens", tag, maxTagLength)
+		}
+	}
+	if problem.Difficulty != 0 && (problem.Difficulty < minDifficulty || problem.Difficulty > maxDifficulty) {
+		invalid("difficulty", "must be between %d and %d, or 0 for unrated", minDifficulty, maxDifficulty)
+	}
+	return errs
+}
 
-	return c.JSON(fiber.Map{"output": result.Stdout})
+// validateProblemTransition checks that the role may change the status and visibility
+// of a problem from old to problem; admins may set any
+func validateProblemTransition(old, problem models.Problem, role string) []FieldError {
+	if role == "admin" {
+		return nil
+	}
+	var errs []FieldError
+	if problem.Status != old.Status && !slices.Contains(authorTransitions[old.Status], problem.Status) {
+		errs = append(errs, FieldError{Field: "status", Message: fmt.Sprintf("cannot change from %s to %s", old.Status, problem.Status)})
+	}
+	if problem.Visibility != old.Visibility {
+		switch {
+		case problem.Visibility == "public":
+			errs = append(errs, FieldError{Field: "visibility", Message: "only moderation makes a problem public"})
+		case problem.Status == "published":
+			errs = append(errs, FieldError{Field: "visibility", Message: "a published problem stays public, withdraw it to draft first"})
+		}
+	}
+	return errs
+}
+
+// validateJuryPrograms compiles the checker, interactor and validator of a problem that differ from old
+func validateJuryPrograms(problem, old models.Problem) []FieldError {
+	programs := []struct {
+		role                   string
+		source, language       string
+		oldSource, oldLanguage string
+	}{
+		{"checker", problem.CheckerSourceCode, problem.CheckerLanguage, old.CheckerSourceCode, old.CheckerLanguage},
+		{"interactor", problem.InteractorSourceCode, problem.InteractorLanguage, old.InteractorSourceCode, old.InteractorLanguage},
+		{"validator", problem.ValidatorSourceCode, problem.ValidatorLanguage, old.ValidatorSourceCode, old.ValidatorLanguage},
+	}
+
+	var errs []FieldError
+	for _, p := range programs {
+		if p.source == p.oldSource && p.language == p.oldLanguage {
+			continue
+		}
+		if err := validateJuryProgram(p.source, p.language, p.role); err != nil {
+			errs = append(errs, FieldError{Field: p.role + "_source_code", Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// storeTestCase puts the data of a new test case into the test storage and creates it with a revision
+func storeTestCase(testCase *models.TestCase, input, output string, userID float64) error {
+	inputObject, err := storage.PutString(input)
+	if err != nil {
+		return err
+	}
+	outputObject := storage.Object{}
+	if output != "" {
+		if outputObject, err = storage.PutString(output); err != nil {
+			return err
+		}
+	}
+
+	testCase.InputHash, testCase.InputSize = inputObject.Hash, inputObject.Size
+	testCase.OutputHash, testCase.OutputSize = outputObject.Hash, outputObject.Size
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(testCase).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, testCase.ProblemID, userID, fmt.Sprintf("Test %d added", testCase.ID))
+	})
+	if err != nil {
+		testgc.Release(inputObject.Hash, outputObject.Hash)
+		return err
+	}
+
+	testCase.Input = compiler.Preview(input, testPreviewLimit)
+	testCase.ExpectedOutput = compiler.Preview(output, testPreviewLimit)
+	return nil
+}
+
+// releaseTestData hands the stored data of removed test cases to the collector,
+// which deletes it later unless another test or a revision still uses it
+func releaseTestData(testCases ...models.TestCase) {
+	hashes := make([]string, 0, len(testCases)*2)
+	for _, tc := range testCases {
+		hashes = append(hashes, tc.InputHash, tc.OutputHash)
+	}
+	testgc.Release(hashes...)
+}
+
+// fillTestPreviews reads the start of the test data for responses
+func fillTestPreviews(testCases []models.TestCase, limit int64) {
+	for i := range testCases {
+		tc := &testCases[i]
+		tc.Input, _ = storage.Preview(tc.InputHash, limit)
+		tc.ExpectedOutput, _ = storage.Preview(tc.OutputHash, limit)
+	}
+}
+
+// describeFailure explains a failed author run; authors see their own diagnostics
+func describeFailure(result compiler.ExecutionResult) string {
+	switch result.Outcome {
+	case compiler.OutcomeCompilationError:
+		return "Compilation Error: " + compiler.Preview(result.Compile.Output, outputPreviewLimit)
+	case compiler.OutcomeTimeLimit:
+		return "Time Limit Exceeded"
+	case compiler.OutcomeMemoryLimit:
+		return "Memory Limit Exceeded"
+	case compiler.OutcomeOutputLimit:
+		return "Output Limit Exceeded"
+	case compiler.OutcomeRuntimeError:
+		return fmt.Sprintf("Runtime Error (exit code %d): %s", result.ExitCode, compiler.Preview(result.Stderr, outputPreviewLimit))
+	default:
+		return "System Error: " + compiler.Preview(result.Stderr, outputPreviewLimit)
+	}
 }
 
 // ShareProblem godoc
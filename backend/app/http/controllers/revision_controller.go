@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"fmt"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/revision"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// recordRevision takes a revision of a problem in the transaction of a change, so that a
+// change is never saved without its revision or credited to whoever changes the problem next
+func recordRevision(tx *gorm.DB, problemID uint, userID float64, change string) error {
+	_, err := revision.Record(tx, problemID, uint(userID), change)
+	return err
+}
+
+// loadRevision loads a revision of a problem by its number, with its tests
+func loadRevision(problemID uint, number string) (models.ProblemRevision, error) {
+	var rev models.ProblemRevision
+	err := database.DB.Preload("Tests", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("problem_id = ? AND number = ?", problemID, number).First(&rev).Error
+	return rev, err
+}
+
+// GetRevisions godoc
+// @Summary List revisions of a problem
+// @Description Revisions of a problem, newest first, without their statements, programs and tests
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Success 200 {array} models.ProblemRevision
+// @Router /problems/{id}/revisions [get]
+func GetRevisions(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	revisions := []models.ProblemRevision{}
+	database.DB.Select("id", "problem_id", "number", "user_id", "change", "created_at", "title", "time_limit", "memory_limit").
+		Where("problem_id = ?", problem.ID).Order("number DESC").Find(&revisions)
+	return c.JSON(revisions)
+}
+
+// GetRevision godoc
+// @Summary Get a revision of a problem
+// @Description The full state of a problem in a revision, with its tests
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} models.ProblemRevision
+// @Router /problems/{id}/revisions/{number} [get]
+func GetRevision(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	rev, err := loadRevision(problem.ID, c.Params("number"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Revision not found"})
+	}
+	return c.JSON(rev)
+}
+
+// DiffRevisions godoc
+// @Summary Compare revisions of a problem
+// @Description Changed fields, and added, removed and changed tests from revision `against`
+// @Description (the previous one by default) to the given revision
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param number path int true "Revision number"
+// @Param against query int false "Revision to compare with"
+// @Success 200 {object} revision.Diff
+// @Router /problems/{id}/revisions/{number}/diff [get]
+func DiffRevisions(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	to, err := loadRevision(problem.ID, c.Params("number"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Revision not found"})
+	}
+
+	against := c.Query("against", strconv.Itoa(to.Number-1))
+	// The first revision is compared with an empty problem
+	from := models.ProblemRevision{Subtasks: []models.Subtask{}}
+	if against != "0" {
+		if from, err = loadRevision(problem.ID, against); err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Revision " + against + " not found"})
+		}
+	}
+	return c.JSON(revision.Compare(from, to))
+}
+
+// RollbackRevision godoc
+// @Summary Roll a problem back to a revision
+// @Description Restore the statement, limits, programs, subtasks and tests of a revision.
+// @Description The rollback is recorded as a new revision, so it can be undone in turn.
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} models.ProblemRevision
+// @Router /problems/{id}/revisions/{number}/rollback [post]
+func RollbackRevision(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	target, err := loadRevision(problem.ID, c.Params("number"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Revision not found"})
+	}
+
+	var latest models.ProblemRevision
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := revision.Restore(tx, target); err != nil {
+			return err
+		}
+		latest, err = revision.Record(tx, problem.ID, uint(userID), fmt.Sprintf("Rolled back to revision %d", target.Number))
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Rollback failed: " + err.Error()})
+	}
+	return c.JSON(latest)
+}
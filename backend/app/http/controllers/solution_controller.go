@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"fmt"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/judge"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// maxInvocationsListed is how many recent invocations of a problem are listed
+const maxInvocationsListed = 20
+
+// SetSolutions godoc
+// @Summary Replace the reference solutions of a problem
+// @Description Set the reference solutions, each tagged with its expected verdict
+// @Description (accepted, wrong_answer, time_limit, memory_limit, runtime_error, time_or_accepted, rejected).
+// @Description The author solution is the main correct one and is not part of the list.
+// @Tags Problems
+// @Accept json
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param solutions body []models.ProblemSolution true "Solutions"
+// @Success 200 {array} models.ProblemSolution
+// @Router /problems/{id}/solutions [put]
+func SetSolutions(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var solutions []models.ProblemSolution
+	if err := c.BodyParser(&solutions); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	// Wrong solutions may well fail to compile, so they are only checked when invoked
+	for i := range solutions {
+		sol := &solutions[i]
+		if sol.Name == "" {
+			sol.Name = fmt.Sprintf("solution %d", i+1)
+		}
+		if !compiler.IsSupported(sol.Language) {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Solution %s: unsupported language: %s", sol.Name, sol.Language)})
+		}
+		if !judge.ValidTag(sol.Tag) {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Solution %s: unknown tag %q", sol.Name, sol.Tag)})
+		}
+		sol.ID = 0
+		sol.ProblemID = problem.ID
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", problem.ID).Delete(&models.ProblemSolution{}).Error; err != nil {
+			return err
+		}
+		if len(solutions) > 0 {
+			if err := tx.Create(&solutions).Error; err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, problem.ID, userID, "Reference solutions updated")
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if solutions == nil {
+		solutions = []models.ProblemSolution{}
+	}
+	return c.JSON(solutions)
+}
+
+// InvokeSolutions godoc
+// @Summary Invoke the reference solutions
+// @Description Queue a run of the author solution and all reference solutions on all tests.
+// @Description Poll the returned invocation for the matrix of verdicts and times.
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Success 200 {object} models.Invocation
+// @Router /problems/{id}/invocations [post]
+func InvokeSolutions(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var tests, solutions int64
+	database.DB.Model(&models.TestCase{}).Where("problem_id = ?", problem.ID).Count(&tests)
+	database.DB.Model(&models.ProblemSolution{}).Where("problem_id = ?", problem.ID).Count(&solutions)
+	if tests == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Problem has no tests"})
+	}
+	if solutions == 0 && problem.AuthorSourceCode == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Problem has no solutions"})
+	}
+
+	invocation := models.Invocation{
+		ProblemID: problem.ID,
+		UserID:    uint(userID),
+		Status:    judge.JobQueued,
+		TimeLimit: problem.TimeLimit,
+	}
+	if err := queueInvocation(&invocation); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to queue invocation"})
+	}
+
+	return c.JSON(invocation)
+}
+
+// CalibrateTimeLimit godoc
+// @Summary Calibrate the time limit
+// @Description Queue a calibration: the author solution and the solutions tagged accepted run `runs` times
+// @Description on every test with a generous limit, and `factor` times the slowest run, adjusted by the
+// @Description language multipliers, is proposed as the time limit. With `apply` it is also set on the problem.
+// @Tags Problems
+// @Accept json
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param request body object false "Runs (default 3), factor (default 2) and apply"
+// @Success 200 {object} models.Invocation
+// @Router /problems/{id}/calibrations [post]
+func CalibrateTimeLimit(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	type Request struct {
+		Runs   int     `json:"runs"`
+		Factor float64 `json:"factor"`
+		Apply  bool    `json:"apply"`
+	}
+	req := Request{Runs: judge.DefaultCalibrationRuns, Factor: judge.DefaultCalibrationFactor}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	}
+	if req.Runs < 1 || req.Runs > judge.MaxCalibrationRuns {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Runs must be between 1 and %d", judge.MaxCalibrationRuns)})
+	}
+	if req.Factor < 1 || req.Factor > judge.MaxCalibrationFactor {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Factor must be between 1 and %g", judge.MaxCalibrationFactor)})
+	}
+
+	var tests, solutions int64
+	database.DB.Model(&models.TestCase{}).Where("problem_id = ?", problem.ID).Count(&tests)
+	database.DB.Model(&models.ProblemSolution{}).Where("problem_id = ? AND tag = ?", problem.ID, judge.TagAccepted).Count(&solutions)
+	if tests == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Problem has no tests"})
+	}
+	if solutions == 0 && problem.AuthorSourceCode == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Problem has no correct solutions"})
+	}
+
+	invocation := models.Invocation{
+		ProblemID: problem.ID,
+		UserID:    uint(userID),
+		Status:    judge.JobQueued,
+		TimeLimit: problem.TimeLimit,
+		Runs:      req.Runs,
+		Factor:    req.Factor,
+		Apply:     req.Apply,
+	}
+	if err := queueInvocation(&invocation); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to queue calibration"})
+	}
+
+	return c.JSON(invocation)
+}
+
+// queueInvocation creates the invocation together with its judge job
+func queueInvocation(invocation *models.Invocation) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invocation).Error; err != nil {
+			return err
+		}
+		return judge.EnqueueInvocation(tx, invocation.ID)
+	})
+	if err == nil {
+		judge.Notify()
+	}
+	return err
+}
+
+// GetInvocations godoc
+// @Summary List invocations
+// @Description Recent invocations and calibrations of the reference solutions of a problem, newest first
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Success 200 {array} models.Invocation
+// @Router /problems/{id}/invocations [get]
+func GetInvocations(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var invocations []models.Invocation
+	database.DB.Where("problem_id = ?", problem.ID).Order("id desc").Limit(maxInvocationsListed).Find(&invocations)
+	return c.JSON(invocations)
+}
+
+// GetInvocation godoc
+// @Summary Get an invocation
+// @Description The matrix of verdicts of an invocation, filled in as solutions finish
+// @Tags Problems
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param invocation_id path int true "Invocation ID"
+// @Success 200 {object} models.Invocation
+// @Router /problems/{id}/invocations/{invocation_id} [get]
+func GetInvocation(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var invocation models.Invocation
+	if err := database.DB.Where("problem_id = ?", problem.ID).First(&invocation, c.Params("invocation_id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Invocation not found"})
+	}
+	return c.JSON(invocation)
+}
@@ -1,14 +1,17 @@
 package controllers
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"onlineJudge/backend/app/models"
 	"onlineJudge/backend/database"
 	"onlineJudge/backend/services/compiler"
-	"strings"
+	"onlineJudge/backend/services/judge"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type SubmitRequest struct {
@@ -20,12 +23,12 @@ type SubmitRequest struct {
 
 // SubmitSolution godoc
 // @Summary Submit a solution
-// @Description Submit code for a problem
+// @Description Submit code for a problem. The submission is queued and judged asynchronously.
 // @Tags Submissions
 // @Accept json
 // @Produce json
 // @Param submission body SubmitRequest true "Submission Data"
-// @Success 200 {object} models.Submission
+// @Success 202 {object} models.Submission
 // @Router /submit [post]
 func SubmitSolution(c *fiber.Ctx) error {
 	var req SubmitRequest
@@ -35,9 +38,13 @@ func SubmitSolution(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(float64)
 
-	// 1. Get Problem and Test Cases
+	if !compiler.IsSupported(req.Language) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unsupported language: " + req.Language})
+	}
+
+	// 1. Get Problem
 	var problem models.Problem
-	if err := database.DB.Preload("TestCases").First(&problem, req.ProblemID).Error; err != nil {
+	if err := database.DB.First(&problem, req.ProblemID).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
 	}
 
@@ -76,7 +83,7 @@ func SubmitSolution(c *fiber.Ctx) error {
 		}
 	}
 
-	// 3. Create Submission Record
+	// 3. Create Submission Record and queue it for judging
 	submission := models.Submission{
 		UserID:     uint(userID),
 		ProblemID:  req.ProblemID,
@@ -85,80 +92,19 @@ func SubmitSolution(c *fiber.Ctx) error {
 		SourceCode: req.SourceCode,
 		Status:     "Pending",
 	}
-	database.DB.Create(&submission)
-
-	// 4. Run Tests (Sync for simplicity)
-	langID := 0
-	switch req.Language {
-	case "python":
-		langID = 71
-	case "cpp":
-		langID = 54
-	case "java":
-		langID = 62
-	case "go":
-		langID = 60
-	case "javascript":
-		langID = 63
-	}
-
-	compSubmission := compiler.CompilerSubmission{
-		SourceCode:  req.SourceCode,
-		LanguageID:  langID,
-		TimeLimit:   problem.TimeLimit,
-		MemoryLimit: problem.MemoryLimit,
-	}
-
-	finalStatus := "Accepted"
-	totalTime := ""
-
-	for i, tc := range problem.TestCases {
-		compSubmission.Stdin = tc.Input
-		result, err := compiler.ExecuteCode(compSubmission)
-
-		status := "Accepted"
-		userOutput := strings.TrimSpace(result.Stdout)
-		expectedOutput := strings.TrimSpace(tc.ExpectedOutput)
-
-		if err != nil {
-			status = "System Error"
-		} else if result.Stderr != "" {
-			status = "Runtime Error"
-		} else if userOutput != expectedOutput {
-			status = "Wrong Answer"
-			// Debug Log
-			fmt.Printf("❌ Test #%d Failed:\nInput: %q\nExpected: %q\nGot: %q\n", i+1, tc.Input, expectedOutput, userOutput)
-		}
-
-		// Save Detail
-		database.DB.Create(&models.SubmissionDetail{
-			SubmissionID:  submission.ID,
-			TestCaseID:    tc.ID,
-			Status:        status,
-			ExecutionTime: result.ExecutionTime,
-			IsSample:      tc.IsSample,
-		})
-
-		// Update total time (take the max or sum, usually max for parallel, sum for serial)
-		totalTime = result.ExecutionTime
-
-		if status != "Accepted" {
-			finalStatus = status
-			break
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&submission).Error; err != nil {
+			return err
 		}
+		return judge.Enqueue(tx, submission.ID)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to queue submission"})
 	}
+	judge.Notify()
 
-	// Update Submission
-	submission.Status = finalStatus
-	submission.ExecutionTime = totalTime
-	database.DB.Save(&submission)
-
-	// Return result with details
-	var details []models.SubmissionDetail
-	database.DB.Where("submission_id = ?", submission.ID).Find(&details)
-	submission.Details = details
-
-	return c.JSON(submission)
+	// Judging happens in the worker pool; clients follow GET /submission/:id
+	return c.Status(202).JSON(submission)
 }
 
 // GetHistory godoc
@@ -195,21 +141,161 @@ func GetHistory(c *fiber.Ctx) error {
 func GetSubmission(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
 
 	var submission models.Submission
 	if err := database.DB.Preload("Details").First(&submission, id).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Submission not found"})
 	}
 
-	// Check access (Owner or Solved)
-	if submission.UserID != uint(userID) {
-		// Check if user has solved this problem
-		var count int64
-		database.DB.Model(&models.Submission{}).Where("user_id = ? AND problem_id = ? AND status = 'Accepted'", userID, submission.ProblemID).Count(&count)
-		if count == 0 {
-			return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
-		}
+	if !canViewSubmission(userID, submission) {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	if !canSeeDiagnostics(userID, role, submission) {
+		hideDiagnostics(&submission)
 	}
 
 	return c.JSON(submission)
 }
+
+// canSeeDiagnostics checks whether compiler output and stderr may be shown (Owner, Problem Author or Admin)
+func canSeeDiagnostics(userID float64, role string, submission models.Submission) bool {
+	if submission.UserID == uint(userID) || role == "admin" {
+		return true
+	}
+
+	var count int64
+	database.DB.Model(&models.Problem{}).Where("id = ? AND author_id = ?", submission.ProblemID, userID).Count(&count)
+	return count > 0
+}
+
+// hideDiagnostics strips program output that may leak test data or another user's debugging
+func hideDiagnostics(submission *models.Submission) {
+	submission.CompileOutput = ""
+	for i := range submission.Details {
+		submission.Details[i].Stderr = ""
+		submission.Details[i].CheckerMessage = ""
+	}
+}
+
+// canViewSubmission checks access to a submission (Owner or Solved)
+func canViewSubmission(userID float64, submission models.Submission) bool {
+	if submission.UserID == uint(userID) {
+		return true
+	}
+
+	// Check if user has solved this problem
+	var count int64
+	database.DB.Model(&models.Submission{}).Where("user_id = ? AND problem_id = ? AND status = 'Accepted'", userID, submission.ProblemID).Count(&count)
+	return count > 0
+}
+
+// StreamSubmission godoc
+// @Summary Stream submission progress
+// @Description Server-Sent Events stream of judging progress. The first event is a snapshot of the submission, followed by status, compiling, running, detail and final events.
+// @Tags Submissions
+// @Produce text/event-stream
+// @Param id path int true "Submission ID"
+// @Success 200 {string} string "event stream"
+// @Router /submission/{id}/stream [get]
+func StreamSubmission(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var submission models.Submission
+	if err := database.DB.First(&submission, id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Submission not found"})
+	}
+
+	if !canViewSubmission(userID, submission) {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// Subscribe before taking the snapshot so no event falls in between
+	events, unsubscribe := judge.Subscribe(submission.ID)
+
+	database.DB.Preload("Details").First(&submission, submission.ID)
+
+	showDiagnostics := canSeeDiagnostics(userID, role, submission)
+	if !showDiagnostics {
+		hideDiagnostics(&submission)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		if err := writeEvent(w, "snapshot", submission); err != nil {
+			return
+		}
+		if judge.IsFinal(submission.Status) {
+			writeEvent(w, judge.EventFinal, judge.Event{
+				Type:          judge.EventFinal,
+				SubmissionID:  submission.ID,
+				Status:        submission.Status,
+				ExecutionTime: submission.ExecutionTime,
+			})
+			return
+		}
+
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event := <-events:
+				if event.Detail != nil && !showDiagnostics {
+					detail := *event.Detail
+					detail.Stderr = ""
+					detail.CheckerMessage = ""
+					event.Detail = &detail
+				}
+				if err := writeEvent(w, event.Type, event); err != nil {
+					return
+				}
+				if event.Type == judge.EventFinal {
+					return
+				}
+			case <-ticker.C:
+				// Keep-alive; also catches submissions judged by another backend instance
+				var current models.Submission
+				database.DB.Select("id", "status", "execution_time").First(&current, submission.ID)
+				if judge.IsFinal(current.Status) {
+					writeEvent(w, judge.EventFinal, judge.Event{
+						Type:          judge.EventFinal,
+						SubmissionID:  current.ID,
+						Status:        current.Status,
+						ExecutionTime: current.ExecutionTime,
+					})
+					return
+				}
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeEvent writes a single SSE message and flushes it to the client
+func writeEvent(w *bufio.Writer, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"fmt"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/judge"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// SetSubtasks godoc
+// @Summary Replace the subtasks of a problem
+// @Description Set the subtasks of an IOI-style problem; an empty list makes it a regular problem again
+// @Tags Problems
+// @Accept json
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param subtasks body []models.Subtask true "Subtasks"
+// @Success 200 {array} models.Subtask
+// @Router /problems/{id}/subtasks [put]
+func SetSubtasks(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var subtasks []models.Subtask
+	if err := c.BodyParser(&subtasks); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	// Stored in index order, so readers can simply order by id
+	sort.Slice(subtasks, func(i, j int) bool { return subtasks[i].Index < subtasks[j].Index })
+
+	seen := map[int]bool{}
+	for i := range subtasks {
+		st := &subtasks[i]
+		if st.Index < 1 || seen[st.Index] {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Subtask index %d is invalid or duplicated", st.Index)})
+		}
+		if st.Points < 0 {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Subtask %d has negative points", st.Index)})
+		}
+		if st.Scoring == "" {
+			st.Scoring = judge.ScoringAll
+		}
+		if st.Scoring != judge.ScoringAll && st.Scoring != judge.ScoringMin && st.Scoring != judge.ScoringAvg {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Subtask %d has unknown scoring %q", st.Index, st.Scoring)})
+		}
+		// Only earlier subtasks can be dependencies, which also rules out cycles
+		for _, dep := range st.Dependencies {
+			if dep >= st.Index || !seen[dep] {
+				return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Subtask %d cannot depend on subtask %d", st.Index, dep)})
+			}
+		}
+		seen[st.Index] = true
+		st.ID = 0
+		st.ProblemID = problem.ID
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", problem.ID).Delete(&models.Subtask{}).Error; err != nil {
+			return err
+		}
+		// Tests of removed subtasks are no longer scored
+		removed := tx.Model(&models.TestCase{}).Where("problem_id = ? AND subtask > 0", problem.ID)
+		if len(seen) > 0 {
+			indexes := make([]int, 0, len(seen))
+			for index := range seen {
+				indexes = append(indexes, index)
+			}
+			removed = removed.Where("subtask NOT IN ?", indexes)
+		}
+		if err := removed.Update("subtask", 0).Error; err != nil {
+			return err
+		}
+		if len(subtasks) > 0 {
+			if err := tx.Create(&subtasks).Error; err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, problem.ID, userID, "Subtasks updated")
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to save subtasks"})
+	}
+
+	if subtasks == nil {
+		subtasks = []models.Subtask{}
+	}
+	return c.JSON(subtasks)
+}
+
+// validateSubtask checks that a test case refers to an existing subtask, 0 meaning none
+func validateSubtask(problemID uint, index int) error {
+	if index == 0 {
+		return nil
+	}
+
+	var count int64
+	database.DB.Model(&models.Subtask{}).
+		Where("problem_id = ? AND index = ?", problemID, index).
+		Count(&count)
+	if count == 0 {
+		return fmt.Errorf("Subtask %d does not exist", index)
+	}
+	return nil
+}
@@ -0,0 +1,494 @@
+package controllers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/config"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/storage"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// maxImportTests caps the number of tests imported at once
+const maxImportTests = 1000
+
+// importedTest is a test being imported from an archive or a generator script
+type importedTest struct {
+	Name   string    // Archive path without the extension, e.g. "tests/01", or the script line
+	Script string    // Generator command line of a generated test
+	Input  *zip.File // Archive files; nil for generated tests
+	Output *zip.File
+
+	inputObject  storage.Object
+	outputObject storage.Object
+	isSample     bool
+	subtask      int
+}
+
+// ImportEntry is one line of an import report
+type ImportEntry struct {
+	Name       string `json:"name"`
+	TestCaseID uint   `json:"test_case_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// ImportReport tells what an import did with each test
+type ImportReport struct {
+	Added     []ImportEntry     `json:"added"`
+	Replaced  []ImportEntry     `json:"replaced"`
+	Skipped   []ImportEntry     `json:"skipped"`
+	TestCases []models.TestCase `json:"test_cases"` // All tests of the problem after the import
+}
+
+// ImportTestArchive godoc
+// @Summary Import tests from a ZIP archive
+// @Description Add tests from `01.in`/`01.out`, `01`/`01.a` or `input1.txt`/`output1.txt` pairs in one transaction.
+// @Description Tests named `sample*` or listed in `samples` become samples. With `generate_outputs`
+// @Description the answers are produced by the author solution and answer files are not needed.
+// @Description A test whose input already exists gets the new answer and is reported as replaced.
+// @Tags Problems
+// @Accept mpfd
+// @Produce json
+// @Param id path int true "Problem ID"
+// @Param archive formData file true "ZIP archive with tests"
+// @Param samples formData string false "Comma-separated names of sample tests, e.g. 01,02"
+// @Param generate_outputs formData bool false "Generate answers with the author solution"
+// @Param subtask formData int false "Subtask of the added tests"
+// @Success 200 {object} ImportReport
+// @Router /problems/{id}/testcases/archive [post]
+func ImportTestArchive(c *fiber.Ctx) error {
+	problemID := c.Params("id")
+	userID := c.Locals("user_id").(float64)
+	role := c.Locals("role").(string)
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, problemID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Problem not found"})
+	}
+
+	if problem.AuthorID != uint(userID) && role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	interactive := problem.InteractorSourceCode != ""
+	generate, _ := strconv.ParseBool(c.FormValue("generate_outputs"))
+	if generate && problem.AuthorSourceCode == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Author solution is missing. Please save author solution first."})
+	}
+	if generate && !compiler.IsSupported(problem.AuthorLanguage) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unsupported author language: " + problem.AuthorLanguage})
+	}
+
+	subtask := 0
+	if value := c.FormValue("subtask"); value != "" {
+		var err error
+		if subtask, err = strconv.Atoi(value); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid subtask"})
+		}
+	}
+	if err := validateSubtask(problem.ID, subtask); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	header, err := c.FormFile("archive")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Archive is missing"})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ZIP archive: " + err.Error()})
+	}
+
+	report := newImportReport()
+
+	// Answers are only needed when they are neither generated nor replaced by an interactor
+	tests, errs := pairArchiveTests(archive, !generate && !interactive, &report)
+	if len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid archive: " + strings.Join(errs, "; ")})
+	}
+	if len(tests) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Archive contains no tests"})
+	}
+	if len(tests) > maxImportTests {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Archive contains %d tests, at most %d are allowed", len(tests), maxImportTests)})
+	}
+
+	// archive/zip refuses entries longer than declared, so the declared sizes bound what is unpacked
+	var unpacked uint64
+	for _, test := range tests {
+		unpacked += test.Input.UncompressedSize64
+		if test.Output != nil {
+			unpacked += test.Output.UncompressedSize64
+		}
+	}
+	if unpacked > uint64(config.TestArchiveUnpackedLimit)*1024*1024 {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Archive unpacks to more than %d MB", config.TestArchiveUnpackedLimit)})
+	}
+
+	samples := map[string]bool{}
+	for _, name := range strings.Split(c.FormValue("samples"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			samples[name] = true
+		}
+	}
+	for _, test := range tests {
+		base := path.Base(test.Name)
+		test.isSample = samples[test.Name] || samples[base] || strings.HasPrefix(strings.ToLower(base), "sample")
+		test.subtask = subtask
+	}
+
+	// Data is stored before the transaction; whatever ends up unused is released again
+	defer releaseImportedData(tests)
+
+	for _, test := range tests {
+		if test.inputObject, err = putZipFile(test.Input); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Test %s: %v", test.Name, err)})
+		}
+		if test.Output != nil && !generate && !interactive {
+			if test.outputObject, err = putZipFile(test.Output); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Test %s: %v", test.Name, err)})
+			}
+		}
+	}
+
+	if status, err := importTests(problem, tests, generate, userID, "Tests imported from archive", &report); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// newImportReport makes a report whose lists encode as empty arrays rather than null
+func newImportReport() ImportReport {
+	return ImportReport{Added: []ImportEntry{}, Replaced: []ImportEntry{}, Skipped: []ImportEntry{}}
+}
+
+// importTests takes tests whose inputs are stored, checks them with the validator, produces the
+// missing answers with the author solution if asked, and creates the tests in one transaction that also
+// records the change as a revision. A test whose input already exists gets the new answer. The status is
+// the HTTP status of a failure; the caller releases the stored data of tests that were not created.
+func importTests(problem models.Problem, tests []*importedTest, generate bool, userID float64, change string, report *ImportReport) (int, error) {
+	if status, err := validateInputs(problem, tests); err != nil {
+		return status, err
+	}
+	if generate {
+		var missing []*importedTest
+		for _, test := range tests {
+			if test.outputObject.Hash == "" {
+				missing = append(missing, test)
+			}
+		}
+		if len(missing) > 0 {
+			if status, err := runAuthorOnTests(problem, missing); err != nil {
+				return status, err
+			}
+		}
+	}
+
+	var replaced []models.TestCase
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		seen := map[string]string{}
+		for _, test := range tests {
+			if first, ok := seen[test.inputObject.Hash]; ok {
+				report.Skipped = append(report.Skipped, ImportEntry{Name: test.Name, Reason: "same input as " + first})
+				continue
+			}
+			seen[test.inputObject.Hash] = test.Name
+
+			var existing models.TestCase
+			err := tx.Where("problem_id = ? AND input_hash = ?", problem.ID, test.inputObject.Hash).First(&existing).Error
+			if err == gorm.ErrRecordNotFound {
+				testCase := models.TestCase{
+					ProblemID:  problem.ID,
+					InputHash:  test.inputObject.Hash,
+					InputSize:  test.inputObject.Size,
+					OutputHash: test.outputObject.Hash,
+					OutputSize: test.outputObject.Size,
+					IsSample:   test.isSample,
+					Subtask:    test.subtask,
+					Script:     test.Script,
+				}
+				if err := tx.Create(&testCase).Error; err != nil {
+					return err
+				}
+				report.Added = append(report.Added, ImportEntry{Name: test.Name, TestCaseID: testCase.ID})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			// Existing tests keep their subtask; samples stay samples
+			if existing.OutputHash == test.outputObject.Hash && (existing.IsSample || !test.isSample) {
+				report.Skipped = append(report.Skipped, ImportEntry{Name: test.Name, TestCaseID: existing.ID, Reason: "unchanged"})
+				continue
+			}
+			replaced = append(replaced, existing)
+			existing.OutputHash, existing.OutputSize = test.outputObject.Hash, test.outputObject.Size
+			existing.IsSample = existing.IsSample || test.isSample
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			report.Replaced = append(report.Replaced, ImportEntry{Name: test.Name, TestCaseID: existing.ID})
+		}
+		return recordRevision(tx, problem.ID, userID, change)
+	})
+	if err != nil {
+		return 500, fmt.Errorf("Import failed: %v", err)
+	}
+
+	// Answers that were replaced may no longer be used
+	releaseTestData(replaced...)
+
+	database.DB.Where("problem_id = ?", problem.ID).Order("id").Find(&report.TestCases)
+	fillTestPreviews(report.TestCases, testPreviewLimit)
+	return 0, nil
+}
+
+// validateInputs runs the problem's validator, if any, over the stored inputs of the tests
+func validateInputs(problem models.Problem, tests []*importedTest) (int, error) {
+	validator, err := newValidator(problem)
+	if err != nil {
+		return 500, err
+	}
+	if validator == nil {
+		return 0, nil
+	}
+	defer validator.Close()
+
+	for _, test := range tests {
+		input, err := storage.Open(test.inputObject.Hash)
+		if err != nil {
+			return 500, err
+		}
+		status, err := validateInput(validator, input)
+		input.Close()
+		if err != nil {
+			return status, fmt.Errorf("Test %s: %v", test.Name, err)
+		}
+	}
+	return 0, nil
+}
+
+// newValidator compiles the problem's validator; it is nil if the problem has none
+func newValidator(problem models.Problem) (*compiler.Validator, error) {
+	if problem.ValidatorSourceCode == "" {
+		return nil, nil
+	}
+	return compiler.NewValidator(problem.ValidatorSourceCode, problem.ValidatorLanguage)
+}
+
+// validateInput checks the input of one test
+func validateInput(validator *compiler.Validator, input io.Reader) (int, error) {
+	valid, message, err := validator.Validate(input)
+	if err != nil {
+		return 500, err
+	}
+	if !valid {
+		return 400, fmt.Errorf("Invalid input: %s", message)
+	}
+	return 0, nil
+}
+
+// pairArchiveTests matches the input and answer files of an archive. Files that are not tests
+// are reported as skipped; pairing problems are returned as errors.
+func pairArchiveTests(archive *zip.Reader, needOutputs bool, report *ImportReport) ([]*importedTest, []string) {
+	byName := map[string]*importedTest{}
+	var errs []string
+
+	for _, f := range archive.File {
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+
+		ext := path.Ext(base)
+		stem := strings.TrimSuffix(base, ext)
+		isOutput := false
+		switch {
+		case (ext == "" || ext == ".txt") && hasNumberedPrefix(stem, "input"):
+			stem = stem[len("input"):]
+		case (ext == "" || ext == ".txt") && hasNumberedPrefix(stem, "output"):
+			stem, isOutput = stem[len("output"):], true
+		case ext == "" || ext == ".in":
+		case ext == ".out" || ext == ".a" || ext == ".ans":
+			isOutput = true
+		default:
+			report.Skipped = append(report.Skipped, ImportEntry{Name: f.Name, Reason: "not a test file"})
+			continue
+		}
+		name := path.Join(path.Dir(f.Name), stem)
+
+		test := byName[name]
+		if test == nil {
+			test = &importedTest{Name: name}
+			byName[name] = test
+		}
+		slot := &test.Input
+		if isOutput {
+			slot = &test.Output
+		}
+		if *slot != nil {
+			errs = append(errs, fmt.Sprintf("%s and %s are the same test", (*slot).Name, f.Name))
+			continue
+		}
+		*slot = f
+	}
+
+	tests := make([]*importedTest, 0, len(byName))
+	for _, test := range byName {
+		switch {
+		case test.Input == nil:
+			errs = append(errs, fmt.Sprintf("%s has no input file", test.Output.Name))
+		case test.Output == nil && needOutputs:
+			errs = append(errs, fmt.Sprintf("%s has no answer file", test.Input.Name))
+		default:
+			tests = append(tests, test)
+		}
+	}
+
+	// Tests are created in archive order, with numbers compared by value: 2 comes before 10
+	sort.Slice(tests, func(i, j int) bool { return naturalLess(tests[i].Name, tests[j].Name) })
+	sort.Strings(errs)
+	return tests, errs
+}
+
+// putZipFile streams an archive entry into the test storage
+func putZipFile(f *zip.File) (storage.Object, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return storage.Object{}, err
+	}
+	defer reader.Close()
+	return storage.Put(reader)
+}
+
+// runAuthorOnTests compiles the author solution once and runs it on every test: it produces
+// the answers, or for interactive problems checks that the solution passes the interactor.
+// The status is the HTTP status of a failure.
+func runAuthorOnTests(problem models.Problem, tests []*importedTest) (int, error) {
+	sub := compiler.CompilerSubmission{
+		SourceCode:  problem.AuthorSourceCode,
+		Language:    problem.AuthorLanguage,
+		TimeLimit:   5.0,
+		MemoryLimit: problem.MemoryLimit,
+		OutputLimit: problem.OutputLimit,
+	}
+
+	var interactor *compiler.Interactor
+	if problem.InteractorSourceCode != "" {
+		var err error
+		if interactor, err = compiler.NewInteractor(problem.InteractorSourceCode, problem.InteractorLanguage); err != nil {
+			return 500, err
+		}
+		defer interactor.Close()
+	}
+
+	session, err := compiler.NewSession(sub)
+	if err != nil {
+		return 500, err
+	}
+	defer session.Close()
+
+	compileResult, err := session.Compile()
+	if err != nil {
+		return 500, err
+	}
+	if compileResult != nil && !compileResult.Success {
+		return 400, fmt.Errorf("Author solution %s", describeFailure(compiler.ExecutionResult{Outcome: compiler.OutcomeCompilationError, Compile: compileResult}))
+	}
+
+	for _, test := range tests {
+		input, err := storage.Open(test.inputObject.Hash)
+		if err != nil {
+			return 500, err
+		}
+
+		var result compiler.ExecutionResult
+		var check compiler.CheckResult
+		if interactor != nil {
+			result, check, err = interactor.Interact(session, input)
+		} else {
+			result, err = session.Run(input)
+		}
+		input.Close()
+		if err != nil {
+			return 500, fmt.Errorf("Test %s: execution failed: %v", test.Name, err)
+		}
+		if result.Outcome != compiler.OutcomeOK {
+			return 400, fmt.Errorf("Test %s: author solution %s", test.Name, describeFailure(result))
+		}
+
+		if interactor != nil {
+			if check.Verdict != compiler.CheckOK {
+				return 400, fmt.Errorf("Test %s: interactor rejected author solution (%s): %s", test.Name, check.Verdict, check.Message)
+			}
+			continue
+		}
+
+		if test.outputObject, err = storage.PutString(strings.TrimSpace(result.Stdout)); err != nil {
+			return 500, err
+		}
+	}
+	return 0, nil
+}
+
+// releaseImportedData deletes stored data of imported tests that no test case refers to
+func releaseImportedData(tests []*importedTest) {
+	stored := make([]models.TestCase, 0, len(tests))
+	for _, test := range tests {
+		stored = append(stored, models.TestCase{InputHash: test.inputObject.Hash, OutputHash: test.outputObject.Hash})
+	}
+	releaseTestData(stored...)
+}
+
+// naturalLess compares names with runs of digits compared as numbers
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// hasNumberedPrefix reports whether a file name is prefix followed by a test number, like input1 and output1
+func hasNumberedPrefix(stem, prefix string) bool {
+	return len(stem) > len(prefix) && strings.EqualFold(stem[:len(prefix)], prefix) && leadingDigits(stem[len(prefix):]) != ""
+}
+
+// leadingDigits returns the run of ASCII digits s starts with
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
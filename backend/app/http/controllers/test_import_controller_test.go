@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"2", "10", true},
+		{"10", "2", false},
+		{"01", "2", true},
+		{"09", "10", true},
+		{"01", "1", false},
+		{"1", "01", false},
+		{"1", "1.a", true},
+		{"1.a", "1", false},
+		{"test2", "test10", true},
+		{"test10", "test2", false},
+		{"a2b3", "a2b10", true},
+		{"sample1", "test1", true},
+		{"abc", "abd", true},
+		{"", "1", true},
+		{"1", "1", false},
+		{"99999999999999999999", "100000000000000000000", true},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.less {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.less)
+		}
+	}
+}
+
+// zipOf builds an archive in memory with an empty file for each name; names ending in / are directories
+func zipOf(t *testing.T, names ...string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		if _, err := w.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestPairArchiveTests(t *testing.T) {
+	type pair struct{ name, input, output string }
+
+	tests := []struct {
+		name        string
+		files       []string
+		needOutputs bool
+		pairs       []pair
+		errs        []string
+		skipped     []string
+	}{
+		{
+			name:        "in and out, numbers by value",
+			files:       []string{"10.in", "10.out", "01.in", "2.in", "01.out", "2.out"},
+			needOutputs: true,
+			pairs:       []pair{{"01", "01.in", "01.out"}, {"2", "2.in", "2.out"}, {"10", "10.in", "10.out"}},
+		},
+		{
+			name:        "bare input and .a answer",
+			files:       []string{"1", "1.a", "2", "2.a", "3", "3.ans"},
+			needOutputs: true,
+			pairs:       []pair{{"1", "1", "1.a"}, {"2", "2", "2.a"}, {"3", "3", "3.ans"}},
+		},
+		{
+			name:        "input and output prefixes",
+			files:       []string{"output2.txt", "input1.txt", "output1.txt", "input2.txt", "Input10", "Output10"},
+			needOutputs: true,
+			pairs:       []pair{{"1", "input1.txt", "output1.txt"}, {"2", "input2.txt", "output2.txt"}, {"10", "Input10", "Output10"}},
+		},
+		{
+			name:        "tests in a directory",
+			files:       []string{"tests/", "tests/1.in", "tests/1.out", "tests/input2.txt", "tests/output2.txt"},
+			needOutputs: true,
+			pairs:       []pair{{"tests/1", "tests/1.in", "tests/1.out"}, {"tests/2", "tests/input2.txt", "tests/output2.txt"}},
+		},
+		{
+			name:        "unmatched files",
+			files:       []string{"01.in", "01.out", "02.in", "03.out"},
+			needOutputs: true,
+			pairs:       []pair{{"01", "01.in", "01.out"}},
+			errs:        []string{"02.in has no answer file", "03.out has no input file"},
+		},
+		{
+			name:        "answers are optional when generated",
+			files:       []string{"01.in", "02.in", "02.out"},
+			needOutputs: false,
+			pairs:       []pair{{"01", "01.in", ""}, {"02", "02.in", "02.out"}},
+		},
+		{
+			name:        "two inputs of one test",
+			files:       []string{"05.in", "05", "05.out"},
+			needOutputs: true,
+			pairs:       []pair{{"05", "05.in", "05.out"}},
+			errs:        []string{"05.in and 05 are the same test"},
+		},
+		{
+			name:        "other files are skipped",
+			files:       []string{"1.in", "1.out", "README.md", "input.txt", "checker.cpp"},
+			needOutputs: true,
+			pairs:       []pair{{"1", "1.in", "1.out"}},
+			skipped:     []string{"README.md", "input.txt", "checker.cpp"},
+		},
+		{
+			name:        "hidden and macOS files are ignored",
+			files:       []string{"1.in", "1.out", ".DS_Store", "__MACOSX/._1.in"},
+			needOutputs: true,
+			pairs:       []pair{{"1", "1.in", "1.out"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newImportReport()
+			tests, errs := pairArchiveTests(zipOf(t, tt.files...), tt.needOutputs, &report)
+
+			var pairs []pair
+			for _, test := range tests {
+				p := pair{name: test.Name, input: test.Input.Name}
+				if test.Output != nil {
+					p.output = test.Output.Name
+				}
+				pairs = append(pairs, p)
+			}
+			if !reflect.DeepEqual(pairs, tt.pairs) {
+				t.Errorf("pairs = %v, want %v", pairs, tt.pairs)
+			}
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+
+			var skipped []string
+			for _, entry := range report.Skipped {
+				skipped = append(skipped, entry.Name)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+// Generator is a test generator program of a problem. Tests are generated from script lines
+// such as `gen 100000 7`, where the first word is the generator's name and the rest its arguments.
+type Generator struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ProblemID  uint   `gorm:"index" json:"problem_id"`
+	Name       string `json:"name"`
+	SourceCode string `json:"source_code"`
+	Language   string `json:"language"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// JudgeJob is a queued request to judge a submission, or to run an invocation when InvocationID is set.
+// Rows are claimed by judge workers with SELECT ... FOR UPDATE SKIP LOCKED.
+type JudgeJob struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	SubmissionID uint       `gorm:"index" json:"submission_id"`
+	InvocationID uint       `gorm:"index" json:"invocation_id,omitempty"`
+	Status       string     `gorm:"index;default:queued" json:"status"` // queued, running, done, failed
+	Attempts     int        `gorm:"default:0" json:"attempts"`
+	WorkerID     string     `json:"worker_id"`
+	LockedAt     *time.Time `json:"locked_at"` // Last heartbeat of the worker holding the job
+	LastError    string     `json:"last_error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -8,6 +8,7 @@ type Problem struct {
 	Description string  `json:"description"`
 	TimeLimit   float64 `json:"time_limit"`
 	MemoryLimit int     `json:"memory_limit"`
+	OutputLimit int     `gorm:"default:64" json:"output_limit"` // MB of stdout per test
 	AuthorID    uint    `json:"author_id"`
 	Visibility  string  `gorm:"default:private" json:"visibility"` // private, public
 
@@ -15,20 +16,52 @@ type Problem struct {
 	Status            string `gorm:"default:draft" json:"status"`
 	ModerationComment string `json:"moderation_comment"` // Reason for rejection
 
+	// Topics such as "dp" or "graphs", lowercase and sorted, and a rating from
+	// 800 to 3500 where 0 means unrated; both are used by the problem list filters
+	Tags       []string `gorm:"type:jsonb;default:'[]';serializer:json;index:,type:gin" json:"tags"`
+	Difficulty int      `gorm:"default:0;index" json:"difficulty"`
+
 	AuthorSourceCode string `json:"author_source_code"`
 	AuthorLanguage   string `json:"author_language"`
 
+	// Built-in output comparison, see services/comparator; ignored when a checker is set
+	CheckerMode    string  `gorm:"default:tokens" json:"checker_mode"`
+	CheckerEpsilon float64 `json:"checker_epsilon"` // Tolerance of the "float" mode, 0 means the default
+
+	// Special judge (testlib-compatible); CheckerMode is used when empty
+	CheckerSourceCode string `json:"checker_source_code"`
+	CheckerLanguage   string `json:"checker_language"`
+
+	// Interactor (testlib-compatible); a problem with an interactor is interactive
+	// and its tests have no expected output
+	InteractorSourceCode string `json:"interactor_source_code"`
+	InteractorLanguage   string `json:"interactor_language"`
+
+	// Input validator (testlib-compatible); every new test must pass it when set
+	ValidatorSourceCode string `json:"validator_source_code"`
+	ValidatorLanguage   string `json:"validator_language"`
+
 	// Sharing
 	ShareToken string `json:"share_token"` // Unique token for link sharing
 
 	CreatedAt time.Time `json:"created_at"`
 
-	TestCases   []TestCase      `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"test_cases,omitempty"`
-	Submissions []Submission    `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"-"`
-	AccessList  []ProblemAccess `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"access_list,omitempty"`
+	TestCases   []TestCase        `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"test_cases,omitempty"`
+	Subtasks    []Subtask         `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"subtasks,omitempty"`
+	Generators  []Generator       `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"generators,omitempty"`
+	Solutions   []ProblemSolution `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"solutions,omitempty"`
+	Invocations []Invocation      `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"-"`
+	Revisions   []ProblemRevision `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"-"`
+	Submissions []Submission      `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"-"`
+	AccessList  []ProblemAccess   `gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE" json:"access_list,omitempty"`
 
 	// Virtual field for statistics
 	SolvedCount int64 `gorm:"-" json:"solved_count"`
+	Solved      bool  `gorm:"-" json:"solved,omitempty"` // Whether the current user solved it, in the problem list
+
+	// Virtual fields of scored problems
+	MaxScore  float64  `gorm:"-" json:"max_score,omitempty"`  // Sum of subtask points
+	BestScore *float64 `gorm:"-" json:"best_score,omitempty"` // Best score of the current user
 }
 
 type ProblemAccess struct {
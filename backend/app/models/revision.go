@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// ProblemRevision is an immutable snapshot of a problem taken after each change: statement,
+// limits, judging programs, subtasks and the test set. Revisions share test data with the tests
+// through the content-addressed storage, which keeps data as long as a revision refers to it.
+type ProblemRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProblemID uint      `gorm:"uniqueIndex:idx_problem_revision" json:"problem_id"`
+	Number    int       `gorm:"uniqueIndex:idx_problem_revision" json:"number"` // 1-based, per problem
+	UserID    uint      `json:"user_id"`                                        // Who made the change
+	Change    string    `json:"change"`                                         // What was done, e.g. "Test deleted"
+	CreatedAt time.Time `json:"created_at"`
+
+	Title          string  `json:"title"`
+	Description    string  `json:"description"`
+	TimeLimit      float64 `json:"time_limit"`
+	MemoryLimit    int     `json:"memory_limit"`
+	OutputLimit    int     `json:"output_limit"`
+	CheckerMode    string  `json:"checker_mode"`
+	CheckerEpsilon float64 `json:"checker_epsilon"`
+
+	Tags       []string `gorm:"default:'[]';serializer:json" json:"tags"`
+	Difficulty int      `gorm:"default:0" json:"difficulty"`
+
+	AuthorSourceCode     string `json:"author_source_code"`
+	AuthorLanguage       string `json:"author_language"`
+	CheckerSourceCode    string `json:"checker_source_code"`
+	CheckerLanguage      string `json:"checker_language"`
+	InteractorSourceCode string `json:"interactor_source_code"`
+	InteractorLanguage   string `json:"interactor_language"`
+	ValidatorSourceCode  string `json:"validator_source_code"`
+	ValidatorLanguage    string `json:"validator_language"`
+
+	Subtasks []Subtask `gorm:"serializer:json" json:"subtasks"`
+	// Nil in revisions taken before generators and reference solutions were kept
+	Generators []Generator       `gorm:"serializer:json" json:"generators"`
+	Solutions  []ProblemSolution `gorm:"serializer:json" json:"solutions"`
+	Tests      []RevisionTest    `gorm:"foreignKey:RevisionID;constraint:OnDelete:CASCADE" json:"tests,omitempty"`
+}
+
+// RevisionTest is a test as it was in a revision
+type RevisionTest struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	RevisionID uint   `gorm:"index" json:"-"`
+	TestCaseID uint   `json:"test_case_id"` // The test in the problem, which may have been deleted since
+	InputHash  string `gorm:"size:64;index" json:"input_hash"`
+	InputSize  int64  `json:"input_size"`
+	OutputHash string `gorm:"size:64;index" json:"output_hash"`
+	OutputSize int64  `json:"output_size"`
+	IsSample   bool   `json:"is_sample"`
+	Subtask    int    `json:"subtask"`
+	Script     string `json:"script,omitempty"`
+}
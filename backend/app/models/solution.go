@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// ProblemSolution is a reference solution of a problem, tagged with the verdict it must get.
+// Invocations judge all of them on all tests, which shows whether the tests catch the wrong
+// solutions and whether the time limit is fair to the correct ones.
+type ProblemSolution struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ProblemID  uint   `gorm:"index" json:"problem_id"`
+	Name       string `json:"name"`
+	SourceCode string `json:"source_code"`
+	Language   string `json:"language"`
+	Tag        string `json:"tag"` // Expected verdict: accepted, wrong_answer, time_limit, ..., see judge.Tag*
+}
+
+// Invocation is a run of the reference solutions of a problem on all of its tests.
+// The author solution is always included as the main correct one.
+type Invocation struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProblemID uint   `gorm:"index" json:"problem_id"`
+	UserID    uint   `json:"user_id"`
+	Status    string `gorm:"default:queued" json:"status"` // queued, running, done, failed
+	Error     string `json:"error,omitempty"`
+
+	TimeLimit   float64              `json:"time_limit"`                           // Of the problem when invoked, seconds
+	TestCaseIDs []uint               `gorm:"serializer:json" json:"test_case_ids"` // Columns of the matrix
+	Solutions   []InvocationSolution `gorm:"serializer:json" json:"solutions"`     // Rows, filled in as they finish
+
+	// A calibration runs the correct solutions Runs times on every test with a generous time limit,
+	// and proposes Factor times the slowest run as the time limit, setting it on the problem if Apply is set
+	Runs              int     `json:"runs,omitempty"` // 0 for a plain invocation
+	Factor            float64 `json:"factor,omitempty"`
+	Apply             bool    `json:"apply,omitempty"`
+	ProposedTimeLimit float64 `json:"proposed_time_limit,omitempty"`
+	Applied           bool    `json:"applied,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// InvocationSolution is the row of one solution in the verdict matrix of an invocation
+type InvocationSolution struct {
+	SolutionID    uint             `json:"solution_id"` // 0 for the author solution
+	Name          string           `json:"name"`
+	Language      string           `json:"language"`
+	Tag           string           `json:"tag"`
+	Verdict       string           `json:"verdict"`  // Verdict of the first failed test, as for a submission
+	MaxTime       int64            `json:"max_time"` // Slowest test, ms of CPU time
+	MaxMemory     int64            `json:"max_memory"`
+	CompileOutput string           `json:"compile_output,omitempty"`
+	Matches       bool             `json:"matches"`           // The results agree with the tag
+	Problem       string           `json:"problem,omitempty"` // Why they do not
+	Warning       string           `json:"warning,omitempty"` // E.g. a correct solution close to the time limit
+	Tests         []InvocationTest `json:"tests"`
+}
+
+// InvocationTest is one cell of the verdict matrix
+type InvocationTest struct {
+	TestCaseID uint   `json:"test_case_id"`
+	Verdict    string `json:"verdict"`
+	Time       int64  `json:"time"`   // ms of CPU time
+	Memory     int64  `json:"memory"` // KB
+}
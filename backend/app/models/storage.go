@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// ReleasedObject is stored test data that lost its last reference. It is deleted
+// by a later sweep unless a test refers to it again by then, see services/testgc.
+type ReleasedObject struct {
+	Hash       string    `gorm:"primaryKey;size:64" json:"hash"`
+	ReleasedAt time.Time `gorm:"index" json:"released_at"`
+}
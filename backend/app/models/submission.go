@@ -11,18 +11,30 @@ type Submission struct {
 	SourceCode    string    `json:"source_code"`
 	Status        string    `json:"status"` // Pending, Accepted, Wrong Answer, etc.
 	ExecutionTime string    `json:"execution_time"`
+	Memory        int64     `json:"memory"`                   // Peak memory across tests, KB
+	Score         float64   `json:"score"`                    // Points earned on a problem with subtasks
+	CompileOutput string    `json:"compile_output,omitempty"` // Compiler messages, visible to the owner only
+	RevisionID    uint      `json:"revision_id,omitempty"`    // Revision of the problem it was last judged against
 	CreatedAt     time.Time `json:"created_at"`
 
+	SubtaskScores []SubtaskScore `gorm:"serializer:json" json:"subtask_scores,omitempty"`
+
 	User    User               `gorm:"foreignKey:UserID" json:"user"`
 	Problem Problem            `gorm:"foreignKey:ProblemID" json:"problem"`
 	Details []SubmissionDetail `gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE" json:"details"`
 }
 
 type SubmissionDetail struct {
-	ID            uint   `gorm:"primaryKey" json:"id"`
-	SubmissionID  uint   `json:"submission_id"`
-	TestCaseID    uint   `json:"test_case_id"`
-	Status        string `json:"status"`
-	ExecutionTime string `json:"execution_time"`
-	IsSample      bool   `json:"is_sample"`
+	ID             uint    `gorm:"primaryKey" json:"id"`
+	SubmissionID   uint    `json:"submission_id"`
+	TestCaseID     uint    `json:"test_case_id"`
+	Status         string  `json:"status"`
+	ExecutionTime  string  `json:"execution_time"`
+	Memory         int64   `json:"memory"` // Peak memory, KB
+	Score          float64 `json:"score"`  // Fraction of the test passed, from 0 to 1
+	ExitCode       int     `json:"exit_code"`
+	Signal         int     `json:"signal"`                    // Terminating signal, 0 if none
+	Stderr         string  `json:"stderr,omitempty"`          // Visible to the owner only
+	CheckerMessage string  `json:"checker_message,omitempty"` // Comment of the problem's checker, visible to the owner only
+	IsSample       bool    `json:"is_sample"`
 }
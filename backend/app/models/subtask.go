@@ -0,0 +1,24 @@
+package models
+
+// Subtask groups tests of a scored (IOI-style) problem. A problem with subtasks runs
+// all tests of a submission and scores it as the sum of its subtask scores.
+type Subtask struct {
+	ID        uint    `gorm:"primaryKey" json:"id"`
+	ProblemID uint    `gorm:"index" json:"problem_id"`
+	Index     int     `json:"index"` // 1-based number, referenced by TestCase.Subtask and Dependencies
+	Name      string  `json:"name"`
+	Points    float64 `json:"points"`
+
+	// Scoring: all (full points only if every test passes), min or avg of per-test scores
+	Scoring string `gorm:"default:all" json:"scoring"`
+
+	// Indexes of earlier subtasks whose tests also count towards this one
+	Dependencies []int `gorm:"serializer:json" json:"dependencies"`
+}
+
+// SubtaskScore is the result of one subtask in a submission
+type SubtaskScore struct {
+	Index  int     `json:"index"`
+	Score  float64 `json:"score"`
+	Points float64 `json:"points"`
+}
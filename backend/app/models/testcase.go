@@ -1,9 +1,19 @@
 package models
 
+// TestCase holds the metadata of a test. The data lives in the test storage
+// (services/storage), addressed by the SHA-256 of its content.
 type TestCase struct {
-	ID             uint   `gorm:"primaryKey" json:"id"`
-	ProblemID      uint   `json:"problem_id"`
-	Input          string `json:"input"`
-	ExpectedOutput string `json:"expected_output"`
-	IsSample       bool   `gorm:"default:false" json:"is_sample"`
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ProblemID  uint   `json:"problem_id"`
+	InputHash  string `gorm:"size:64;index" json:"input_hash"`
+	InputSize  int64  `json:"input_size"`
+	OutputHash string `gorm:"size:64;index" json:"output_hash"` // Empty for interactive problems
+	OutputSize int64  `json:"output_size"`
+	IsSample   bool   `gorm:"default:false" json:"is_sample"`
+	Subtask    int    `gorm:"default:0" json:"subtask"` // Subtask index, 0 if the test is not scored
+	Script     string `json:"script"`                   // Generator command line of a generated test
+
+	// Input of new tests, and previews of the data in responses; never stored in the database
+	Input          string `gorm:"-" json:"input"`
+	ExpectedOutput string `gorm:"-" json:"expected_output"`
 }
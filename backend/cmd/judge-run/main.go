@@ -0,0 +1,86 @@
+// Command judge-run is copied into sandbox containers (or run on the host by the
+// local sandbox). It runs the contestant program with the inherited stdio and
+// writes resource usage as JSON to a stats file.
+//
+// Usage: judge-run -stats /tmp/stats.json -cpu 2 -wall 5 -- program args...
+//
+// For interactive problems, -stdin and -stdout connect the program to the interactor
+// directly through two FIFOs instead of the inherited stdio.
+//
+// In containers it is started as root and drops to -uid/-gid for the program, which
+// therefore cannot forge the stats file or replace the helper.
+//
+// It must be built statically (CGO_ENABLED=0) so it works in any language image.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"onlineJudge/backend/services/compiler/runner"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == runner.LimitsArg {
+		err := runner.ExecWithLimits(os.Args[2:])
+		fmt.Fprintln(os.Stderr, "judge-run:", err)
+		os.Exit(127)
+	}
+
+	statsPath := flag.String("stats", "/tmp/judge-stats.json", "file to write resource usage to")
+	cpuLimit := flag.Float64("cpu", 0, "CPU time limit in seconds (0 = unlimited)")
+	wallLimit := flag.Float64("wall", 0, "wall-clock limit in seconds (0 = unlimited)")
+	addressSpace := flag.Int64("as", 0, "address space limit in bytes (0 = unlimited)")
+	processes := flag.Int("nproc", 0, "process and thread limit of the user (0 = unlimited)")
+	cgroupDir := flag.String("cgroup", "/sys/fs/cgroup", "cgroup to sample CPU and OOM kills from (empty = none)")
+	uid := flag.Int("uid", -1, "user ID to run the program as (-1 = unchanged)")
+	gid := flag.Int("gid", -1, "group ID to run the program as (-1 = unchanged)")
+	stdinPath := flag.String("stdin", "", "FIFO to use as the program's stdin, shared with an interacting program")
+	stdoutPath := flag.String("stdout", "", "FIFO to use as the program's stdout, shared with an interacting program")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "judge-run: no command given")
+		os.Exit(2)
+	}
+
+	stdin, stdout := os.Stdin, os.Stdout
+	if *stdinPath != "" && *stdoutPath != "" {
+		var err error
+		// The peer is started right after this one, its wall-clock limit is a generous bound
+		if stdin, stdout, err = runner.OpenChannel(*stdinPath, *stdoutPath, seconds(*wallLimit)); err != nil {
+			fmt.Fprintln(os.Stderr, "judge-run:", err)
+			os.Exit(2)
+		}
+	}
+
+	stats := runner.Run(runner.Options{
+		Command: flag.Args(),
+		Env:     os.Environ(),
+		Stdin:   stdin,
+		Stdout:  stdout,
+		Stderr:  os.Stderr,
+
+		CPULimit:          seconds(*cpuLimit),
+		WallLimit:         seconds(*wallLimit),
+		AddressSpaceLimit: *addressSpace,
+		ProcessLimit:      *processes,
+		CgroupDir:         *cgroupDir,
+		UID:               *uid,
+		GID:               *gid,
+	})
+
+	data, _ := json.Marshal(stats)
+	if err := os.WriteFile(*statsPath, data, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "judge-run: failed to write stats:", err)
+		os.Exit(2)
+	}
+
+	os.Exit(stats.ExitCode)
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -2,6 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +14,40 @@ var (
 	AppPort        string
 	DBUrl          string
 	AllowedOrigins string
+
+	// Judge queue
+	JudgeWorkers    int           // Number of concurrent judge workers
+	InvokeWorkers   int           // How many of them may run invocations at once, the rest only judge submissions
+	JudgeStaleAfter time.Duration // Running jobs without a heartbeat for this long are reclaimed
+
+	// Sandbox
+	SandboxBackend       string         // "docker" (default) or "local" to run toolchains on the host
+	SandboxCgroupRoot    string         // Delegated cgroup v2 directory the local sandbox may create children in
+	SandboxNamespaces    bool           // Run local sandbox processes in their own user, PID and network namespaces
+	SandboxLocalInsecure bool           // Allows the local backend, which does not isolate programs from the host
+	SandboxHelper        string         // Path to the static judge-run binary copied into sandbox containers
+	SandboxPoolSize      int            // Warm containers kept per language
+	SandboxPoolSizes     map[string]int // Per-language overrides, e.g. "python=4,cpp=2"
+	SandboxPidsLimit     int            // Processes and threads a sandbox may have, stops fork bombs
+
+	// Languages
+	LanguagesFile string // JSON language registry replacing the built-in one
+
+	// Checkers
+	CheckerTestlib string // Path to testlib.h, made available to C and C++ checkers
+
+	// Test data storage
+	TestStorage    string // "local" (default) or "s3"
+	TestStorageDir string // Directory of the local storage, shared by all backend instances
+	S3Endpoint     string // e.g. https://s3.amazonaws.com or http://minio:9000
+	S3Bucket       string
+	S3Region       string
+	S3AccessKey    string
+	S3SecretKey    string
+
+	// Test archives
+	TestArchiveLimit         int // MB of an uploaded ZIP with tests; also the request body limit
+	TestArchiveUnpackedLimit int // MB of test data unpacked from one archive
 )
 
 func LoadConfig() {
@@ -26,4 +64,73 @@ func LoadConfig() {
 	if AllowedOrigins == "" {
 		AllowedOrigins = "*" // Default for dev
 	}
+
+	JudgeWorkers = getEnvInt("JUDGE_WORKERS", 2)
+	InvokeWorkers = getEnvInt("INVOKE_WORKERS", 1)
+	JudgeStaleAfter = time.Duration(getEnvInt("JUDGE_STALE_AFTER_SECONDS", 300)) * time.Second
+
+	SandboxBackend = os.Getenv("SANDBOX_BACKEND")
+	if SandboxBackend == "" {
+		SandboxBackend = "docker"
+	}
+	SandboxCgroupRoot = os.Getenv("SANDBOX_CGROUP_ROOT")
+	SandboxNamespaces = os.Getenv("SANDBOX_NAMESPACES") == "true"
+	SandboxLocalInsecure = os.Getenv("SANDBOX_LOCAL_INSECURE") == "true"
+
+	SandboxHelper = os.Getenv("SANDBOX_HELPER")
+	if SandboxHelper == "" {
+		// Built next to the backend binary (see Dockerfile)
+		if executable, err := os.Executable(); err == nil {
+			SandboxHelper = filepath.Join(filepath.Dir(executable), "judge-run")
+		}
+	}
+
+	SandboxPoolSize = getEnvInt("SANDBOX_POOL_SIZE", 1)
+	SandboxPoolSizes = map[string]int{}
+	for _, entry := range strings.Split(os.Getenv("SANDBOX_POOL_SIZES"), ",") {
+		name, size, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		if value, err := strconv.Atoi(size); err == nil && value >= 0 {
+			SandboxPoolSizes[name] = value
+		}
+	}
+
+	// JVMs start dozens of threads, which count as well
+	SandboxPidsLimit = getEnvInt("SANDBOX_PIDS_LIMIT", 256)
+
+	LanguagesFile = os.Getenv("LANGUAGES_FILE")
+
+	CheckerTestlib = os.Getenv("CHECKER_TESTLIB")
+	if CheckerTestlib == "" {
+		if executable, err := os.Executable(); err == nil {
+			CheckerTestlib = filepath.Join(filepath.Dir(executable), "testlib.h")
+		}
+	}
+
+	TestStorage = os.Getenv("TEST_STORAGE")
+	if TestStorage == "" {
+		TestStorage = "local"
+	}
+	TestStorageDir = os.Getenv("TEST_STORAGE_DIR")
+	if TestStorageDir == "" {
+		TestStorageDir = "data/tests"
+	}
+	S3Endpoint = os.Getenv("S3_ENDPOINT")
+	S3Bucket = os.Getenv("S3_BUCKET")
+	S3Region = os.Getenv("S3_REGION")
+	S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+	S3SecretKey = os.Getenv("S3_SECRET_KEY")
+
+	TestArchiveLimit = getEnvInt("TEST_ARCHIVE_LIMIT", 64)
+	TestArchiveUnpackedLimit = getEnvInt("TEST_ARCHIVE_UNPACKED_LIMIT", 1024)
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
 }
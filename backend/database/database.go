@@ -46,8 +46,24 @@ func Connect() {
 		&models.ContestProblem{},
 		&models.ContestParticipant{},
 		&models.ProblemAccess{}, // New model
+		&models.JudgeJob{},
+		&models.Subtask{},
+		&models.Generator{},
+		&models.ProblemSolution{},
+		&models.Invocation{},
+		&models.ProblemRevision{},
+		&models.RevisionTest{},
+		&models.ReleasedObject{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
+
+	if err := moveTestDataToStorage(); err != nil {
+		log.Fatal("Failed to move test data to storage: ", err)
+	}
+
+	if err := recordInitialRevisions(); err != nil {
+		log.Fatal("Failed to record initial revisions: ", err)
+	}
 }
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"log"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/revision"
+
+	"gorm.io/gorm"
+)
+
+// recordInitialRevisions gives problems that have no revision yet, such as problems created
+// before revisions were kept or by the seeder, their first revision as they are now
+func recordInitialRevisions() error {
+	var problems []models.Problem
+	err := DB.Select("id", "author_id").
+		Where("NOT EXISTS (SELECT 1 FROM problem_revisions WHERE problem_revisions.problem_id = problems.id)").
+		Order("id").Find(&problems).Error
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range problems {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			_, err := revision.Record(tx, problem.ID, problem.AuthorID, "Initial revision")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(problems) > 0 {
+		log.Printf("Recorded initial revisions of %d problems", len(problems))
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"math/rand"
 	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/storage"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -65,6 +66,7 @@ func Seed() {
 	langs := []string{"python", "cpp", "java", "go", "javascript"}
 	statuses := []string{"draft", "pending_review", "published", "rejected"}
 	visibilities := []string{"public", "private"}
+	tags := []string{"dp", "graphs", "greedy", "math", "strings", "sortings", "binary search", "implementation"}
 
 	problems := []models.Problem{}
 
@@ -87,6 +89,8 @@ func Seed() {
 			Status:           status,
 			AuthorLanguage:   "python",
 			AuthorSourceCode: "print('Hello World')",
+			Tags:             []string{tags[i%len(tags)]},
+			Difficulty:       800 + 100*rand.Intn(20),
 			CreatedAt:        time.Now().Add(-time.Duration(rand.Intn(1000)) * time.Hour),
 		}
 		DB.Create(&problem)
@@ -94,11 +98,21 @@ func Seed() {
 
 		// Create Test Cases
 		for j := 0; j < rand.Intn(3)+1; j++ {
+			input, err := storage.PutString(fmt.Sprintf("%d %d", rand.Intn(100), rand.Intn(100)))
+			if err != nil {
+				log.Fatal("Failed to store test data: ", err)
+			}
+			output, err := storage.PutString(fmt.Sprintf("%d", rand.Intn(200)))
+			if err != nil {
+				log.Fatal("Failed to store test data: ", err)
+			}
 			testCase := models.TestCase{
-				ProblemID:      problem.ID,
-				Input:          fmt.Sprintf("%d %d", rand.Intn(100), rand.Intn(100)),
-				ExpectedOutput: fmt.Sprintf("%d", rand.Intn(200)),
-				IsSample:       j == 0,
+				ProblemID:  problem.ID,
+				InputHash:  input.Hash,
+				InputSize:  input.Size,
+				OutputHash: output.Hash,
+				OutputSize: output.Size,
+				IsSample:   j == 0,
 			}
 			DB.Create(&testCase)
 		}
@@ -132,5 +146,9 @@ func Seed() {
 	}
 	log.Println("Submissions seeded.")
 
+	if err := recordInitialRevisions(); err != nil {
+		log.Println("Failed to record revisions of seeded problems: ", err)
+	}
+
 	log.Println("Database seeding completed.")
 }
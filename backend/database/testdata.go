@@ -0,0 +1,69 @@
+package database
+
+import (
+	"log"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/storage"
+)
+
+// moveTestDataToStorage moves test data of older versions, kept in the input and
+// expected_output columns, into the test storage and drops the columns afterwards.
+// Rows are moved in small batches so large tests are never all in memory.
+func moveTestDataToStorage() error {
+	migrator := DB.Migrator()
+	if !migrator.HasColumn(&models.TestCase{}, "input") {
+		return nil
+	}
+
+	type legacyTestCase struct {
+		ID             uint
+		Input          string
+		ExpectedOutput string
+	}
+
+	moved := 0
+	lastID := uint(0)
+	for {
+		var batch []legacyTestCase
+		err := DB.Raw(`SELECT id, input, expected_output FROM test_cases
+			WHERE (input_hash IS NULL OR input_hash = '') AND id > ? ORDER BY id LIMIT 20`, lastID).
+			Scan(&batch).Error
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, row := range batch {
+			input, err := storage.PutString(row.Input)
+			if err != nil {
+				return err
+			}
+			output := storage.Object{}
+			if row.ExpectedOutput != "" {
+				if output, err = storage.PutString(row.ExpectedOutput); err != nil {
+					return err
+				}
+			}
+
+			err = DB.Model(&models.TestCase{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"input_hash":  input.Hash,
+				"input_size":  input.Size,
+				"output_hash": output.Hash,
+				"output_size": output.Size,
+			}).Error
+			if err != nil {
+				return err
+			}
+			lastID = row.ID
+			moved++
+		}
+	}
+
+	log.Printf("Moved %d test cases to the test storage", moved)
+	if err := migrator.DropColumn(&models.TestCase{}, "input"); err != nil {
+		return err
+	}
+	return migrator.DropColumn(&models.TestCase{}, "expected_output")
+}
@@ -14,6 +14,11 @@ require (
 	gorm.io/gorm v1.25.7
 )
 
+require (
+	golang.org/x/crypto v0.28.0
+	golang.org/x/sys v0.26.0
+)
+
 require (
 	cloud.google.com/go/compute v1.20.1 // indirect
 	cloud.google.com/go/compute/metadata v0.2.3 // indirect
@@ -64,9 +69,7 @@ require (
 	go.opentelemetry.io/otel/metric v1.24.0 // indirect
 	go.opentelemetry.io/otel/sdk v1.19.0 // indirect
 	go.opentelemetry.io/otel/trace v1.24.0 // indirect
-	golang.org/x/crypto v0.28.0 // indirect
 	golang.org/x/net v0.30.0 // indirect
-	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
 	golang.org/x/time v0.0.0-00010101000000-000000000000 // indirect
 	golang.org/x/tools v0.26.0 // indirect
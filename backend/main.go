@@ -6,6 +6,10 @@ import (
 	"onlineJudge/backend/database"
 	"onlineJudge/backend/routes"
 	"onlineJudge/backend/selftest"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/judge"
+	"onlineJudge/backend/services/storage"
+	"onlineJudge/backend/services/testgc"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -23,14 +27,42 @@ func main() {
 	// Load config
 	config.LoadConfig()
 
+	// Load the language registry
+	if err := compiler.LoadLanguages(); err != nil {
+		log.Fatal(err)
+	}
+
+	// The local sandbox backend must be enabled explicitly
+	if err := compiler.CheckBackend(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Test data storage, needed by the migration of older test cases
+	if err := storage.Init(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Connect to database
 	database.Connect()
 
+	// Released test data is deleted after a grace period; storing content claims it back
+	storage.SetClaim(testgc.Claim)
+	testgc.StartSweeper()
+
 	// Seed database (if empty)
 	database.Seed()
 
+	// Pre-start sandbox containers
+	go compiler.StartPools()
+
+	// Start judge workers
+	judge.StartWorkers(config.JudgeWorkers)
+
 	// Initialize Fiber app
-	app := fiber.New()
+	// Test archives are the largest requests
+	app := fiber.New(fiber.Config{
+		BodyLimit: config.TestArchiveLimit * 1024 * 1024,
+	})
 
 	// Middleware
 	app.Use(logger.New())
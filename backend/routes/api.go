@@ -16,8 +16,10 @@ func SetupRoutes(app *fiber.App) {
 
 	// Problems (Public)
 	api.Get("/problems", controllers.GetProblems)
+	api.Get("/problems/tags", controllers.GetProblemTags)
 	api.Get("/problems/:id", controllers.GetProblem)
 	api.Get("/leaderboard", controllers.GetLeaderboard)
+	api.Get("/languages", controllers.GetLanguages)
 
 	// Contests (Public)
 	api.Get("/contests", controllers.GetContests)
@@ -28,11 +30,29 @@ func SetupRoutes(app *fiber.App) {
 	api.Use(middleware.AuthRequired)
 	api.Post("/problems", controllers.CreateProblem)
 	api.Put("/problems/:id", controllers.UpdateProblem)
+	api.Patch("/problems/:id", controllers.UpdateProblem)
 	api.Delete("/problems/:id", controllers.DeleteProblem)
+	api.Post("/problems/import", controllers.ImportProblem)
+	api.Get("/problems/:id/package", controllers.ExportProblem)
 
 	// Test Cases
 	api.Post("/problems/:id/testcases", controllers.AddTestCase)
+	api.Post("/problems/:id/testcases/archive", controllers.ImportTestArchive)
+	api.Post("/problems/:id/testcases/generate", controllers.GenerateTests)
+	api.Put("/problems/:id/testcases/:testcase_id", controllers.UpdateTestCase)
 	api.Delete("/problems/:id/testcases/:testcase_id", controllers.DeleteTestCase)
+	api.Get("/problems/:id/testcases/:testcase_id/:kind", controllers.GetTestData)
+	api.Put("/problems/:id/subtasks", controllers.SetSubtasks)
+	api.Put("/problems/:id/generators", controllers.SetGenerators)
+	api.Put("/problems/:id/solutions", controllers.SetSolutions)
+	api.Post("/problems/:id/invocations", controllers.InvokeSolutions)
+	api.Get("/problems/:id/invocations", controllers.GetInvocations)
+	api.Get("/problems/:id/invocations/:invocation_id", controllers.GetInvocation)
+	api.Post("/problems/:id/calibrations", controllers.CalibrateTimeLimit)
+	api.Get("/problems/:id/revisions", controllers.GetRevisions)
+	api.Get("/problems/:id/revisions/:number", controllers.GetRevision)
+	api.Get("/problems/:id/revisions/:number/diff", controllers.DiffRevisions)
+	api.Post("/problems/:id/revisions/:number/rollback", controllers.RollbackRevision)
 	api.Post("/problems/generate-output", controllers.GenerateOutput)
 
 	// Sharing
@@ -42,6 +62,7 @@ func SetupRoutes(app *fiber.App) {
 	api.Post("/submit", controllers.SubmitSolution)
 	api.Get("/history", controllers.GetHistory)
 	api.Get("/submission/:id", controllers.GetSubmission)
+	api.Get("/submission/:id/stream", controllers.StreamSubmission)
 	api.Get("/profile", controllers.GetProfile)
 
 	// Contest Management
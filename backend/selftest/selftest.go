@@ -14,28 +14,56 @@ func Run() {
 
 	time.Sleep(2 * time.Second)
 
-	tests := []struct {
-		LangID int
-		Name   string
-		Code   string
+	languages := []struct {
+		Language string
+		Name     string
+		Code     string
 	}{
-		{71, "Python 3.8", "print('test')"},
-		{54, "C++ (GCC)", "#include <iostream>\nint main() { std::cout << \"test\"; return 0; }"},
-		{62, "Java (OpenJDK)", "public class Main { public static void main(String[] args) { System.out.print(\"test\"); } }"},
-		{60, "Go", "package main\nimport \"fmt\"\nfunc main() { fmt.Print(\"test\") }"},
-		{63, "Node.js", "console.log('test')"},
+		{"python", "Python 3.12", "print('test')"},
+		{"pypy", "PyPy 3.10", "print('test')"},
+		{"c", "C (GCC)", "#include <stdio.h>\nint main() { printf(\"test\"); return 0; }"},
+		{"cpp", "C++ (GCC)", "#include <iostream>\nint main() { std::cout << \"test\"; return 0; }"},
+		{"java", "Java (OpenJDK)", "public class Main { public static void main(String[] args) { System.out.print(\"test\"); } }"},
+		{"kotlin", "Kotlin", "fun main() { print(\"test\") }"},
+		{"csharp", "C# (Mono)", "using System;\nclass Program { static void Main() { Console.Write(\"test\"); } }"},
+		{"go", "Go", "package main\nimport \"fmt\"\nfunc main() { fmt.Print(\"test\") }"},
+		{"rust", "Rust", "fn main() { print!(\"test\"); }"},
+		{"javascript", "Node.js", "console.log('test')"},
+	}
+
+	tests := make([]selfTest, 0, len(languages)+len(containmentTests)+len(forkBombTests))
+	for _, language := range languages {
+		tests = append(tests, selfTest{Language: language.Language, Name: language.Name, Code: language.Code})
+	}
+	tests = append(tests, containmentTests...)
+	// Without a pids limit a fork bomb is not contained, it would run on the host
+	if compiler.ProcessesLimited() {
+		tests = append(tests, forkBombTests...)
+	} else {
+		for _, test := range forkBombTests {
+			fmt.Printf("⏭️  ПРОПУЩЕНО [%s]: песочница не ограничивает число процессов\n", test.Name)
+		}
 	}
 
 	hasErrors := false
 
 	for _, test := range tests {
-		fmt.Printf("⏳ Testing %s (ID: %d)...\n", test.Name, test.LangID)
+		fmt.Printf("⏳ Testing %s (%s)...\n", test.Name, test.Language)
+
+		timeLimit := test.TimeLimit
+		if timeLimit == 0 {
+			timeLimit = 30.0 // Increased time limit for first pull
+		}
+		want := test.Outcome
+		if want == "" {
+			want = compiler.OutcomeOK
+		}
 
 		submission := compiler.CompilerSubmission{
-			LanguageID:  test.LangID,
+			Language:    test.Language,
 			SourceCode:  test.Code,
-			Stdin:       "",
-			TimeLimit:   30.0, // Increased time limit for first pull
+			Stdin:       test.Stdin,
+			TimeLimit:   timeLimit,
 			MemoryLimit: 512,
 		}
 
@@ -49,11 +77,21 @@ func Run() {
 			continue
 		}
 
-		if result.Stderr != "" {
-			fmt.Printf("❌ ОШИБКА [%s] (Stderr): %s\n", test.Name, result.Stderr)
+		if result.Outcome == compiler.OutcomeCompilationError {
+			fmt.Printf("❌ ОШИБКА [%s] (Compilation): %s\n", test.Name, result.Compile.Output)
+			hasErrors = true
+			continue
+		}
+
+		if result.Outcome != want {
+			fmt.Printf("❌ ОШИБКА [%s] (%s вместо %s, exit code %d): %s\n", test.Name, result.Outcome, want, result.ExitCode, result.Stderr)
 			hasErrors = true
 			continue
 		}
+		if want != compiler.OutcomeOK {
+			fmt.Printf("✅ УСПЕШНО [%s] (%s, %s)\n", test.Name, result.Outcome, duration)
+			continue
+		}
 
 		output := result.Stdout
 		// Trim newline for comparison
@@ -79,3 +117,48 @@ func Run() {
 	}
 	fmt.Println("==========================================")
 }
+
+// selfTest must end with Outcome (OK when empty); OK runs must print "test"
+type selfTest struct {
+	Language  string
+	Name      string
+	Code      string
+	Stdin     string
+	Outcome   compiler.Outcome
+	TimeLimit float64
+}
+
+// containmentTests check that the sandbox contains hostile programs, instead of relying on what the code looks like
+var containmentTests = []selfTest{
+	{Language: "python", Name: "Reading stdin with open(0)", Code: "print(open(0).read().strip())", Stdin: "test"},
+	{Language: "python", Name: "File writes outside the work dir", Code: forbiddenWrites},
+}
+
+// forkBombTests only run when the backend enforces a pids limit
+var forkBombTests = []selfTest{
+	{Language: "python", Name: "Fork bomb", Code: forkBomb, Outcome: compiler.OutcomeTimeLimit, TimeLimit: 1},
+	{Language: "c", Name: "Fork bomb (C)", Code: "#include <unistd.h>\nint main() { for (;;) fork(); }", Outcome: compiler.OutcomeTimeLimit, TimeLimit: 1},
+}
+
+// forbiddenWrites tries to modify the judge helper, its stats, the toolchain and system files
+const forbiddenWrites = `import os
+targets = ['/judge/judge-run', '/judge/stats.json', '/usr/local/bin/python3', '/etc/passwd', '/root/x']
+escaped = []
+for path in targets:
+    try:
+        with open(path, 'a') as f:
+            f.write('x')
+        escaped.append(path)
+    except OSError:
+        pass
+print('test' if not escaped else 'escaped: ' + ', '.join(escaped))
+`
+
+// forkBomb must hit the process limit and then the time limit, not take the host down
+const forkBomb = `import os
+while True:
+    try:
+        os.fork()
+    except OSError:
+        pass
+`
@@ -0,0 +1,227 @@
+// Package comparator implements the built-in ways of comparing a contestant's output
+// with the jury answer, for problems that do not need a custom checker.
+package comparator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mode selects how outputs are compared
+type Mode string
+
+const (
+	ModeExact           Mode = "exact"            // Line by line; trailing spaces and trailing empty lines are ignored
+	ModeTokens          Mode = "tokens"           // Whitespace-separated tokens, any amount of whitespace between them
+	ModeFloat           Mode = "float"            // Tokens; numbers may differ by an absolute or relative epsilon
+	ModeCaseInsensitive Mode = "case-insensitive" // Tokens, ignoring letter case
+	ModeLineSet         Mode = "line-set"         // Same set of non-empty lines in any order, duplicates ignored
+	ModeLineMultiset    Mode = "line-multiset"    // Same non-empty lines in any order, duplicates counted
+)
+
+// DefaultMode is used when a problem has no mode set
+const DefaultMode = ModeTokens
+
+// DefaultEpsilon is used by ModeFloat when a problem has no epsilon set
+const DefaultEpsilon = 1e-6
+
+// Modes lists every supported mode
+var Modes = []Mode{ModeExact, ModeTokens, ModeFloat, ModeCaseInsensitive, ModeLineSet, ModeLineMultiset}
+
+// Verdict is the outcome of a comparison
+type Verdict int
+
+const (
+	Accepted Verdict = iota
+	WrongAnswer
+	PresentationError // The answer is right but formatted differently than required
+)
+
+// Result is a verdict with a short explanation for the jury
+type Result struct {
+	Verdict Verdict
+	Message string
+}
+
+// Valid reports whether the mode is supported; the empty mode means DefaultMode
+func Valid(mode Mode) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare checks output against the expected answer.
+// epsilon is only used by ModeFloat; zero or less means DefaultEpsilon.
+func Compare(mode Mode, epsilon float64, output, expected string) Result {
+	switch mode {
+	case ModeExact:
+		return compareExact(output, expected)
+	case ModeFloat:
+		if epsilon <= 0 {
+			epsilon = DefaultEpsilon
+		}
+		return compareTokens(output, expected, func(a, b string) bool { return floatEqual(a, b, epsilon) })
+	case ModeCaseInsensitive:
+		return compareTokens(output, expected, strings.EqualFold)
+	case ModeLineSet:
+		return compareLines(output, expected, true)
+	case ModeLineMultiset:
+		return compareLines(output, expected, false)
+	default:
+		return compareTokens(output, expected, func(a, b string) bool { return a == b })
+	}
+}
+
+func compareExact(output, expected string) Result {
+	outputLines := normalizeLines(output)
+	expectedLines := normalizeLines(expected)
+
+	for i := 0; i < len(outputLines) && i < len(expectedLines); i++ {
+		if outputLines[i] != expectedLines[i] {
+			return formatMismatch(output, expected, fmt.Sprintf("line %d: expected %q, found %q", i+1, short(expectedLines[i]), short(outputLines[i])))
+		}
+	}
+	if len(outputLines) != len(expectedLines) {
+		return formatMismatch(output, expected, fmt.Sprintf("expected %d lines, found %d", len(expectedLines), len(outputLines)))
+	}
+	return Result{Verdict: Accepted, Message: fmt.Sprintf("%d lines", len(outputLines))}
+}
+
+// formatMismatch tells a wrong answer from a right answer that only differs in whitespace
+func formatMismatch(output, expected, message string) Result {
+	if tokensEqual(strings.Fields(output), strings.Fields(expected)) {
+		return Result{Verdict: PresentationError, Message: "whitespace differs: " + message}
+	}
+	return Result{Verdict: WrongAnswer, Message: message}
+}
+
+func compareTokens(output, expected string, equal func(a, b string) bool) Result {
+	outputTokens := strings.Fields(output)
+	expectedTokens := strings.Fields(expected)
+
+	for i := 0; i < len(outputTokens) && i < len(expectedTokens); i++ {
+		if !equal(outputTokens[i], expectedTokens[i]) {
+			return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("token %d: expected %q, found %q", i+1, short(expectedTokens[i]), short(outputTokens[i]))}
+		}
+	}
+	if len(outputTokens) < len(expectedTokens) {
+		return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("answer too short: expected %d tokens, found %d", len(expectedTokens), len(outputTokens))}
+	}
+	if len(outputTokens) > len(expectedTokens) {
+		return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("extra output: expected %d tokens, found %d", len(expectedTokens), len(outputTokens))}
+	}
+	return Result{Verdict: Accepted, Message: fmt.Sprintf("%d tokens", len(outputTokens))}
+}
+
+func compareLines(output, expected string, set bool) Result {
+	outputLines := nonEmptyLines(output)
+	expectedLines := nonEmptyLines(expected)
+	if set {
+		outputLines = unique(outputLines)
+		expectedLines = unique(expectedLines)
+	}
+	sort.Strings(outputLines)
+	sort.Strings(expectedLines)
+
+	// Walk both sorted lists to find the first line present in only one of them
+	i, j := 0, 0
+	for i < len(outputLines) && j < len(expectedLines) {
+		switch {
+		case outputLines[i] == expectedLines[j]:
+			i++
+			j++
+		case outputLines[i] < expectedLines[j]:
+			return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("unexpected line %q", short(outputLines[i]))}
+		default:
+			return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("missing line %q", short(expectedLines[j]))}
+		}
+	}
+	if i < len(outputLines) {
+		return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("unexpected line %q", short(outputLines[i]))}
+	}
+	if j < len(expectedLines) {
+		return Result{Verdict: WrongAnswer, Message: fmt.Sprintf("missing line %q", short(expectedLines[j]))}
+	}
+	return Result{Verdict: Accepted, Message: fmt.Sprintf("%d lines", len(outputLines))}
+}
+
+// floatEqual compares numbers with an absolute or relative tolerance and anything else exactly
+func floatEqual(a, b string, epsilon float64) bool {
+	if a == b {
+		return true
+	}
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil || math.IsNaN(x) || math.IsNaN(y) {
+		return false
+	}
+	if math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return x == y
+	}
+	diff := math.Abs(x - y)
+	return diff <= epsilon || diff <= epsilon*math.Abs(y)
+}
+
+func tokensEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeLines splits into lines without trailing whitespace, dropping trailing empty lines
+func normalizeLines(s string) []string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func unique(lines []string) []string {
+	seen := make(map[string]bool, len(lines))
+	result := lines[:0]
+	for _, line := range lines {
+		if !seen[line] {
+			seen[line] = true
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// short keeps messages readable when a token or line is huge
+func short(s string) string {
+	const limit = 64
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "..."
+}
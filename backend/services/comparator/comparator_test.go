@@ -0,0 +1,93 @@
+package comparator
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     Mode
+		epsilon  float64
+		output   string
+		expected string
+		verdict  Verdict
+	}{
+		// Exact: line by line, only trailing whitespace is forgiven
+		{"exact equal", ModeExact, 0, "1 2\n3\n", "1 2\n3\n", Accepted},
+		{"exact missing trailing newline", ModeExact, 0, "1 2\n3", "1 2\n3\n", Accepted},
+		{"exact extra trailing newlines", ModeExact, 0, "1 2\n3\n\n\n", "1 2\n3", Accepted},
+		{"exact CR-LF", ModeExact, 0, "1 2\r\n3\r\n", "1 2\n3\n", Accepted},
+		{"exact trailing spaces", ModeExact, 0, "1 2  \n3\t\n", "1 2\n3\n", Accepted},
+		{"exact empty", ModeExact, 0, "", "", Accepted},
+		{"exact empty output", ModeExact, 0, "", "1\n", WrongAnswer},
+		{"exact inner spaces are PE", ModeExact, 0, "1  2\n3\n", "1 2\n3\n", PresentationError},
+		{"exact line break moved is PE", ModeExact, 0, "1\n2 3\n", "1 2\n3\n", PresentationError},
+		{"exact leading space is PE", ModeExact, 0, " 1 2\n3\n", "1 2\n3\n", PresentationError},
+		{"exact different value is WA", ModeExact, 0, "1 2\n4\n", "1 2\n3\n", WrongAnswer},
+		{"exact extra line is WA", ModeExact, 0, "1 2\n3\n4\n", "1 2\n3\n", WrongAnswer},
+
+		// Tokens: any whitespace between tokens
+		{"tokens equal", ModeTokens, 0, "1 2 3", "1 2 3", Accepted},
+		{"tokens whitespace differs", ModeTokens, 0, "1\n2\t\t3\r\n\n", "1 2 3\n", Accepted},
+		{"tokens empty", ModeTokens, 0, "", "\n", Accepted},
+		{"tokens empty output", ModeTokens, 0, "", "1", WrongAnswer},
+		{"tokens too short", ModeTokens, 0, "1 2", "1 2 3", WrongAnswer},
+		{"tokens extra output", ModeTokens, 0, "1 2 3 4", "1 2 3", WrongAnswer},
+		{"tokens numbers compared as text", ModeTokens, 0, "1.0", "1", WrongAnswer},
+		{"tokens case matters", ModeTokens, 0, "YES", "yes", WrongAnswer},
+		{"empty mode is tokens", "", 0, "1\n2", "1 2", Accepted},
+
+		// Case-insensitive tokens
+		{"case-insensitive equal", ModeCaseInsensitive, 0, "YES\nNo", "yes no", Accepted},
+		{"case-insensitive different", ModeCaseInsensitive, 0, "YES", "NO", WrongAnswer},
+		{"case-insensitive too short", ModeCaseInsensitive, 0, "yes", "yes yes", WrongAnswer},
+
+		// Float: absolute or relative epsilon
+		{"float default epsilon", ModeFloat, 0, "0.3333333", "0.333333333", Accepted},
+		{"float default epsilon exceeded", ModeFloat, 0, "0.33333", "0.333333333", WrongAnswer},
+		{"float at absolute boundary", ModeFloat, 0.5, "0.5", "0", Accepted},
+		{"float past absolute boundary", ModeFloat, 0.5, "0.75", "0", WrongAnswer},
+		{"float at relative boundary", ModeFloat, 0.25, "5", "4", Accepted},
+		{"float past relative boundary", ModeFloat, 0.25, "5.5", "4", WrongAnswer},
+		{"float relative to the answer only", ModeFloat, 0.25, "4", "5.5", WrongAnswer},
+		{"float large relative", ModeFloat, 1e-6, "1000000.5", "1000000", Accepted},
+		{"float exponent notation", ModeFloat, 1e-9, "1e3", "1000.0", Accepted},
+		{"float words compared exactly", ModeFloat, 0.5, "yes", "YES", WrongAnswer},
+		{"float word against number", ModeFloat, 0.5, "abc", "1", WrongAnswer},
+		{"float NaN spelled the same", ModeFloat, 0, "nan", "nan", Accepted},
+		{"float NaN never equals", ModeFloat, 0, "NaN", "nan", WrongAnswer},
+		{"float NaN against number", ModeFloat, 1e9, "nan", "1", WrongAnswer},
+		{"float Inf equal", ModeFloat, 0, "+Inf", "inf", Accepted},
+		{"float Inf signs differ", ModeFloat, 0, "-inf", "inf", WrongAnswer},
+		{"float Inf against huge number", ModeFloat, 1e-6, "inf", "1e308", WrongAnswer},
+		{"float empty output", ModeFloat, 0, "", "1.5", WrongAnswer},
+
+		// Line sets and multisets
+		{"line-set any order", ModeLineSet, 0, "b\na\n", "a\nb", Accepted},
+		{"line-set duplicates ignored", ModeLineSet, 0, "a\na\nb\n", "b\na", Accepted},
+		{"line-set missing line", ModeLineSet, 0, "a\n", "a\nb", WrongAnswer},
+		{"line-set unexpected line", ModeLineSet, 0, "a\nb\nc\n", "a\nb", WrongAnswer},
+		{"line-multiset any order", ModeLineMultiset, 0, "b\r\na\n\n", "a\nb", Accepted},
+		{"line-multiset duplicates counted", ModeLineMultiset, 0, "a\na\nb\n", "b\na", WrongAnswer},
+		{"line-multiset empty", ModeLineMultiset, 0, "\n\n", "", Accepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Compare(tt.mode, tt.epsilon, tt.output, tt.expected)
+			if result.Verdict != tt.verdict {
+				t.Errorf("Compare(%q, %g, %q, %q) = %d (%s), want %d", tt.mode, tt.epsilon, tt.output, tt.expected, result.Verdict, result.Message, tt.verdict)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, mode := range append(Modes, "") {
+		if !Valid(mode) {
+			t.Errorf("Valid(%q) = false, want true", mode)
+		}
+	}
+	if Valid("fuzzy") {
+		t.Error(`Valid("fuzzy") = true, want false`)
+	}
+}
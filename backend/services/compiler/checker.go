@@ -0,0 +1,176 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"onlineJudge/backend/config"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CheckVerdict is a checker's opinion of one contestant output
+type CheckVerdict string
+
+const (
+	CheckOK                CheckVerdict = "OK"
+	CheckWrongAnswer       CheckVerdict = "WA"
+	CheckPresentationError CheckVerdict = "PE"
+	CheckPartial           CheckVerdict = "PC"   // Partially correct, see CheckResult.Score
+	CheckFailed            CheckVerdict = "FAIL" // The checker crashed or reported a jury error
+)
+
+// Exit codes of the testlib protocol
+const (
+	checkerExitOK   = 0
+	checkerExitWA   = 1
+	checkerExitPE   = 2
+	checkerExitFail = 3
+
+	checkerExitPoints  = 7  // quitp: the message starts with the score as a fraction of the test
+	checkerExitPartial = 16 // _pc(n) exits with 16+n, n being the percentage of the test from 0 to 100
+)
+
+// Checkers get fixed limits: problem limits apply to contestants, not to the jury
+const (
+	checkerTimeLimit   = 10.0
+	checkerMemoryLimit = 256
+)
+
+// CheckResult is the verdict of a checker with its message for the jury
+type CheckResult struct {
+	Verdict CheckVerdict
+	Message string
+	Score   float64 // Fraction of the test passed: 1 for OK, 0 for a rejected answer
+}
+
+// Checker is a compiled special judge. It follows the testlib protocol:
+// it is run as `checker input.txt output.txt answer.txt` and reports through its exit code,
+// with a human-readable message on stderr.
+type Checker struct {
+	session *Session
+}
+
+// NewChecker compiles the checker in its own sandbox. The caller must Close it.
+func NewChecker(source, language string) (*Checker, error) {
+	session, err := newJurySession(source, language, "checker")
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{session: session}, nil
+}
+
+// newJurySession compiles a checker or interactor; role names it in errors
+func newJurySession(source, language, role string) (*Session, error) {
+	session, err := NewSession(CompilerSubmission{
+		SourceCode:  source,
+		Language:    language,
+		TimeLimit:   checkerTimeLimit,
+		MemoryLimit: checkerMemoryLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Most checkers are written against testlib.h, which no compiler image ships
+	if language == "c" || language == "cpp" {
+		if testlib, err := os.ReadFile(config.CheckerTestlib); err == nil {
+			if err := session.sandbox.WriteFile("testlib.h", bytes.NewReader(testlib)); err != nil {
+				session.Close()
+				return nil, err
+			}
+		}
+	}
+
+	compileResult, err := session.Compile()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if compileResult != nil && !compileResult.Success {
+		session.Close()
+		return nil, fmt.Errorf("%s compilation failed: %s", role, compileResult.Output)
+	}
+
+	return session, nil
+}
+
+// Check runs the checker on one test; the test files are streamed into its sandbox
+func (c *Checker) Check(input io.Reader, output string, answer io.Reader) (CheckResult, error) {
+	result, err := c.session.Exec(map[string]io.Reader{
+		"input.txt":  input,
+		"output.txt": strings.NewReader(output),
+		"answer.txt": answer,
+	}, []string{"input.txt", "output.txt", "answer.txt"}, strings.NewReader(""))
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	return checkResultOf(result, "checker"), nil
+}
+
+// checkResultOf reads a testlib verdict from the exit code and message of a checker or interactor
+func checkResultOf(result ExecutionResult, role string) CheckResult {
+	message := strings.TrimSpace(result.Stderr)
+	if message == "" {
+		message = strings.TrimSpace(result.Stdout)
+	}
+
+	switch {
+	case result.Outcome == OutcomeOK:
+		return CheckResult{Verdict: CheckOK, Message: message, Score: 1}
+	case result.Outcome != OutcomeRuntimeError || result.Signal != 0:
+		return CheckResult{Verdict: CheckFailed, Message: fmt.Sprintf("%s %s: %s", role, result.Outcome, message)}
+	}
+
+	switch result.ExitCode {
+	case checkerExitWA:
+		return CheckResult{Verdict: CheckWrongAnswer, Message: message}
+	case checkerExitPE:
+		return CheckResult{Verdict: CheckPresentationError, Message: message}
+	case checkerExitFail:
+		return CheckResult{Verdict: CheckFailed, Message: message}
+	case checkerExitPoints:
+		return partialResult(pointsOf(message), message)
+	default:
+		// Codes past _pc(100), such as Python's 120 for a failed flush at exit, are not scores
+		if result.ExitCode >= checkerExitPartial && result.ExitCode <= checkerExitPartial+100 {
+			return partialResult(float64(result.ExitCode-checkerExitPartial)/100, message)
+		}
+		return CheckResult{Verdict: CheckWrongAnswer, Message: message}
+	}
+}
+
+// partialResult clamps a partial score; a full or zero score is reported as OK or WA
+func partialResult(score float64, message string) CheckResult {
+	switch {
+	case score >= 1:
+		return CheckResult{Verdict: CheckOK, Message: message, Score: 1}
+	case score <= 0:
+		return CheckResult{Verdict: CheckWrongAnswer, Message: message}
+	default:
+		return CheckResult{Verdict: CheckPartial, Message: message, Score: score}
+	}
+}
+
+// pointsOf reads the score testlib's quitp puts in front of the message, e.g. "points 0.5 ok"
+func pointsOf(message string) float64 {
+	fields := strings.Fields(message)
+	if len(fields) > 0 && fields[0] == "points" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return 0
+	}
+	score, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return score
+}
+
+// Close destroys the checker's sandbox
+func (c *Checker) Close() {
+	c.session.Close()
+}
@@ -0,0 +1,31 @@
+package compiler
+
+import "testing"
+
+func TestCheckResultOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  ExecutionResult
+		verdict CheckVerdict
+		score   float64
+	}{
+		{"ok", ExecutionResult{Outcome: OutcomeOK}, CheckOK, 1},
+		{"wrong answer", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: checkerExitWA}, CheckWrongAnswer, 0},
+		{"presentation error", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: checkerExitPE}, CheckPresentationError, 0},
+		{"fail", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: checkerExitFail}, CheckFailed, 0},
+		{"points", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: checkerExitPoints, Stderr: "points 0.25 close"}, CheckPartial, 0.25},
+		{"partial", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: checkerExitPartial + 40}, CheckPartial, 0.4},
+		{"partial full score", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: checkerExitPartial + 100}, CheckOK, 1},
+		{"past the partial range", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: 120}, CheckWrongAnswer, 0},
+		{"killed", ExecutionResult{Outcome: OutcomeRuntimeError, ExitCode: 137, Signal: 9}, CheckFailed, 0},
+		{"time limit", ExecutionResult{Outcome: OutcomeTimeLimit}, CheckFailed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := checkResultOf(tt.result, "checker")
+			if check.Verdict != tt.verdict || check.Score != tt.score {
+				t.Errorf("verdict %s, score %g; want %s, %g", check.Verdict, check.Score, tt.verdict, tt.score)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"onlineJudge/backend/services/compiler/runner"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// dockerSandbox runs the session in a warm container taken from the pool
+type dockerSandbox struct {
+	lang        languageSpec
+	ctx         context.Context
+	cli         *client.Client
+	containerID string
+	memory      int64 // Current memory limit of the container
+}
+
+func (d *dockerSandbox) Prepare(files map[string][]byte) error {
+	var err error
+	d.ctx = context.Background()
+
+	d.cli, err = dockerClient()
+	if err != nil {
+		return err
+	}
+
+	d.containerID, err = poolFor(d.lang).acquire(d.ctx, d.cli)
+	if err != nil {
+		return err
+	}
+	d.memory = compileMemory // Pool containers start with the default compile limit
+
+	return writeFiles(d, files)
+}
+
+func (d *dockerSandbox) WriteFile(name string, content io.Reader) error {
+	if err := writeFile(d.ctx, d.cli, d.containerID, "", workDir+"/"+name, content, "0644"); err != nil {
+		return fmt.Errorf("failed to copy %s: %v", name, err)
+	}
+	return nil
+}
+
+// Compile runs the compiler under judge-run, like a program, so that a compile bomb
+// is stopped by the CPU and wall-clock limits instead of blocking the worker
+func (d *dockerSandbox) Compile(cmd []string, limits Limits) (CompileResult, error) {
+	return compileStreamed(d, cmd, limits)
+}
+
+func (d *dockerSandbox) Run(cmd []string, stdin io.Reader, limits Limits) (RunOutput, error) {
+	return runStreamed(d, cmd, stdin, limits)
+}
+
+// Start runs cmd under judge-run in the container with attached stdio
+func (d *dockerSandbox) Start(cmd []string, limits Limits) (Process, error) {
+	return d.start(cmd, limits, sandboxUserArgs)
+}
+
+// StartConnected runs cmd under judge-run with the channel, which the shared volume makes
+// visible to the other container too
+func (d *dockerSandbox) StartConnected(cmd []string, limits Limits, stdin, stdout string) (Process, error) {
+	return d.start(cmd, limits, append(channelArgs(stdin, stdout), sandboxUserArgs...))
+}
+
+func (d *dockerSandbox) start(cmd []string, limits Limits, extra []string) (Process, error) {
+	if err := d.setMemory(limits.Memory); err != nil {
+		return nil, err
+	}
+
+	execIDResp, err := d.cli.ContainerExecCreate(d.ctx, d.containerID, types.ExecConfig{
+		Cmd:          helperArgs(helperDir+"/"+helperName, statsFile, limits, extra, cmd),
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		WorkingDir:   workDir,
+		Env:          d.lang.Env,
+		User:         "0", // judge-run writes the stats as root and drops to the sandbox user for the program
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec for run: %v", err)
+	}
+
+	attach, err := d.cli.ContainerExecAttach(d.ctx, execIDResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach exec for run: %v", err)
+	}
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	p := &dockerProcess{
+		sandbox: d,
+		attach:  attach,
+		stdout:  stdoutReader,
+		stderr:  limitedBuffer{limit: diagnosticsLimit},
+		done:    make(chan struct{}),
+		// judge-run enforces the limits itself; this only guards against a hung Docker exec
+		timeout: time.Duration(limits.WallTime*1000)*time.Millisecond + 5*time.Second,
+	}
+	go func() {
+		stdcopy.StdCopy(stdoutWriter, &p.stderr, attach.Reader)
+		stdoutWriter.Close()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Cleanup gives the container back to the pool, which destroys and replaces it
+func (d *dockerSandbox) Cleanup() {
+	if d.containerID != "" {
+		release(d.cli, d.containerID)
+		d.containerID = ""
+	}
+}
+
+// setMemory changes the memory limit of the running container
+func (d *dockerSandbox) setMemory(limit int64) error {
+	if limit == d.memory {
+		return nil
+	}
+	_, err := d.cli.ContainerUpdate(d.ctx, d.containerID, container.UpdateConfig{
+		Resources: container.Resources{Memory: limit, MemorySwap: limit},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply memory limit: %v", err)
+	}
+	d.memory = limit
+	return nil
+}
+
+// exec runs a command in the container to completion and returns its exit code
+func (d *dockerSandbox) exec(cmd []string, stdout, stderr io.Writer) (int, error) {
+	execIDResp, err := d.cli.ContainerExecCreate(d.ctx, d.containerID, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+		WorkingDir:   workDir,
+		Env:          d.lang.Env,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := d.cli.ContainerExecAttach(d.ctx, execIDResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, resp.Reader); err != nil {
+		return 0, err
+	}
+
+	inspectResp, err := d.cli.ContainerExecInspect(d.ctx, execIDResp.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspectResp.ExitCode, nil
+}
+
+// dockerProcess is a judge-run exec with its stdio attached over the Docker API
+type dockerProcess struct {
+	sandbox *dockerSandbox
+	attach  types.HijackedResponse
+	stdout  *io.PipeReader
+	stderr  limitedBuffer
+	done    chan struct{} // Closed when the output streams end
+	timeout time.Duration
+}
+
+func (p *dockerProcess) Stdin() io.WriteCloser { return execStdin{p.attach} }
+func (p *dockerProcess) Stdout() io.Reader     { return p.stdout }
+
+func (p *dockerProcess) Wait() (RunOutput, error) {
+	defer p.attach.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(p.timeout):
+		return RunOutput{Hung: true}, nil
+	}
+
+	output := RunOutput{Stderr: p.stderr.String()}
+	stats, err := p.sandbox.readStats()
+	if err != nil {
+		return output, fmt.Errorf("failed to read run stats: %v", err)
+	}
+	output.Stats = stats
+	return output, nil
+}
+
+// execStdin closes only the write side of the hijacked connection, so output can still be read
+type execStdin struct {
+	attach types.HijackedResponse
+}
+
+func (s execStdin) Write(data []byte) (int, error) { return s.attach.Conn.Write(data) }
+func (s execStdin) Close() error                   { return s.attach.CloseWrite() }
+
+// readStats fetches the resource usage judge-run recorded for the last run
+func (d *dockerSandbox) readStats() (runner.Stats, error) {
+	var stats runner.Stats
+
+	var out, errOut bytes.Buffer
+	if _, err := d.exec([]string{"cat", statsFile}, &out, &errOut); err != nil {
+		return stats, err
+	}
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		return stats, fmt.Errorf("invalid stats %q: %v", out.String()+errOut.String(), err)
+	}
+	return stats, nil
+}
+
+func ensureImage(ctx context.Context, cli *client.Client, imageName string) error {
+	_, _, err := cli.ImageInspectWithRaw(ctx, imageName)
+	if err == nil {
+		return nil
+	}
+
+	reader, err := cli.ImagePull(ctx, imageName, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	io.Copy(io.Discard, reader)
+	return nil
+}
+
+// writeFile streams content into a file through a shell in the container. The copy API
+// cannot be used: it refuses a read-only root filesystem and does not see tmpfs mounts.
+// An empty user is the container's sandbox user.
+func writeFile(ctx context.Context, cli *client.Client, containerID, user, path string, content io.Reader, mode string) error {
+	execIDResp, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          []string{"sh", "-c", `cat > "$0" && chmod "$1" "$0"`, path, mode},
+		User:         user,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	attach, err := cli.ContainerExecAttach(ctx, execIDResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return err
+	}
+	defer attach.Close()
+
+	go func() {
+		io.Copy(attach.Conn, content)
+		attach.CloseWrite()
+	}()
+
+	output := limitedBuffer{limit: diagnosticsLimit}
+	if _, err := stdcopy.StdCopy(&output, &output, attach.Reader); err != nil {
+		return err
+	}
+
+	inspectResp, err := cli.ContainerExecInspect(ctx, execIDResp.ID)
+	if err != nil {
+		return err
+	}
+	if inspectResp.ExitCode != 0 {
+		return fmt.Errorf("exit code %d: %s", inspectResp.ExitCode, output.String())
+	}
+	return nil
+}
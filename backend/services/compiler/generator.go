@@ -0,0 +1,39 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Generator is a compiled test generator. Following testlib, it is run with the arguments
+// of a script line, e.g. `gen 100000 7`, and prints one test input to stdout. Generators
+// must be deterministic: the same arguments always give the same test.
+type Generator struct {
+	session *Session
+}
+
+// NewGenerator compiles the generator in its own sandbox. The caller must Close it.
+func NewGenerator(source, language string) (*Generator, error) {
+	session, err := newJurySession(source, language, "generator")
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{session: session}, nil
+}
+
+// Generate runs the generator with args and returns the test it printed
+func (g *Generator) Generate(args []string) (string, error) {
+	result, err := g.session.Exec(nil, args, strings.NewReader(""))
+	if err != nil {
+		return "", err
+	}
+	if result.Outcome != OutcomeOK {
+		return "", fmt.Errorf("generator %s: %s", result.Outcome, Preview(strings.TrimSpace(result.Stderr), 1024))
+	}
+	return result.Stdout, nil
+}
+
+// Close destroys the generator's sandbox
+func (g *Generator) Close() {
+	g.session.Close()
+}
@@ -0,0 +1,48 @@
+package compiler
+
+import (
+	"fmt"
+	"onlineJudge/backend/config"
+	"os"
+	"sync"
+)
+
+const (
+	// The helper and its stats live in a root-owned tmpfs the sandbox user cannot write to
+	helperDir  = "/judge"
+	helperName = "judge-run"
+	statsFile  = "/judge/stats.json"
+
+	// workDir is the sandbox user's tmpfs holding the source and build output
+	workDir = "/app"
+
+	// channelDir is a volume shared by all sandbox containers, holding the FIFOs of interactive runs.
+	// The channel directories in it belong to root, so only judge-run can open the FIFOs.
+	channelDir    = "/interaction"
+	channelVolume = "onlinejudge-interaction"
+
+	// sandboxUID and sandboxGID ("nobody") run compilers and contestant programs in containers
+	sandboxUID = 65534
+	sandboxGID = 65534
+
+	// compileMemory and compileTimeLimit (seconds of CPU) are the limits while the compiler runs
+	compileMemory    = 512 * 1024 * 1024
+	compileTimeLimit = 30.0
+)
+
+var (
+	helperOnce   sync.Once
+	helperBinary []byte
+	helperErr    error
+)
+
+// loadHelper reads the judge-run binary that measures the contestant process inside the container
+func loadHelper() ([]byte, error) {
+	helperOnce.Do(func() {
+		helperBinary, helperErr = os.ReadFile(config.SandboxHelper)
+		if helperErr != nil {
+			helperErr = fmt.Errorf("sandbox helper not available (build ./cmd/judge-run with CGO_ENABLED=0): %v", helperErr)
+		}
+	})
+	return helperBinary, helperErr
+}
@@ -0,0 +1,52 @@
+package compiler
+
+import (
+	"fmt"
+	"onlineJudge/backend/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// helperBuildErr is why judge-run could not be built; sandbox tests are skipped then
+var helperBuildErr error
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "judge-run-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Built like the Dockerfile does, so it also works inside any language image
+	config.SandboxHelper = filepath.Join(dir, helperName)
+	build := exec.Command("go", "build", "-o", config.SandboxHelper, "onlineJudge/backend/cmd/judge-run")
+	build.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if output, err := build.CombinedOutput(); err != nil {
+		helperBuildErr = fmt.Errorf("%v: %s", err, output)
+	}
+	config.SandboxPidsLimit = 64
+	config.SandboxLocalInsecure = true // The tests only run their own programs
+	config.SandboxPoolSize = 0
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// requireHelper skips a test that runs programs when judge-run is not available
+func requireHelper(t *testing.T) {
+	t.Helper()
+	if helperBuildErr != nil {
+		t.Skipf("judge-run could not be built: %v", helperBuildErr)
+	}
+}
+
+// useBackend switches the sandbox backend for the duration of a test
+func useBackend(t *testing.T, backend string) {
+	t.Helper()
+	previous := config.SandboxBackend
+	config.SandboxBackend = backend
+	t.Cleanup(func() { config.SandboxBackend = previous })
+}
@@ -0,0 +1,124 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Interactor is a compiled interactor of an interactive problem. It follows the testlib protocol:
+// it is run as `interactor input.txt output.txt` with its stdin and stdout cross-wired to the
+// contestant's program, and reports the verdict through its exit code like a checker.
+//
+// The two programs run in separate sandboxes but are connected directly, through a pair of FIFOs
+// in a channel both sandboxes see. Messages do not pass through the judge, so the contestant's
+// limits only see the time the interactor itself takes to answer.
+type Interactor struct {
+	session *Session
+}
+
+// NewInteractor compiles the interactor in its own sandbox. The caller must Close it.
+func NewInteractor(source, language string) (*Interactor, error) {
+	session, err := newJurySession(source, language, "interactor")
+	if err != nil {
+		return nil, err
+	}
+	return &Interactor{session: session}, nil
+}
+
+// Interact runs the contestant's compiled session against the interactor on one test.
+// The contestant's limits are enforced as usual; the interactor's verdict is returned separately.
+func (it *Interactor) Interact(contestant *Session, input io.Reader) (ExecutionResult, CheckResult, error) {
+	if !contestant.compiled {
+		return ExecutionResult{}, CheckResult{}, fmt.Errorf("session must be compiled before running")
+	}
+
+	if err := it.session.sandbox.WriteFile("input.txt", input); err != nil {
+		return ExecutionResult{}, CheckResult{}, err
+	}
+
+	// The interactor mostly waits for the contestant, so it must outlive the contestant's wall-clock limit
+	limits := it.session.runLimits
+	if wall := contestant.runLimits.WallTime + 1; wall > limits.WallTime {
+		limits.WallTime = wall
+	}
+
+	ch, err := newChannel()
+	if err != nil {
+		return ExecutionResult{}, CheckResult{}, err
+	}
+
+	interactor, err := it.session.sandbox.StartConnected(it.session.command([]string{"input.txt", "output.txt"}), limits,
+		ch.toInteractor(), ch.fromInteractor())
+	if err != nil {
+		return ExecutionResult{}, CheckResult{}, fmt.Errorf("failed to start interactor: %v", err)
+	}
+
+	program, err := contestant.sandbox.StartConnected(contestant.command(nil), contestant.runLimits,
+		ch.fromInteractor(), ch.toInteractor())
+	if err != nil {
+		// The interactor's judge-run stops waiting for the contestant at its wall-clock limit
+		interactor.Wait()
+		return ExecutionResult{}, CheckResult{}, err
+	}
+
+	// Both talk through the channel; their own stdio carries nothing
+	go io.Copy(io.Discard, interactor.Stdout())
+	go io.Copy(io.Discard, program.Stdout())
+
+	type waited struct {
+		output RunOutput
+		err    error
+	}
+	programDone := make(chan waited, 1)
+	go func() {
+		output, err := program.Wait()
+		programDone <- waited{output, err}
+	}()
+
+	interactorOutput, err := interactor.Wait()
+	programResult := <-programDone
+	if programResult.err != nil {
+		return ExecutionResult{}, CheckResult{}, programResult.err
+	}
+	if err != nil {
+		return ExecutionResult{}, CheckResult{}, fmt.Errorf("interactor: %v", err)
+	}
+
+	result := contestant.classify(programResult.output)
+	check := checkResultOf(it.session.classify(interactorOutput), "interactor")
+	return result, check, nil
+}
+
+// ExecuteInteractive compiles the submission and the interactor and runs them once against each other,
+// with sub.Stdin as the interactor's input file. Judging several tests should reuse both sessions instead.
+func ExecuteInteractive(sub CompilerSubmission, interactorSource, interactorLanguage string) (ExecutionResult, CheckResult, error) {
+	interactor, err := NewInteractor(interactorSource, interactorLanguage)
+	if err != nil {
+		return ExecutionResult{}, CheckResult{}, err
+	}
+	defer interactor.Close()
+
+	session, err := NewSession(sub)
+	if err != nil {
+		return ExecutionResult{}, CheckResult{}, err
+	}
+	defer session.Close()
+
+	compileResult, err := session.Compile()
+	if err != nil {
+		return ExecutionResult{}, CheckResult{}, err
+	}
+	if compileResult != nil && !compileResult.Success {
+		return ExecutionResult{Outcome: OutcomeCompilationError, Compile: compileResult}, CheckResult{}, nil
+	}
+
+	result, check, err := interactor.Interact(session, strings.NewReader(sub.Stdin))
+	result.Compile = compileResult
+	return result, check, err
+}
+
+// Close destroys the interactor's sandbox
+func (it *Interactor) Close() {
+	it.session.Close()
+}
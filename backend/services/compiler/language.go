@@ -0,0 +1,169 @@
+package compiler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"onlineJudge/backend/config"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// defaultLanguages is the registry used when LANGUAGES_FILE is not set
+//
+//go:embed languages.json
+var defaultLanguages []byte
+
+// memoryPlaceholder in a run command is replaced with the problem's memory limit in MB,
+// for runtimes that need their heap limit on the command line
+const memoryPlaceholder = "{memory_mb}"
+
+// languageSpec describes how to build and run one language inside its image
+type languageSpec struct {
+	Name       string   `json:"name"`   // Key stored on submissions and problems, e.g. "python"
+	Title      string   `json:"title"`  // Shown to users
+	Editor     string   `json:"editor"` // Monaco editor language id
+	Image      string   `json:"image"`
+	FileName   string   `json:"file_name"`
+	CompileCmd []string `json:"compile"` // Empty for interpreted languages
+	RunCmd     []string `json:"run"`
+	Env        []string `json:"env"`
+
+	TimeMultiplier   float64 `json:"time_multiplier"`    // Scales the problem's time limit, 1 if unset
+	MemoryOverheadMB int     `json:"memory_overhead_mb"` // Headroom for the language runtime on top of the problem's limit
+
+	// AddressSpaceOverheadMB replaces MemoryOverheadMB where virtual memory is limited instead (RLIMIT_AS).
+	// Runtimes like the JVM reserve much more address space than they ever touch, on top of their heap.
+	AddressSpaceOverheadMB int `json:"address_space_overhead_mb"`
+}
+
+// Language is the public description of a supported language
+type Language struct {
+	Name   string `json:"name"`
+	Title  string `json:"title"`
+	Editor string `json:"editor"`
+}
+
+// registry holds the languages in configuration order
+var registry = mustParseLanguages(defaultLanguages)
+
+// LoadLanguages replaces the built-in registry with config.LanguagesFile, if set.
+// It must be called before judging starts.
+func LoadLanguages() error {
+	if config.LanguagesFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(config.LanguagesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read language registry: %v", err)
+	}
+	specs, err := parseLanguages(data)
+	if err != nil {
+		return fmt.Errorf("invalid language registry %s: %v", config.LanguagesFile, err)
+	}
+	registry = specs
+	return nil
+}
+
+func mustParseLanguages(data []byte) []languageSpec {
+	specs, err := parseLanguages(data)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in language registry: %v", err))
+	}
+	return specs
+}
+
+func parseLanguages(data []byte) ([]languageSpec, error) {
+	var specs []languageSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i, spec := range specs {
+		switch {
+		case spec.Name == "":
+			return nil, fmt.Errorf("language #%d has no name", i+1)
+		case seen[spec.Name]:
+			return nil, fmt.Errorf("language %q is defined twice", spec.Name)
+		case spec.Image == "" || spec.FileName == "" || len(spec.RunCmd) == 0:
+			return nil, fmt.Errorf("language %q needs an image, a file name and a run command", spec.Name)
+		}
+		seen[spec.Name] = true
+
+		if spec.Title == "" {
+			specs[i].Title = spec.Name
+		}
+		if spec.TimeMultiplier <= 0 {
+			specs[i].TimeMultiplier = 1
+		}
+		if spec.AddressSpaceOverheadMB <= 0 {
+			specs[i].AddressSpaceOverheadMB = spec.MemoryOverheadMB
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no languages defined")
+	}
+	return specs, nil
+}
+
+// Languages lists the supported languages
+func Languages() []Language {
+	languages := make([]Language, 0, len(registry))
+	for _, spec := range registry {
+		languages = append(languages, Language{Name: spec.Name, Title: spec.Title, Editor: spec.Editor})
+	}
+	return languages
+}
+
+// IsSupported reports whether the language is in the registry
+func IsSupported(name string) bool {
+	_, ok := findLanguage(name)
+	return ok
+}
+
+func findLanguage(name string) (languageSpec, bool) {
+	for _, spec := range registry {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return languageSpec{}, false
+}
+
+// SourceExtension is the file extension of sources in the language, e.g. ".cpp"; empty if unsupported
+func SourceExtension(name string) string {
+	spec, ok := findLanguage(name)
+	if !ok {
+		return ""
+	}
+	return path.Ext(spec.FileName)
+}
+
+// BaseTimeLimit is the problem time limit that gives a program in the language cpuSeconds of CPU time,
+// undoing the multiplier of the language
+func BaseTimeLimit(language string, cpuSeconds float64) (float64, error) {
+	spec, ok := findLanguage(language)
+	if !ok {
+		return 0, fmt.Errorf("unsupported language: %q", language)
+	}
+	return cpuSeconds / spec.TimeMultiplier, nil
+}
+
+// languageFor returns the spec of a language; memoryLimitMB is used for runtime heap flags
+func languageFor(name string, memoryLimitMB int) (languageSpec, error) {
+	spec, ok := findLanguage(name)
+	if !ok {
+		return languageSpec{}, fmt.Errorf("unsupported language: %q", name)
+	}
+
+	runCmd := make([]string, len(spec.RunCmd))
+	for i, arg := range spec.RunCmd {
+		runCmd[i] = strings.ReplaceAll(arg, memoryPlaceholder, strconv.Itoa(memoryLimitMB))
+	}
+	spec.RunCmd = runCmd
+	return spec, nil
+}
@@ -0,0 +1,92 @@
+package compiler
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLanguageForHeapLimit(t *testing.T) {
+	tests := []struct {
+		language string
+		flag     string
+	}{
+		{"java", "-Xmx200m"},
+		{"kotlin", "-Xmx200m"},
+		{"javascript", "--max-old-space-size=200"},
+	}
+	for _, tt := range tests {
+		lang, err := languageFor(tt.language, 200)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(lang.RunCmd, tt.flag) {
+			t.Errorf("%s run command %v lacks %s", tt.language, lang.RunCmd, tt.flag)
+		}
+	}
+
+	// The heap follows the problem's limit only, nothing in the environment overrides it
+	for _, lang := range registry {
+		for _, env := range lang.Env {
+			if strings.Contains(env, "-Xmx") || strings.Contains(env, "max-old-space-size") {
+				t.Errorf("%s sets a fixed heap size in %s", lang.Name, env)
+			}
+		}
+		for _, arg := range lang.RunCmd {
+			if strings.Contains(arg, "-Xmx") && !strings.Contains(arg, memoryPlaceholder) {
+				t.Errorf("%s runs with a fixed heap size %s", lang.Name, arg)
+			}
+		}
+	}
+}
+
+// JVM address space reserved outside the capped areas: its libraries, thread stacks and class data sharing
+const jvmBaseAddressSpaceMB = 256
+
+// The JVM reserves its whole heap and more at startup, so under RLIMIT_AS a run whose -Xmx and
+// reservations do not fit fails before main as a runtime error, whatever the program does
+func TestJVMFitsAddressSpace(t *testing.T) {
+	for _, lang := range registry {
+		if !slices.ContainsFunc(lang.RunCmd, func(arg string) bool { return strings.HasPrefix(arg, "-Xmx") }) {
+			continue
+		}
+
+		reserved := jvmBaseAddressSpaceMB
+		for _, flag := range []string{"-XX:ReservedCodeCacheSize=", "-XX:CompressedClassSpaceSize=", "-XX:MaxMetaspaceSize="} {
+			i := slices.IndexFunc(lang.RunCmd, func(arg string) bool { return strings.HasPrefix(arg, flag) })
+			if i < 0 {
+				t.Errorf("%s runs without %s, the JVM default reserves too much", lang.Name, flag)
+				continue
+			}
+			size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(lang.RunCmd[i], flag), "m"))
+			if err != nil {
+				t.Fatalf("%s: %s is not in MB: %v", lang.Name, lang.RunCmd[i], err)
+			}
+			reserved += size
+		}
+
+		// Each glibc malloc arena reserves 64MB
+		i := slices.IndexFunc(lang.Env, func(env string) bool { return strings.HasPrefix(env, "MALLOC_ARENA_MAX=") })
+		if i < 0 {
+			t.Errorf("%s runs without MALLOC_ARENA_MAX, every JVM thread may reserve an arena", lang.Name)
+		} else {
+			arenas, err := strconv.Atoi(strings.TrimPrefix(lang.Env[i], "MALLOC_ARENA_MAX="))
+			if err != nil {
+				t.Fatal(err)
+			}
+			reserved += arenas * 64
+		}
+
+		if reserved > lang.AddressSpaceOverheadMB {
+			t.Errorf("%s reserves %dMB besides its heap, more than its address space overhead of %dMB",
+				lang.Name, reserved, lang.AddressSpaceOverheadMB)
+		}
+	}
+}
+
+func TestLanguageForUnknown(t *testing.T) {
+	if _, err := languageFor("cobol", 256); err == nil {
+		t.Error("languageFor accepted an unknown language")
+	}
+}
@@ -0,0 +1,248 @@
+//go:build linux
+
+package compiler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"onlineJudge/backend/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// localSandbox runs the session directly on the host with the installed toolchains.
+// It only limits resources, through an optional delegated cgroup, rlimits set by judge-run and
+// optional namespaces: the program runs as the backend's user and sees the host's file system,
+// so it can read anything the backend can, including other sessions, test data and credentials.
+// It is therefore only for development and testing and must be enabled with SANDBOX_LOCAL_INSECURE.
+type localSandbox struct {
+	lang      languageSpec
+	workDir   string
+	statsDir  string // Outside workDir, but the program runs as the same user and can write to it
+	cgroupDir string // Empty when no cgroup root is configured
+
+	pidsLimited bool // The cgroup has a pids.max, otherwise judge-run falls back to RLIMIT_NPROC
+}
+
+func newLocalSandbox(lang languageSpec) (Sandbox, error) {
+	if !config.SandboxLocalInsecure {
+		return nil, errLocalInsecure
+	}
+	if _, err := os.Stat(config.SandboxHelper); err != nil {
+		return nil, fmt.Errorf("judge-run helper not available: %v", err)
+	}
+	return &localSandbox{lang: lang}, nil
+}
+
+func (l *localSandbox) Prepare(files map[string][]byte) error {
+	var err error
+	l.workDir, err = os.MkdirTemp("", "judge-work-")
+	if err != nil {
+		return fmt.Errorf("failed to create work dir: %v", err)
+	}
+	l.statsDir, err = os.MkdirTemp("", "judge-stats-")
+	if err != nil {
+		return fmt.Errorf("failed to create stats dir: %v", err)
+	}
+
+	if config.SandboxCgroupRoot != "" {
+		dir, err := os.MkdirTemp(config.SandboxCgroupRoot, "judge-")
+		if err != nil {
+			return fmt.Errorf("failed to create cgroup: %v", err)
+		}
+		l.cgroupDir = dir
+		// Not every delegation includes the pids controller
+		err = os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.Itoa(config.SandboxPidsLimit)), 0644)
+		l.pidsLimited = err == nil
+	}
+
+	return writeFiles(l, files)
+}
+
+func (l *localSandbox) WriteFile(name string, content io.Reader) error {
+	file, err := os.Create(filepath.Join(l.workDir, name))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, content); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
+
+func (l *localSandbox) Compile(cmd []string, limits Limits) (CompileResult, error) {
+	return compileStreamed(l, cmd, limits)
+}
+
+func (l *localSandbox) Run(cmd []string, stdin io.Reader, limits Limits) (RunOutput, error) {
+	return runStreamed(l, cmd, stdin, limits)
+}
+
+// Start launches judge-run on the host around cmd
+func (l *localSandbox) Start(cmd []string, limits Limits) (Process, error) {
+	return l.start(cmd, limits, nil)
+}
+
+// StartConnected launches judge-run with the channel, a directory on the host
+func (l *localSandbox) StartConnected(cmd []string, limits Limits, stdin, stdout string) (Process, error) {
+	return l.start(cmd, limits, channelArgs(stdin, stdout))
+}
+
+func (l *localSandbox) start(cmd []string, limits Limits, extra []string) (Process, error) {
+	statsPath := filepath.Join(l.statsDir, "stats.json")
+	os.Remove(statsPath)
+
+	extra = append(extra, "-cgroup", l.cgroupDir)
+	if l.cgroupDir != "" {
+		if err := l.setMemory(limits.Memory); err != nil {
+			return nil, err
+		}
+	} else {
+		// Without a memory cgroup, virtual memory is the best available approximation
+		addressSpace := limits.AddressSpace
+		if addressSpace == 0 {
+			addressSpace = limits.Memory
+		}
+		extra = append(extra, "-as", strconv.FormatInt(addressSpace, 10))
+	}
+	if !l.pidsLimited {
+		// A weaker stand-in: the rlimit counts all processes of the user, the backend's included
+		extra = append(extra, "-nproc", strconv.Itoa(config.SandboxPidsLimit))
+	}
+
+	// judge-run enforces the limits itself; this only guards against the helper hanging
+	timeout := time.Duration(limits.WallTime*1000)*time.Millisecond + 5*time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	args := helperArgs(config.SandboxHelper, statsPath, limits, extra, cmd)
+	proc := exec.CommandContext(ctx, args[0], args[1:]...)
+	proc.Dir = l.workDir
+	proc.Env = append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + l.workDir}, l.lang.Env...)
+	// Do not wait forever for stray children holding the output pipes
+	proc.WaitDelay = 2 * time.Second
+
+	p := &localProcess{cmd: proc, ctx: ctx, cancel: cancel, statsPath: statsPath, stderr: limitedBuffer{limit: diagnosticsLimit}}
+	stdoutReader, stdoutWriter := io.Pipe()
+	p.stdout, p.stdoutWriter = stdoutReader, stdoutWriter
+	proc.Stdout = stdoutWriter
+	proc.Stderr = &p.stderr
+
+	stdin, err := proc.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	p.stdin = stdin
+
+	attr := &syscall.SysProcAttr{}
+	if config.SandboxNamespaces {
+		// No network and a private view of processes and IPC; the user namespace makes this work unprivileged
+		attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	if l.cgroupDir != "" {
+		cgroup, err := os.Open(l.cgroupDir)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to open cgroup: %v", err)
+		}
+		defer cgroup.Close()
+		attr.UseCgroupFD = true
+		attr.CgroupFD = int(cgroup.Fd())
+	}
+	proc.SysProcAttr = attr
+
+	if err := proc.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start judge-run: %v", err)
+	}
+	return p, nil
+}
+
+// Cleanup removes the work directory and the cgroup
+func (l *localSandbox) Cleanup() {
+	if l.workDir != "" {
+		os.RemoveAll(l.workDir)
+	}
+	if l.statsDir != "" {
+		os.RemoveAll(l.statsDir)
+	}
+	if l.cgroupDir != "" {
+		// The cgroup can only be removed once its processes are gone; judge-run has reaped them by now
+		os.Remove(l.cgroupDir)
+	}
+}
+
+// setMemory writes the memory limit of the session's cgroup, with swap disabled
+func (l *localSandbox) setMemory(limit int64) error {
+	value := []byte(strconv.FormatInt(limit, 10))
+	if err := os.WriteFile(filepath.Join(l.cgroupDir, "memory.max"), value, 0644); err != nil {
+		return fmt.Errorf("failed to apply memory limit: %v", err)
+	}
+	// Not every kernel has swap accounting
+	os.WriteFile(filepath.Join(l.cgroupDir, "memory.swap.max"), []byte("0"), 0644)
+	return nil
+}
+
+// localPidsLimited reports whether sandboxes created under the cgroup root get a pids.max
+func localPidsLimited() bool {
+	if config.SandboxCgroupRoot == "" {
+		return false
+	}
+	controllers, err := os.ReadFile(filepath.Join(config.SandboxCgroupRoot, "cgroup.subtree_control"))
+	return err == nil && slices.Contains(strings.Fields(string(controllers)), "pids")
+}
+
+// localProcess is judge-run started on the host
+type localProcess struct {
+	cmd       *exec.Cmd
+	ctx       context.Context
+	cancel    context.CancelFunc
+	statsPath string
+
+	stdin        io.WriteCloser
+	stdout       *io.PipeReader
+	stdoutWriter *io.PipeWriter
+	stderr       limitedBuffer
+}
+
+func (p *localProcess) Stdin() io.WriteCloser { return p.stdin }
+func (p *localProcess) Stdout() io.Reader     { return p.stdout }
+
+func (p *localProcess) Wait() (RunOutput, error) {
+	defer p.cancel()
+
+	err := p.cmd.Wait()
+	p.stdoutWriter.Close()
+	if err != nil {
+		if p.ctx.Err() != nil {
+			return RunOutput{Hung: true}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return RunOutput{}, fmt.Errorf("judge-run failed: %v", err)
+		}
+		// judge-run exits with the program's exit code, which is reported in the stats
+	}
+
+	output := RunOutput{Stderr: p.stderr.String()}
+	data, err := os.ReadFile(p.statsPath)
+	if err != nil {
+		return output, fmt.Errorf("failed to read run stats: %v", err)
+	}
+	if err := json.Unmarshal(data, &output.Stats); err != nil {
+		return output, fmt.Errorf("invalid stats %q: %v", string(data), err)
+	}
+	return output, nil
+}
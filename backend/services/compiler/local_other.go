@@ -0,0 +1,13 @@
+//go:build !linux
+
+package compiler
+
+import "fmt"
+
+func newLocalSandbox(lang languageSpec) (Sandbox, error) {
+	return nil, fmt.Errorf("the local sandbox backend is only supported on Linux")
+}
+
+func localPidsLimited() bool {
+	return false
+}
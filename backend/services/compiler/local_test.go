@@ -0,0 +1,200 @@
+//go:build linux
+
+package compiler
+
+import (
+	"onlineJudge/backend/config"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// localSessionForTest compiles code in a session of the local backend, skipping when the toolchain is missing
+func localSessionForTest(t *testing.T, language, code string, timeLimit float64) *Session {
+	t.Helper()
+	requireHelper(t)
+	useBackend(t, BackendLocal)
+
+	lang, err := languageFor(language, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := lang.RunCmd[0]
+	if len(lang.CompileCmd) > 0 {
+		tool = lang.CompileCmd[0]
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skipf("%s is not installed", tool)
+	}
+
+	session, err := NewSession(CompilerSubmission{Language: language, SourceCode: code, TimeLimit: timeLimit, MemoryLimit: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(session.Close)
+
+	compiled, err := session.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compiled != nil && !compiled.Success {
+		t.Fatalf("compilation failed: %s", compiled.Output)
+	}
+	return session
+}
+
+func TestLocalSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		code     string
+		outcome  Outcome
+	}{
+		{"python ok", "python", "a, b = map(int, input().split())\nprint(a + b)\n", OutcomeOK},
+		{"python busy loop", "python", "while True:\n    pass\n", OutcomeTimeLimit},
+		{"python sleep", "python", "import time\ntime.sleep(60)\n", OutcomeTimeLimit},
+		{"python growing allocation", "python", "data = []\nwhile True:\n    data.append(bytearray(1 << 20))\n", OutcomeMemoryLimit},
+		{"python forged out of memory message", "python", "import sys\nprint('MemoryError', file=sys.stderr)\nsys.exit(1)\n", OutcomeRuntimeError},
+		{"python exception", "python", "raise ValueError('boom')\n", OutcomeRuntimeError},
+		{"python exit code", "python", "import sys\nsys.exit(3)\n", OutcomeRuntimeError},
+		{"c ok", "c", "#include <stdio.h>\nint main() { int a, b; scanf(\"%d %d\", &a, &b); printf(\"%d\\n\", a + b); }\n", OutcomeOK},
+		{"c busy loop", "c", "int main() { volatile unsigned long i = 0; for (;;) i++; }\n", OutcomeTimeLimit},
+		{"c segfault", "c", "int main() { volatile int *p = 0; return *p; }\n", OutcomeRuntimeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := localSessionForTest(t, tt.language, tt.code, 0.5)
+			result, err := session.Run(strings.NewReader("2 3\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Outcome != tt.outcome {
+				t.Fatalf("outcome = %s, want %s (exit %d, signal %d, stderr %q)",
+					result.Outcome, tt.outcome, result.ExitCode, result.Signal, result.Stderr)
+			}
+			if tt.outcome == OutcomeOK && result.Stdout != "5\n" {
+				t.Errorf("stdout = %q, want %q", result.Stdout, "5\n")
+			}
+		})
+	}
+}
+
+// The heap of the JVM is the problem's limit; filling it is a memory limit, not a crash at startup
+func TestLocalKotlinMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		outcome Outcome
+	}{
+		{"heap close to the limit", "fun main() {\n    val data = IntArray(12 * 1024 * 1024) { it }\n    println(data.last())\n}\n", OutcomeOK},
+		{"heap over the limit", "fun main() {\n    val data = ArrayList<IntArray>()\n    while (true) data.add(IntArray(1 shl 20))\n}\n", OutcomeMemoryLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := localSessionForTest(t, "kotlin", tt.code, 5)
+			result, err := session.Run(strings.NewReader(""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Outcome != tt.outcome {
+				t.Fatalf("outcome = %s, want %s (exit %d, memory %dKB, stderr %q)",
+					result.Outcome, tt.outcome, result.ExitCode, result.MemoryUsed, result.Stderr)
+			}
+		})
+	}
+}
+
+func TestLocalSessionRunsRepeatedly(t *testing.T) {
+	session := localSessionForTest(t, "python", "print(sum(map(int, input().split())))\n", 1)
+	for _, input := range []string{"1 2", "10 20 30", "-5 5"} {
+		result, err := session.Run(strings.NewReader(input + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Outcome != OutcomeOK {
+			t.Fatalf("outcome on %q = %s: %s", input, result.Outcome, result.Stderr)
+		}
+	}
+}
+
+func TestLocalCompilationError(t *testing.T) {
+	requireHelper(t)
+	useBackend(t, BackendLocal)
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not installed")
+	}
+
+	result, err := ExecuteCode(CompilerSubmission{Language: "c", SourceCode: "int main() { return x; }", TimeLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Outcome != OutcomeCompilationError {
+		t.Fatalf("outcome = %s, want %s", result.Outcome, OutcomeCompilationError)
+	}
+	if result.Compile == nil || !strings.Contains(result.Compile.Output, "'x' undeclared") {
+		t.Errorf("compiler output does not explain the error: %+v", result.Compile)
+	}
+}
+
+func TestLocalBackendRequiresOptIn(t *testing.T) {
+	useBackend(t, BackendLocal)
+	config.SandboxLocalInsecure = false
+	t.Cleanup(func() { config.SandboxLocalInsecure = true })
+
+	if err := CheckBackend(); err == nil {
+		t.Error("CheckBackend accepted the local backend without SANDBOX_LOCAL_INSECURE")
+	}
+	if _, err := NewSession(CompilerSubmission{Language: "python", SourceCode: "print(1)"}); err == nil {
+		t.Error("a local session started without SANDBOX_LOCAL_INSECURE")
+	}
+}
+
+// guessInteractor answers guesses of the number in input.txt with <, > or =, allowing 7 of them
+const guessInteractor = `import sys
+secret = int(open(sys.argv[1]).read())
+for _ in range(7):
+    line = sys.stdin.readline()
+    if not line:
+        sys.exit(1)
+    guess = int(line)
+    if guess == secret:
+        print('=', flush=True)
+        sys.exit(0)
+    print('<' if secret < guess else '>', flush=True)
+sys.exit(1)
+`
+
+func TestLocalInteraction(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		outcome Outcome
+		verdict CheckVerdict
+	}{
+		{"binary search", "lo, hi = 1, 100\nwhile True:\n    mid = (lo + hi) // 2\n    print(mid, flush=True)\n" +
+			"    answer = input()\n    if answer == '=':\n        break\n    if answer == '<':\n        hi = mid - 1\n    else:\n        lo = mid + 1\n",
+			OutcomeOK, CheckOK},
+		{"linear search runs out of guesses", "for guess in range(1, 101):\n    print(guess, flush=True)\n    if input() == '=':\n        break\n",
+			OutcomeRuntimeError, CheckWrongAnswer},
+		{"gives up", "print(1, flush=True)\n", OutcomeOK, CheckWrongAnswer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := localSessionForTest(t, "python", tt.code, 1)
+			interactor, err := NewInteractor(guessInteractor, "python")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer interactor.Close()
+
+			result, check, err := interactor.Interact(session, strings.NewReader("42"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Outcome != tt.outcome || check.Verdict != tt.verdict {
+				t.Errorf("outcome %s, verdict %s; want %s, %s (stderr %q, interactor %q)",
+					result.Outcome, check.Verdict, tt.outcome, tt.verdict, result.Stderr, check.Message)
+			}
+		})
+	}
+}
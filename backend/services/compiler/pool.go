@@ -0,0 +1,247 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+	"onlineJudge/backend/config"
+	"os"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+)
+
+// Labels identify sandbox containers owned by this backend instance
+const (
+	sandboxLabel = "onlinejudge.sandbox"
+	ownerLabel   = "onlinejudge.owner"
+)
+
+var (
+	dockerOnce sync.Once
+	dockerCli  *client.Client
+	dockerErr  error
+)
+
+// dockerClient returns the Docker client shared by all sessions
+func dockerClient() (*client.Client, error) {
+	dockerOnce.Do(func() {
+		dockerCli, dockerErr = client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.44"))
+		if dockerErr != nil {
+			dockerErr = fmt.Errorf("failed to create docker client: %v", dockerErr)
+		}
+	})
+	return dockerCli, dockerErr
+}
+
+// containerPool keeps pre-started sandbox containers of one language.
+// Containers are never reused after running contestant code: they are destroyed and replaced.
+type containerPool struct {
+	name  string
+	image string
+	size  int
+	ready chan string
+
+	mu      sync.Mutex
+	pending int // Containers being created
+
+	create func(ctx context.Context, cli *client.Client, name, image string) (string, error) // createSandbox, replaced in tests
+}
+
+var (
+	poolsMu sync.Mutex
+	pools   = map[string]*containerPool{}
+)
+
+// poolFor returns the pool of the language, creating it on first use
+func poolFor(lang languageSpec) *containerPool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if pool, ok := pools[lang.Name]; ok {
+		return pool
+	}
+
+	size := config.SandboxPoolSize
+	if override, ok := config.SandboxPoolSizes[lang.Name]; ok {
+		size = override
+	}
+	pool := &containerPool{name: lang.Name, image: lang.Image, size: size, ready: make(chan string, size), create: createSandbox}
+	pools[lang.Name] = pool
+	return pool
+}
+
+// StartPools removes sandboxes left over by a previous run of this instance
+// and pre-starts the configured number of containers for every language.
+func StartPools() {
+	if config.SandboxBackend != BackendDocker {
+		return
+	}
+
+	cli, err := dockerClient()
+	if err != nil {
+		log.Printf("Sandbox pool disabled: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	leftovers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", ownerLabel+"="+instanceName())),
+	})
+	if err == nil {
+		for _, c := range leftovers {
+			cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true})
+		}
+	}
+
+	for _, lang := range registry {
+		go poolFor(lang).refill()
+	}
+}
+
+// acquire hands out a warm container, or creates one if the pool is empty
+func (p *containerPool) acquire(ctx context.Context, cli *client.Client) (string, error) {
+	defer func() { go p.refill() }()
+
+	for {
+		select {
+		case id := <-p.ready:
+			inspect, err := cli.ContainerInspect(ctx, id)
+			if err == nil && inspect.State != nil && inspect.State.Running {
+				return id, nil
+			}
+			// Died while waiting in the pool
+			cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+		default:
+			return p.create(ctx, cli, p.name, p.image)
+		}
+	}
+}
+
+// refill starts containers until the pool is full again
+func (p *containerPool) refill() {
+	cli, err := dockerClient()
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	missing := p.size - len(p.ready) - p.pending
+	if missing <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.pending += missing
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		id, err := p.create(context.Background(), cli, p.name, p.image)
+		if err != nil {
+			// The rest is not created either; the next acquire refills again
+			p.mu.Lock()
+			p.pending -= missing - i
+			p.mu.Unlock()
+			log.Printf("Sandbox pool %s: %v", p.name, err)
+			return
+		}
+
+		// Ready before it stops being pending, so a concurrent refill never overfills the pool
+		p.ready <- id
+		p.mu.Lock()
+		p.pending--
+		p.mu.Unlock()
+	}
+}
+
+// release destroys a used container; the pool is refilled by acquire
+func release(cli *client.Client, containerID string) {
+	go cli.ContainerRemove(context.Background(), containerID, container.RemoveOptions{Force: true})
+}
+
+// seccompProfile blocks syscalls a contestant program has no use for: namespaces and mounts,
+// kernel keyrings, ptrace and other ways to reach outside the container. Everything else
+// is allowed, the capability set and the non-root user do the rest.
+//
+//go:embed seccomp.json
+var seccompProfile string
+
+// createSandbox starts an idle, locked-down container with the judge-run helper inside.
+// The root filesystem is read-only and the only writable places are small tmpfs mounts;
+// compilers and programs run as an unprivileged user, only judge-run keeps the few
+// capabilities it needs to drop to that user and kill the process tree.
+func createSandbox(ctx context.Context, cli *client.Client, name, image string) (string, error) {
+	helper, err := loadHelper()
+	if err != nil {
+		return "", err
+	}
+
+	if err := ensureImage(ctx, cli, image); err != nil {
+		return "", fmt.Errorf("failed to pull image %s: %v", image, err)
+	}
+
+	pidsLimit := int64(config.SandboxPidsLimit)
+	resp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:           image,
+		Cmd:             []string{"sleep", "infinity"},
+		Tty:             false,
+		NetworkDisabled: true,
+		OpenStdin:       true,
+		WorkingDir:      workDir,
+		User:            fmt.Sprintf("%d:%d", sandboxUID, sandboxGID),
+		Env:             []string{"HOME=/tmp"}, // The sandbox user has no home; toolchains keep caches there
+		Labels:          map[string]string{sandboxLabel: name, ownerLabel: instanceName()},
+	}, &container.HostConfig{
+		Resources: container.Resources{
+			Memory:     compileMemory,
+			MemorySwap: compileMemory, // No swap, so exceeding the limit triggers the OOM killer
+			NanoCPUs:   1000000000,
+			PidsLimit:  &pidsLimit,
+		},
+		ReadonlyRootfs: true,
+		Mounts: []mount.Mount{{
+			Type:   mount.TypeVolume,
+			Source: channelVolume,
+			Target: channelDir,
+			// A small tmpfs on the host, shared by the containers, where the sandbox user cannot write
+			VolumeOptions: &mount.VolumeOptions{NoCopy: true, DriverConfig: &mount.Driver{
+				Name:    "local",
+				Options: map[string]string{"type": "tmpfs", "device": "tmpfs", "o": "size=1m,mode=0711"},
+			}},
+		}},
+		Tmpfs: map[string]string{
+			workDir:   fmt.Sprintf("rw,exec,nosuid,nodev,size=256m,mode=0755,uid=%d,gid=%d", sandboxUID, sandboxGID),
+			"/tmp":    "rw,exec,nosuid,nodev,size=256m,mode=1777",
+			helperDir: "rw,exec,nosuid,nodev,size=16m,mode=0755",
+		},
+		CapDrop:     []string{"ALL"},
+		CapAdd:      []string{"SETUID", "SETGID", "KILL"},
+		SecurityOpt: []string{"no-new-privileges:true", "seccomp=" + seccompProfile},
+	}, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %v", err)
+	}
+
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return "", fmt.Errorf("failed to start container: %v", err)
+	}
+
+	if err := writeFile(ctx, cli, resp.ID, "0", helperDir+"/"+helperName, bytes.NewReader(helper), "0755"); err != nil {
+		cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return "", fmt.Errorf("failed to copy sandbox helper: %v", err)
+	}
+
+	return resp.ID, nil
+}
+
+// instanceName tells sandboxes of this backend apart from other instances sharing the Docker daemon
+func instanceName() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
@@ -0,0 +1,47 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+func TestPoolRefill(t *testing.T) {
+	tests := []struct {
+		name  string
+		fails []bool // Whether each call to create fails, in order; later calls succeed
+		ready int    // Containers ready after the first refill
+	}{
+		{"all created", nil, 3},
+		{"first fails", []bool{true}, 0},
+		{"fails midway", []bool{false, true}, 1},
+		{"last fails", []bool{false, false, true}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			pool := &containerPool{name: "python", size: 3, ready: make(chan string, 3)}
+			pool.create = func(ctx context.Context, cli *client.Client, name, image string) (string, error) {
+				calls++
+				if calls <= len(tt.fails) && tt.fails[calls-1] {
+					return "", errors.New("daemon unavailable")
+				}
+				return fmt.Sprintf("container-%d", calls), nil
+			}
+
+			pool.refill()
+			if pool.pending != 0 || len(pool.ready) != tt.ready {
+				t.Fatalf("after refill: %d pending, %d ready; want 0, %d", pool.pending, len(pool.ready), tt.ready)
+			}
+
+			// Once creating works again the pool fills up completely
+			pool.refill()
+			if pool.pending != 0 || len(pool.ready) != pool.size {
+				t.Errorf("after recovery: %d pending, %d ready; want 0, %d", pool.pending, len(pool.ready), pool.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// OpenChannel opens the FIFOs an interactive program uses as stdin and stdout instead of the
+// inherited ones, creating them if the peer has not yet. The peer opens the same two FIFOs with
+// the roles swapped. Both sides open them in the order of their paths, so each open waits for
+// the peer's matching one and the two can never wait on each other.
+//
+// Once both are open the FIFOs and their directory are removed; they stay connected.
+// Giving up after timeout means the peer never started; 0 waits forever.
+func OpenChannel(stdinPath, stdoutPath string, timeout time.Duration) (stdin, stdout *os.File, err error) {
+	dir := filepath.Dir(stdinPath)
+	defer func() {
+		os.Remove(stdinPath)
+		os.Remove(stdoutPath)
+		os.Remove(dir)
+	}()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, err
+	}
+	for _, path := range []string{stdinPath, stdoutPath} {
+		if err := syscall.Mkfifo(path, 0600); err != nil && !errors.Is(err, os.ErrExist) {
+			return nil, nil, fmt.Errorf("failed to create %s: %v", path, err)
+		}
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	ends := []struct {
+		path string
+		flag int
+		file **os.File
+	}{
+		{stdinPath, os.O_RDONLY, &stdin},
+		{stdoutPath, os.O_WRONLY, &stdout},
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i].path < ends[j].path })
+
+	for _, end := range ends {
+		if *end.file, err = openFIFO(end.path, end.flag, deadline); err != nil {
+			if stdin != nil {
+				stdin.Close()
+			}
+			if stdout != nil {
+				stdout.Close()
+			}
+			return nil, nil, err
+		}
+	}
+	return stdin, stdout, nil
+}
+
+// openFIFO opens one end of a FIFO, which blocks until the peer opens the other end
+func openFIFO(path string, flag int, deadline time.Time) (*os.File, error) {
+	type opened struct {
+		file *os.File
+		err  error
+	}
+	done := make(chan opened, 1)
+	go func() {
+		file, err := os.OpenFile(path, flag, 0)
+		done <- opened{file, err}
+	}()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case result := <-done:
+		return result.file, result.err
+	case <-timeout:
+		// Opening both ends ourselves releases the blocked open
+		if release, err := os.OpenFile(path, os.O_RDWR, 0); err == nil {
+			release.Close()
+		}
+		if result := <-done; result.file != nil {
+			result.file.Close()
+		}
+		return nil, fmt.Errorf("the other side of %s did not start", path)
+	}
+}
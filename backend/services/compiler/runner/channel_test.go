@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenChannel(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "channel")
+	toInteractor, fromInteractor := filepath.Join(dir, "0"), filepath.Join(dir, "1")
+
+	// Either side may come first
+	for _, interactorFirst := range []bool{true, false} {
+		t.Run(fmt.Sprintf("interactor first %v", interactorFirst), func(t *testing.T) {
+			type ends struct {
+				in, out *os.File
+				err     error
+			}
+			interactor, contestant := make(chan ends, 1), make(chan ends, 1)
+			open := func(result chan ends, stdin, stdout string) {
+				in, out, err := OpenChannel(stdin, stdout, 5*time.Second)
+				result <- ends{in, out, err}
+			}
+			if interactorFirst {
+				go open(interactor, toInteractor, fromInteractor)
+				time.Sleep(50 * time.Millisecond)
+				go open(contestant, fromInteractor, toInteractor)
+			} else {
+				go open(contestant, fromInteractor, toInteractor)
+				time.Sleep(50 * time.Millisecond)
+				go open(interactor, toInteractor, fromInteractor)
+			}
+
+			i, c := <-interactor, <-contestant
+			if i.err != nil || c.err != nil {
+				t.Fatalf("interactor: %v, contestant: %v", i.err, c.err)
+			}
+			defer i.in.Close()
+			defer c.in.Close()
+
+			fmt.Fprintln(c.out, "? 5")
+			if line, _ := bufio.NewReader(i.in).ReadString('\n'); line != "? 5\n" {
+				t.Errorf("interactor read %q", line)
+			}
+			fmt.Fprintln(i.out, ">")
+			if line, _ := bufio.NewReader(c.in).ReadString('\n'); line != ">\n" {
+				t.Errorf("contestant read %q", line)
+			}
+
+			// The contestant hanging up is the end of the interactor's input
+			c.out.Close()
+			if n, err := i.in.Read(make([]byte, 1)); n != 0 {
+				t.Errorf("interactor read more after the contestant closed: %v", err)
+			}
+			i.out.Close()
+
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("channel directory left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenChannelWithoutPeer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "channel")
+
+	start := time.Now()
+	_, _, err := OpenChannel(filepath.Join(dir, "0"), filepath.Join(dir, "1"), 200*time.Millisecond)
+	if err == nil {
+		t.Fatal("opened a channel nobody else uses")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %s", elapsed)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("channel directory left behind: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package runner starts a contestant process and collects the resources it used.
+// It is compiled into the judge-run helper, which runs inside every sandbox container
+// (or directly on the host for the local sandbox).
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Stats is written by judge-run after the contestant process exits
+type Stats struct {
+	ExitCode     int    `json:"exit_code"`
+	Signal       int    `json:"signal"`      // Terminating signal, 0 if the process exited normally
+	CPUTimeMs    int64  `json:"cpu_time_ms"` // User + system CPU time of the process tree
+	WallMs       int64  `json:"wall_ms"`     // Wall-clock time
+	MaxRSSKB     int64  `json:"max_rss_kb"`  // Peak resident set size of the process tree
+	TimedOut     bool   `json:"timed_out"`   // Killed for exceeding the CPU time limit
+	WallTimedOut bool   `json:"wall_timed_out"`
+	OOMKilled    bool   `json:"oom_killed"` // The cgroup OOM killer fired during the run
+	StartErr     string `json:"start_error,omitempty"`
+}
+
+// Options describes the process to run
+type Options struct {
+	Command []string
+	Dir     string
+	Env     []string
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+
+	CPULimit  time.Duration // 0 means unlimited
+	WallLimit time.Duration // Safety net for processes that sleep or block; 0 means unlimited
+
+	// AddressSpaceLimit caps virtual memory with RLIMIT_AS when no memory cgroup is available; 0 means unlimited
+	AddressSpaceLimit int64
+
+	// ProcessLimit caps processes and threads with RLIMIT_NPROC when no pids cgroup is available; 0 means unlimited.
+	// The kernel counts every process of the user, not only this tree.
+	ProcessLimit int
+
+	// CgroupDir is the cgroup the process runs in, used for CPU sampling and OOM detection.
+	// Empty disables both, e.g. when the process shares the host's root cgroup.
+	CgroupDir string
+
+	// UID and GID the process runs as, so it cannot touch files of the (root) helper; -1 keeps the current ones
+	UID int
+	GID int
+}
+
+// cpuPollInterval is how often CPU usage is sampled while the process runs
+const cpuPollInterval = 10 * time.Millisecond
+
+// LimitsArg makes judge-run re-execute itself as a wrapper that applies the address space
+// and process limits, then executes the program, see ExecWithLimits
+const LimitsArg = "-exec-with-limits"
+
+// Run executes the command and waits for it to finish
+func Run(opts Options) Stats {
+	var stats Stats
+
+	command := opts.Command
+	if opts.AddressSpaceLimit > 0 || opts.ProcessLimit > 0 {
+		wrapped, err := withLimits(opts)
+		if err != nil {
+			stats.ExitCode = 127
+			stats.StartErr = err.Error()
+			return stats
+		}
+		command = wrapped
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	// Own process group, so the whole tree can be killed at once
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if opts.UID >= 0 && opts.GID >= 0 {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(opts.UID), Gid: uint32(opts.GID), Groups: []uint32{}}
+	}
+
+	if opts.CPULimit > 0 {
+		// Kernel backstop in case sampling misses a burst; inherited by the child
+		seconds := uint64(opts.CPULimit.Seconds()) + 2
+		syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: seconds, Max: seconds + 1})
+	}
+
+	oomBefore := oomKillCount(opts.CgroupDir)
+	cpuBefore := cgroupCPUUsage(opts.CgroupDir)
+	start := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		stats.ExitCode = 127
+		stats.StartErr = err.Error()
+		return stats
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+
+	var wallTimeout <-chan time.Time
+	if opts.WallLimit > 0 {
+		wallTimer := time.NewTimer(opts.WallLimit)
+		defer wallTimer.Stop()
+		wallTimeout = wallTimer.C
+	}
+
+	ticker := time.NewTicker(cpuPollInterval)
+	defer ticker.Stop()
+
+wait:
+	for {
+		select {
+		case <-done:
+			break wait
+		case <-wallTimeout:
+			stats.WallTimedOut = true
+			kill(cmd)
+		case <-ticker.C:
+			if opts.CPULimit > 0 && cpuBefore >= 0 && !stats.TimedOut {
+				if used := cgroupCPUUsage(opts.CgroupDir) - cpuBefore; used > opts.CPULimit {
+					stats.TimedOut = true
+					kill(cmd)
+				}
+			}
+		}
+	}
+
+	stats.WallMs = time.Since(start).Milliseconds()
+
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
+		if status.Signaled() {
+			stats.Signal = int(status.Signal())
+			stats.ExitCode = 128 + stats.Signal
+			if status.Signal() == syscall.SIGXCPU {
+				stats.TimedOut = true
+			}
+		} else {
+			stats.ExitCode = status.ExitStatus()
+		}
+	}
+	if usage, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		stats.MaxRSSKB = int64(usage.Maxrss) // Kilobytes on Linux
+		cpu := time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+		stats.CPUTimeMs = cpu.Milliseconds()
+		if opts.CPULimit > 0 && cpu > opts.CPULimit {
+			stats.TimedOut = true
+		}
+	}
+
+	if oomBefore >= 0 {
+		stats.OOMKilled = oomKillCount(opts.CgroupDir) > oomBefore
+	}
+
+	return stats
+}
+
+// withLimits wraps the command in judge-run itself, which sets the address space and process
+// limits right before executing the program. Set on judge-run, they would starve its own runtime:
+// the Go heap reserves more address space than a small limit, and a fork bomb would leave
+// no thread to collect the stats with.
+func withLimits(opts Options) ([]string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	// Resolved here, so a missing program is still a start error rather than a failed run
+	program, err := exec.LookPath(opts.Command[0])
+	if err != nil && !strings.Contains(opts.Command[0], "/") {
+		return nil, err
+	}
+	if err != nil {
+		program = opts.Command[0] // Relative to opts.Dir, checked by the wrapper
+	}
+
+	args := []string{self, LimitsArg,
+		strconv.FormatInt(opts.AddressSpaceLimit, 10), strconv.Itoa(opts.ProcessLimit), program}
+	return append(args, opts.Command...), nil
+}
+
+// ExecWithLimits is the wrapper started through LimitsArg. Its arguments are the address space
+// limit in bytes, the process limit (0 for none), the program path and the program's argv.
+// It only returns on failure.
+func ExecWithLimits(args []string) error {
+	if len(args) < 4 {
+		return fmt.Errorf("usage: %s bytes processes program argv...", LimitsArg)
+	}
+	addressSpace, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid address space limit: %v", err)
+	}
+	processes, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid process limit: %v", err)
+	}
+
+	// Everything execve needs is allocated before the limits: past them the wrapper's own runtime
+	// may not be able to grow its heap
+	path, err := syscall.BytePtrFromString(args[2])
+	if err != nil {
+		return err
+	}
+	argv, err := syscall.SlicePtrFromStrings(args[3:])
+	if err != nil {
+		return err
+	}
+	envv, err := syscall.SlicePtrFromStrings(os.Environ())
+	if err != nil {
+		return err
+	}
+
+	if addressSpace > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: addressSpace, Max: addressSpace}); err != nil {
+			return fmt.Errorf("failed to limit address space: %v", err)
+		}
+	}
+	if processes > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_NPROC, &unix.Rlimit{Cur: processes, Max: processes}); err != nil {
+			return fmt.Errorf("failed to limit processes: %v", err)
+		}
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_EXECVE,
+		uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&argv[0])), uintptr(unsafe.Pointer(&envv[0])))
+	return fmt.Errorf("failed to execute %s: %v", args[2], errno)
+}
+
+// kill terminates the process group of cmd
+func kill(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// cgroupCPUUsage returns the CPU time consumed by the cgroup (v2, then v1).
+// Unlike rusage it includes children that are still running. Returns -1 if unavailable.
+func cgroupCPUUsage(dir string) time.Duration {
+	if dir == "" {
+		return -1
+	}
+	if usec, ok := readCounter(dir+"/cpu.stat", "usage_usec"); ok {
+		return time.Duration(usec) * time.Microsecond
+	}
+	if data, err := os.ReadFile(dir + "/cpuacct/cpuacct.usage"); err == nil {
+		if ns, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return time.Duration(ns)
+		}
+	}
+	return -1
+}
+
+// oomKillCount reads the number of OOM kills in the memory cgroup (v2, then v1).
+// Returns -1 if the counter is not available.
+func oomKillCount(dir string) int64 {
+	if dir == "" {
+		return -1
+	}
+	for _, path := range []string{
+		dir + "/memory.events",
+		dir + "/memory/memory.oom_control",
+	} {
+		if count, ok := readCounter(path, "oom_kill"); ok {
+			return count
+		}
+	}
+	return -1
+}
+
+// readCounter finds a "key value" line in a cgroup file
+func readCounter(path, key string) (int64, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, err := strconv.ParseInt(fields[1], 10, 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
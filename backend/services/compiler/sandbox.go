@@ -0,0 +1,249 @@
+package compiler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"onlineJudge/backend/config"
+	"onlineJudge/backend/services/compiler/runner"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Sandbox backends selectable with SANDBOX_BACKEND
+const (
+	BackendDocker = "docker"
+	BackendLocal  = "local"
+)
+
+// Limits are the resource limits of a single process in the sandbox
+type Limits struct {
+	CPUTime  float64 // Seconds of CPU time
+	WallTime float64 // Seconds of wall-clock time, catches sleeping or blocked programs
+	Memory   int64   // Bytes
+	Output   int64   // Bytes of stdout kept, 0 for no limit
+
+	// AddressSpace replaces Memory where only virtual memory can be limited, 0 to use Memory
+	AddressSpace int64
+}
+
+// sandboxUserArgs make judge-run drop to the sandbox user in containers
+var sandboxUserArgs = []string{"-uid", strconv.Itoa(sandboxUID), "-gid", strconv.Itoa(sandboxGID)}
+
+// diagnosticsLimit caps stderr and compiler output, which are only kept for diagnostics
+const diagnosticsLimit = 64 * 1024
+
+// RunOutput is what a sandbox reports about one run
+type RunOutput struct {
+	Stdout string
+	Stderr string
+	Stats  runner.Stats
+	Hung   bool // The sandbox itself did not return in time; Stats is empty
+
+	OutputExceeded bool // Stdout went over Limits.Output and was cut
+}
+
+// Sandbox is an isolated environment for one judging session
+type Sandbox interface {
+	// Prepare creates the environment and writes files into its work directory
+	Prepare(files map[string][]byte) error
+	// WriteFile adds or replaces a file in the work directory of a prepared environment
+	WriteFile(name string, content io.Reader) error
+	// Compile runs a build command to completion
+	Compile(cmd []string, limits Limits) (CompileResult, error)
+	// Run executes the command as a fresh process with the given stdin
+	Run(cmd []string, stdin io.Reader, limits Limits) (RunOutput, error)
+	// Start launches the command with streaming stdin and stdout
+	Start(cmd []string, limits Limits) (Process, error)
+	// StartConnected launches the command with the FIFOs of a channel as its stdin and stdout,
+	// through which it talks directly to a program in another sandbox, see Interactor
+	StartConnected(cmd []string, limits Limits, stdin, stdout string) (Process, error)
+	// Cleanup destroys the environment
+	Cleanup()
+}
+
+// Process is a command started in a sandbox whose stdin and stdout are streamed.
+// A connected process reads and writes its channel instead and streams nothing.
+type Process interface {
+	Stdin() io.WriteCloser
+	Stdout() io.Reader
+	// Wait blocks until the process exits; RunOutput.Stdout is empty as it was streamed
+	Wait() (RunOutput, error)
+}
+
+// errLocalInsecure refuses the local backend unless it was enabled explicitly
+var errLocalInsecure = errors.New("the local sandbox backend does not isolate programs from the host; " +
+	"set SANDBOX_LOCAL_INSECURE=true to use it for development or testing")
+
+// CheckBackend validates the configured sandbox backend at startup
+func CheckBackend() error {
+	switch config.SandboxBackend {
+	case BackendDocker:
+		return nil
+	case BackendLocal:
+		if !config.SandboxLocalInsecure {
+			return errLocalInsecure
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown sandbox backend %q", config.SandboxBackend)
+	}
+}
+
+// newSandbox creates a sandbox of the configured backend for the language
+func newSandbox(lang languageSpec) (Sandbox, error) {
+	switch config.SandboxBackend {
+	case BackendDocker:
+		return &dockerSandbox{lang: lang}, nil
+	case BackendLocal:
+		return newLocalSandbox(lang)
+	default:
+		return nil, fmt.Errorf("unknown sandbox backend %q", config.SandboxBackend)
+	}
+}
+
+// ProcessesLimited reports whether the configured backend caps the processes of a sandbox
+// with a pids cgroup, which is what contains a fork bomb
+func ProcessesLimited() bool {
+	switch config.SandboxBackend {
+	case BackendDocker:
+		return true // Every container is created with a PidsLimit
+	case BackendLocal:
+		return localPidsLimited()
+	default:
+		return false
+	}
+}
+
+// channel is a directory for the two FIFOs connecting an interactor with a contestant,
+// under the same path in both sandboxes. judge-run creates and removes it.
+type channel struct {
+	dir string
+}
+
+// newChannel picks a fresh, unguessable channel directory of the configured backend
+func newChannel() (channel, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return channel{}, err
+	}
+	root := channelDir
+	if config.SandboxBackend == BackendLocal {
+		root = os.TempDir()
+	}
+	return channel{dir: fmt.Sprintf("%s/judge-channel-%x", root, name)}, nil
+}
+
+// The names order the opens on both sides, see runner.OpenChannel
+func (c channel) toInteractor() string   { return c.dir + "/0" }
+func (c channel) fromInteractor() string { return c.dir + "/1" }
+
+// channelArgs make judge-run use the FIFOs as the program's stdin and stdout
+func channelArgs(stdin, stdout string) []string {
+	return []string{"-stdin", stdin, "-stdout", stdout}
+}
+
+// writeFiles writes in-memory files, such as the source, into a sandbox
+func writeFiles(sandbox Sandbox, files map[string][]byte) error {
+	for name, content := range files {
+		if err := sandbox.WriteFile(name, bytes.NewReader(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// helperArgs builds the judge-run command line that wraps cmd
+func helperArgs(helperPath, statsPath string, limits Limits, extra []string, cmd []string) []string {
+	args := []string{
+		helperPath,
+		"-stats", statsPath,
+		"-cpu", fmt.Sprintf("%.3f", limits.CPUTime),
+		"-wall", fmt.Sprintf("%.3f", limits.WallTime),
+	}
+	args = append(args, extra...)
+	args = append(args, "--")
+	return append(args, cmd...)
+}
+
+// runStreamed runs a command to completion by feeding stdin to a started process and collecting its stdout
+func runStreamed(sandbox Sandbox, cmd []string, stdin io.Reader, limits Limits) (RunOutput, error) {
+	process, err := sandbox.Start(cmd, limits)
+	if err != nil {
+		return RunOutput{}, err
+	}
+
+	go func() {
+		io.Copy(process.Stdin(), stdin)
+		process.Stdin().Close()
+	}()
+
+	// Past the limit the output is discarded, not left unread: a blocked writer would
+	// only hit the wall-clock limit, while this way it runs into its CPU limit or exits
+	stdout := limitedBuffer{limit: limits.Output}
+	stdoutDone := make(chan struct{})
+	go func() {
+		io.Copy(&stdout, process.Stdout())
+		close(stdoutDone)
+	}()
+
+	output, err := process.Wait()
+	if output.Hung {
+		return output, err
+	}
+	<-stdoutDone
+	output.Stdout = stdout.String()
+	output.OutputExceeded = stdout.exceeded
+	return output, err
+}
+
+// compileStreamed runs a build command to completion under judge-run with the compile limits.
+// Running out of time is reported as a failed compilation.
+func compileStreamed(sandbox Sandbox, cmd []string, limits Limits) (CompileResult, error) {
+	output, err := runStreamed(sandbox, cmd, strings.NewReader(""), limits)
+	if err != nil {
+		return CompileResult{}, fmt.Errorf("failed to run compiler: %v", err)
+	}
+	if output.Hung {
+		return CompileResult{ExitCode: -1, Output: "compilation timed out"}, nil
+	}
+
+	stats := output.Stats
+	message := output.Stdout + output.Stderr + stats.StartErr
+	if stats.TimedOut || stats.WallTimedOut {
+		message += "\ncompilation time limit exceeded"
+	}
+	return CompileResult{
+		Success:  stats.ExitCode == 0 && stats.Signal == 0 && stats.StartErr == "",
+		ExitCode: stats.ExitCode,
+		Output:   message,
+	}, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and silently drops the rest
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64 // 0 for no limit
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if b.limit > 0 {
+		room := b.limit - int64(b.buf.Len())
+		if int64(len(data)) > room {
+			b.exceeded = true
+			if room > 0 {
+				b.buf.Write(data[:room])
+			}
+			return len(data), nil
+		}
+	}
+	return b.buf.Write(data)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"onlineJudge/backend/config"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// dockerForTest returns the Docker client, skipping the test when no daemon is reachable
+func dockerForTest(t *testing.T) *client.Client {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("sandbox tests need Docker")
+	}
+	requireHelper(t)
+
+	cli, err := dockerClient()
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(ctx); err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	useBackend(t, BackendDocker)
+	return cli
+}
+
+// sandboxForTest starts a sandbox container of the language and removes it after the test
+func sandboxForTest(t *testing.T, language string) *dockerSandbox {
+	t.Helper()
+	cli := dockerForTest(t)
+
+	lang, err := languageFor(language, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute) // The image may have to be pulled
+	defer cancel()
+	id, err := createSandbox(ctx, cli, lang.Name, lang.Image)
+	if err != nil {
+		t.Fatalf("createSandbox: %v", err)
+	}
+	t.Cleanup(func() {
+		cli.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+	})
+	return &dockerSandbox{lang: lang, ctx: context.Background(), cli: cli, containerID: id, memory: compileMemory}
+}
+
+func TestSandboxContainerConfig(t *testing.T) {
+	sandbox := sandboxForTest(t, "python")
+
+	inspect, err := sandbox.cli.ContainerInspect(sandbox.ctx, sandbox.containerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := inspect.HostConfig
+	if host.PidsLimit == nil || *host.PidsLimit != int64(config.SandboxPidsLimit) {
+		t.Errorf("pids limit = %v, want %d", host.PidsLimit, config.SandboxPidsLimit)
+	}
+	if !host.ReadonlyRootfs {
+		t.Error("root filesystem is writable")
+	}
+	if !inspect.Config.NetworkDisabled {
+		t.Error("network is enabled")
+	}
+	if len(host.Mounts) != 1 || host.Mounts[0].Target != channelDir {
+		t.Errorf("mounts = %+v, want only the channel volume", host.Mounts)
+	}
+	if len(host.CapAdd) != 3 || len(host.CapDrop) != 1 || host.CapDrop[0] != "ALL" {
+		t.Errorf("capabilities: add %v, drop %v", host.CapAdd, host.CapDrop)
+	}
+}
+
+func TestSandboxContainment(t *testing.T) {
+	sandbox := sandboxForTest(t, "python")
+
+	tests := []struct {
+		name   string
+		script string
+		ok     bool // Whether the command should succeed
+	}{
+		{"work dir is writable", "echo x > /app/x", true},
+		{"tmp is writable", "echo x > /tmp/x", true},
+		{"root filesystem is read-only", "echo x > /etc/judge-test", false},
+		{"system files are read-only", "echo x >> /etc/passwd", false},
+		{"helper cannot be replaced", "echo x > /judge/judge-run", false},
+		{"stats cannot be forged", "echo {} > /judge/stats.json", false},
+		{"channel volume is not writable", "mkdir /interaction/x", false},
+		{"only loopback interface", `test "$(ls /sys/class/net)" = lo`, true},
+		{"no outside connections", `python3 -c "import socket; socket.create_connection(('1.1.1.1', 53), 3)"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			code, err := sandbox.exec([]string{"sh", "-c", tt.script}, &output, &output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (code == 0) != tt.ok {
+				t.Errorf("%q exited with %d, want success %v: %s", tt.script, code, tt.ok, output.String())
+			}
+		})
+	}
+}
+
+func TestSandboxForkBomb(t *testing.T) {
+	dockerForTest(t)
+
+	programs := []struct{ language, code string }{
+		{"python", "import os\nwhile True:\n    try:\n        os.fork()\n    except OSError:\n        pass\n"},
+		{"c", "#include <unistd.h>\nint main() { for (;;) fork(); }"},
+	}
+	for _, p := range programs {
+		t.Run(p.language, func(t *testing.T) {
+			start := time.Now()
+			result, err := ExecuteCode(CompilerSubmission{Language: p.language, SourceCode: p.code, TimeLimit: 1, MemoryLimit: 256})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The pids limit stops the bomb, which then spins until its time limit
+			if result.Outcome != OutcomeTimeLimit {
+				t.Errorf("outcome = %s, want %s (stderr %q)", result.Outcome, OutcomeTimeLimit, result.Stderr)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Minute {
+				t.Errorf("fork bomb took %s to contain", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+)
+
+// Session is a sandbox prepared for judging one submission:
+// the source is compiled once and the binary is then run against every test.
+type Session struct {
+	sandbox Sandbox
+	lang    languageSpec
+
+	timeLimit     float64 // Problem's limit, reported in verdicts
+	memoryLimit   int64   // Problem's limit in bytes, without the language's overhead
+	runLimits     Limits
+	compileLimits Limits
+
+	compiled bool
+}
+
+// NewSession prepares a sandbox of the configured backend and copies the source into it.
+// The caller must Close the session.
+func NewSession(sub CompilerSubmission) (*Session, error) {
+	memoryLimitMB := sub.MemoryLimit
+	if memoryLimitMB <= 0 {
+		memoryLimitMB = 256
+	}
+	outputLimitMB := sub.OutputLimit
+	if outputLimitMB <= 0 {
+		outputLimitMB = 64
+	}
+
+	lang, err := languageFor(sub.Language, memoryLimitMB)
+	if err != nil {
+		return nil, err
+	}
+
+	timeLimit := sub.TimeLimit
+	if timeLimit <= 0 {
+		timeLimit = 5.0
+	}
+
+	s := &Session{
+		lang:        lang,
+		timeLimit:   timeLimit,
+		memoryLimit: int64(memoryLimitMB) * 1024 * 1024,
+	}
+
+	// The limit applies to CPU time; the wall-clock limit only catches sleeping or blocked programs
+	cpuLimit := timeLimit * lang.TimeMultiplier
+	s.runLimits = Limits{
+		CPUTime:  cpuLimit,
+		WallTime: cpuLimit*2 + 1,
+		Memory:   int64(memoryLimitMB+lang.MemoryOverheadMB) * 1024 * 1024,
+		Output:   int64(outputLimitMB) * 1024 * 1024,
+
+		AddressSpace: int64(memoryLimitMB+lang.AddressSpaceOverheadMB) * 1024 * 1024,
+	}
+
+	// Compilers get generous limits of their own; each Run passes the limits it is held to
+	s.compileLimits = Limits{CPUTime: compileTimeLimit, WallTime: compileTimeLimit * 2, Memory: compileMemory, Output: diagnosticsLimit}
+	if s.compileLimits.Memory < s.runLimits.Memory {
+		s.compileLimits.Memory = s.runLimits.Memory
+	}
+	s.compileLimits.AddressSpace = s.compileLimits.Memory + int64(lang.AddressSpaceOverheadMB)*1024*1024
+
+	s.sandbox, err = newSandbox(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sandbox.Prepare(map[string][]byte{lang.FileName: []byte(sub.SourceCode)}); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Compile builds the source once. It returns nil for interpreted languages;
+// a failed compilation is reported in the result, not as an error.
+func (s *Session) Compile() (*CompileResult, error) {
+	if s.compiled {
+		return nil, fmt.Errorf("session already compiled")
+	}
+	s.compiled = true
+
+	if len(s.lang.CompileCmd) == 0 {
+		return nil, nil
+	}
+
+	compileResult, err := s.sandbox.Compile(s.lang.CompileCmd, s.compileLimits)
+	if err != nil {
+		return nil, err
+	}
+	return &compileResult, nil
+}
+
+// Run executes the compiled program as a fresh process, streaming stdin into it
+func (s *Session) Run(stdin io.Reader) (ExecutionResult, error) {
+	return s.Exec(nil, nil, stdin)
+}
+
+// Exec writes files into the work directory, then runs the compiled program
+// with args appended to its run command
+func (s *Session) Exec(files map[string]io.Reader, args []string, stdin io.Reader) (ExecutionResult, error) {
+	if !s.compiled {
+		return ExecutionResult{}, fmt.Errorf("session must be compiled before running")
+	}
+
+	for name, content := range files {
+		if err := s.sandbox.WriteFile(name, content); err != nil {
+			return ExecutionResult{}, err
+		}
+	}
+
+	output, err := s.sandbox.Run(s.command(args), stdin, s.runLimits)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	return s.classify(output), nil
+}
+
+// command is the run command of the language with args appended
+func (s *Session) command(args []string) []string {
+	return append(append([]string{}, s.lang.RunCmd...), args...)
+}
+
+// classify turns what the sandbox reported about a run into an outcome
+func (s *Session) classify(output RunOutput) ExecutionResult {
+	if output.Hung {
+		return ExecutionResult{
+			Outcome:       OutcomeTimeLimit,
+			ExecutionTime: fmt.Sprintf(">%.1fs", s.timeLimit),
+		}
+	}
+
+	if output.OutputExceeded {
+		// Whatever else happened, the program had already produced too much
+		return ExecutionResult{
+			Outcome:       OutcomeOutputLimit,
+			Stderr:        output.Stderr,
+			ExitCode:      output.Stats.ExitCode,
+			Signal:        output.Stats.Signal,
+			CPUTime:       output.Stats.CPUTimeMs,
+			ExecutionTime: fmt.Sprintf("%dms", output.Stats.CPUTimeMs),
+			MemoryUsed:    output.Stats.MaxRSSKB,
+		}
+	}
+
+	stats := output.Stats
+	result := ExecutionResult{
+		Stdout:        output.Stdout,
+		Stderr:        output.Stderr,
+		ExitCode:      stats.ExitCode,
+		Signal:        stats.Signal,
+		CPUTime:       stats.CPUTimeMs,
+		ExecutionTime: fmt.Sprintf("%dms", stats.CPUTimeMs),
+		MemoryUsed:    stats.MaxRSSKB,
+	}
+
+	switch {
+	case stats.StartErr != "":
+		result.Outcome = OutcomeSystemError
+		result.Stderr = stats.StartErr
+	case stats.TimedOut || stats.WallTimedOut:
+		result.Outcome = OutcomeTimeLimit
+		result.ExecutionTime = fmt.Sprintf(">%.1fs", s.timeLimit)
+	case stats.OOMKilled ||
+		((stats.ExitCode != 0 || stats.Signal != 0) && stats.MaxRSSKB*1024 >= s.memoryLimit):
+		// The cgroup OOM killer fired, or the program failed once it had used up the problem's limit,
+		// e.g. a runtime that gave up on its own heap limit. What it printed is not trusted.
+		result.Outcome = OutcomeMemoryLimit
+	case stats.ExitCode != 0 || stats.Signal != 0:
+		result.Outcome = OutcomeRuntimeError
+	default:
+		result.Outcome = OutcomeOK
+	}
+
+	return result
+}
+
+// Close destroys the sandbox
+func (s *Session) Close() {
+	if s.sandbox != nil {
+		s.sandbox.Cleanup()
+	}
+}
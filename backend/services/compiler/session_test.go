@@ -0,0 +1,44 @@
+package compiler
+
+import (
+	"onlineJudge/backend/services/compiler/runner"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	const limitKB = 64 * 1024
+	session := &Session{timeLimit: 1, memoryLimit: limitKB * 1024, runLimits: Limits{Memory: (limitKB + 32*1024) * 1024}}
+
+	tests := []struct {
+		name    string
+		output  RunOutput
+		outcome Outcome
+	}{
+		{"exited normally", RunOutput{Stats: runner.Stats{MaxRSSKB: 1024}}, OutcomeOK},
+		{"exit code", RunOutput{Stats: runner.Stats{ExitCode: 1}}, OutcomeRuntimeError},
+		{"signal", RunOutput{Stats: runner.Stats{ExitCode: 139, Signal: int(syscall.SIGSEGV)}}, OutcomeRuntimeError},
+		{"cpu time", RunOutput{Stats: runner.Stats{ExitCode: 137, Signal: int(syscall.SIGKILL), TimedOut: true}}, OutcomeTimeLimit},
+		{"wall time", RunOutput{Stats: runner.Stats{WallTimedOut: true}}, OutcomeTimeLimit},
+		{"sandbox hung", RunOutput{Hung: true}, OutcomeTimeLimit},
+		{"output limit", RunOutput{OutputExceeded: true, Stats: runner.Stats{ExitCode: 1}}, OutcomeOutputLimit},
+		{"start error", RunOutput{Stats: runner.Stats{ExitCode: 127, StartErr: "not found"}}, OutcomeSystemError},
+
+		{"oom killed", RunOutput{Stats: runner.Stats{ExitCode: 137, Signal: int(syscall.SIGKILL), OOMKilled: true}}, OutcomeMemoryLimit},
+		{"killed at the limit", RunOutput{Stats: runner.Stats{ExitCode: 137, Signal: int(syscall.SIGKILL), MaxRSSKB: limitKB + 100}}, OutcomeMemoryLimit},
+		{"heap exhausted at the limit", RunOutput{Stats: runner.Stats{ExitCode: 1, MaxRSSKB: limitKB}}, OutcomeMemoryLimit},
+		{"within the limit with overhead", RunOutput{Stats: runner.Stats{MaxRSSKB: limitKB + 100}}, OutcomeOK},
+		{"failed below the limit", RunOutput{Stats: runner.Stats{ExitCode: 1, MaxRSSKB: limitKB - 1}}, OutcomeRuntimeError},
+		{"out of memory message is not trusted", RunOutput{
+			Stderr: "Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space\nMemoryError\nstd::bad_alloc",
+			Stats:  runner.Stats{ExitCode: 1, MaxRSSKB: 1024},
+		}, OutcomeRuntimeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := session.classify(tt.output); result.Outcome != tt.outcome {
+				t.Errorf("outcome = %s, want %s", result.Outcome, tt.outcome)
+			}
+		})
+	}
+}
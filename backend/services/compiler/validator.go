@@ -0,0 +1,51 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Validator is a compiled input validator. Following testlib, it reads a test input from stdin
+// and exits with 0 if the input satisfies the constraints, or with an error message otherwise.
+type Validator struct {
+	session *Session
+}
+
+// NewValidator compiles the validator in its own sandbox. The caller must Close it.
+func NewValidator(source, language string) (*Validator, error) {
+	session, err := newJurySession(source, language, "validator")
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{session: session}, nil
+}
+
+// Validate checks one test input. It returns the validator's message for an invalid input,
+// and an error only if the validator itself could not run to completion.
+func (v *Validator) Validate(input io.Reader) (valid bool, message string, err error) {
+	result, err := v.session.Run(input)
+	if err != nil {
+		return false, "", err
+	}
+
+	message = strings.TrimSpace(result.Stderr)
+	if message == "" {
+		message = strings.TrimSpace(result.Stdout)
+	}
+	message = Preview(message, 1024)
+
+	switch {
+	case result.Outcome == OutcomeOK:
+		return true, message, nil
+	case result.Outcome == OutcomeRuntimeError && result.Signal == 0:
+		return false, message, nil
+	default:
+		return false, "", fmt.Errorf("validator %s: %s", result.Outcome, message)
+	}
+}
+
+// Close destroys the validator's sandbox
+func (v *Validator) Close() {
+	v.session.Close()
+}
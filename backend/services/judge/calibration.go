@@ -0,0 +1,48 @@
+package judge
+
+import (
+	"fmt"
+	"math"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/compiler"
+)
+
+// Calibration settings
+const (
+	calibrationTimeLimit = 10.0 // Seconds given to correct solutions while they are measured
+	minProposedTimeLimit = 0.5  // Smallest time limit proposed, below it timings are mostly noise
+
+	DefaultCalibrationRuns   = 3
+	MaxCalibrationRuns       = 10
+	DefaultCalibrationFactor = 2.0
+	MaxCalibrationFactor     = 10.0
+)
+
+// Accepted range of the time limit of a problem, in seconds
+const (
+	MinTimeLimit = 0.1
+	MaxTimeLimit = 60.0
+)
+
+// proposeTimeLimit finds the smallest problem time limit that gives every correct solution factor
+// times its slowest run, taking the language multipliers into account, rounded up to 0.1s.
+// It never proposes more than MaxTimeLimit, even if that leaves the solutions less room.
+func proposeTimeLimit(rows []models.InvocationSolution, factor float64) (float64, error) {
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("Problem has no correct solutions to measure")
+	}
+
+	limit := minProposedTimeLimit
+	for _, row := range rows {
+		if !row.Matches {
+			return 0, fmt.Errorf("Solution %s is not correct: %s", row.Name, row.Problem)
+		}
+		needed, err := compiler.BaseTimeLimit(row.Language, factor*float64(row.MaxTime)/1000)
+		if err != nil {
+			return 0, err
+		}
+		limit = max(limit, needed)
+	}
+	// The epsilon keeps float noise such as 1.2000000001 from rounding up to 1.3
+	return min(math.Ceil(limit*10-1e-9)/10, MaxTimeLimit), nil
+}
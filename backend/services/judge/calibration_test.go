@@ -0,0 +1,65 @@
+package judge
+
+import (
+	"onlineJudge/backend/app/models"
+	"strings"
+	"testing"
+)
+
+func TestProposeTimeLimit(t *testing.T) {
+	correct := func(language string, maxTime int64) models.InvocationSolution {
+		return models.InvocationSolution{Name: language, Language: language, Matches: true, MaxTime: maxTime}
+	}
+
+	tests := []struct {
+		name     string
+		rows     []models.InvocationSolution
+		factor   float64
+		expected float64
+	}{
+		{"factor scales the slowest run", []models.InvocationSolution{correct("c", 400)}, 2, 0.8},
+		{"larger factor", []models.InvocationSolution{correct("c", 400)}, 3, 1.2},
+		{"rounded up to 0.1s", []models.InvocationSolution{correct("c", 610)}, 2, 1.3},
+		{"exact tenths are not rounded up", []models.InvocationSolution{correct("c", 600)}, 2, 1.2},
+		{"fast solutions get the minimum", []models.InvocationSolution{correct("c", 10)}, 2, minProposedTimeLimit},
+		{"no time at all", []models.InvocationSolution{correct("c", 0)}, 2, minProposedTimeLimit},
+		{"language multiplier is undone", []models.InvocationSolution{correct("python", 1800)}, 2, 1.8},
+		{"JVM multiplier is undone", []models.InvocationSolution{correct("java", 1000)}, 2, 1.0},
+		{"slowest solution decides", []models.InvocationSolution{correct("c", 300), correct("cpp", 900), correct("python", 1000)}, 2, 1.8},
+		{"capped at the largest time limit", []models.InvocationSolution{correct("c", 9500)}, 10, MaxTimeLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposed, err := proposeTimeLimit(tt.rows, tt.factor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proposed != tt.expected {
+				t.Errorf("proposeTimeLimit = %v, want %v", proposed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProposeTimeLimitErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []models.InvocationSolution
+		message string
+	}{
+		{"no correct solutions", nil, "no correct solutions"},
+		{"incorrect solution", []models.InvocationSolution{
+			{Name: "main", Language: "c", Matches: true, MaxTime: 100},
+			{Name: "slow", Language: "c", Matches: false, Problem: "Wrong Answer on test #3"},
+		}, "slow is not correct: Wrong Answer on test #3"},
+		{"unknown language", []models.InvocationSolution{{Name: "main", Language: "cobol", Matches: true}}, "unsupported language"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := proposeTimeLimit(tt.rows, 2)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error = %v, want one containing %q", err, tt.message)
+			}
+		})
+	}
+}
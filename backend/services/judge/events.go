@@ -0,0 +1,74 @@
+package judge
+
+import (
+	"onlineJudge/backend/app/models"
+	"sync"
+)
+
+// Event types pushed to submission status subscribers
+const (
+	EventStatus    = "status"    // Submission status changed (Pending, Running)
+	EventCompiling = "compiling" // Source is being compiled
+	EventRunning   = "running"   // A test case started, see Test/Total
+	EventDetail    = "detail"    // A test verdict was written
+	EventFinal     = "final"     // Judging finished, Status holds the final verdict
+)
+
+// Event is a progress notification for a single submission
+type Event struct {
+	Type          string                   `json:"type"`
+	SubmissionID  uint                     `json:"submission_id"`
+	Status        string                   `json:"status,omitempty"`
+	Test          int                      `json:"test,omitempty"`
+	Total         int                      `json:"total,omitempty"`
+	ExecutionTime string                   `json:"execution_time,omitempty"`
+	Detail        *models.SubmissionDetail `json:"detail,omitempty"`
+}
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped
+const subscriberBuffer = 64
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = map[uint]map[chan Event]struct{}{}
+)
+
+// Subscribe returns a channel receiving progress events of the submission judged in this process.
+// The returned function must be called to unsubscribe.
+func Subscribe(submissionID uint) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	subscribersMu.Lock()
+	if subscribers[submissionID] == nil {
+		subscribers[submissionID] = map[chan Event]struct{}{}
+	}
+	subscribers[submissionID][ch] = struct{}{}
+	subscribersMu.Unlock()
+
+	return ch, func() {
+		subscribersMu.Lock()
+		delete(subscribers[submissionID], ch)
+		if len(subscribers[submissionID]) == 0 {
+			delete(subscribers, submissionID)
+		}
+		subscribersMu.Unlock()
+	}
+}
+
+func publish(event Event) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for ch := range subscribers[event.SubmissionID] {
+		select {
+		case ch <- event:
+		default:
+			// Never block judging on a slow client
+		}
+	}
+}
+
+// IsFinal reports whether a submission status is a final verdict
+func IsFinal(status string) bool {
+	return status != "Pending" && status != "Running"
+}
@@ -0,0 +1,249 @@
+package judge
+
+import (
+	"fmt"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/revision"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Tags of reference solutions: the verdict a solution is expected to get
+const (
+	TagAccepted       = "accepted"         // Passes every test
+	TagWrongAnswer    = "wrong_answer"     // Gets a wrong answer on some test, passes the others
+	TagTimeLimit      = "time_limit"       // Is too slow on some test, passes the others
+	TagMemoryLimit    = "memory_limit"     // Uses too much memory on some test, passes the others
+	TagRuntimeError   = "runtime_error"    // Crashes on some test, passes the others
+	TagTimeOrAccepted = "time_or_accepted" // Passes or is too slow on each test: a slow correct solution
+	TagRejected       = "rejected"         // Fails some test in any way
+)
+
+// tagExpectation is what a tag allows: which failures, and whether some failure is required
+type tagExpectation struct {
+	mustFail bool
+	failures []string // Allowed failing verdicts, nil for any
+}
+
+var tagExpectations = map[string]tagExpectation{
+	TagAccepted:       {mustFail: false, failures: []string{}},
+	TagWrongAnswer:    {mustFail: true, failures: []string{VerdictWrongAnswer, VerdictPresentationError, VerdictPartiallyCorrect}},
+	TagTimeLimit:      {mustFail: true, failures: []string{VerdictTimeLimitExceeded}},
+	TagMemoryLimit:    {mustFail: true, failures: []string{VerdictMemoryLimitExceeded}},
+	TagRuntimeError:   {mustFail: true, failures: []string{VerdictRuntimeError}},
+	TagTimeOrAccepted: {mustFail: false, failures: []string{VerdictTimeLimitExceeded}},
+	TagRejected:       {mustFail: true, failures: nil},
+}
+
+// ValidTag reports whether tag is a known expected verdict
+func ValidTag(tag string) bool {
+	_, ok := tagExpectations[tag]
+	return ok
+}
+
+// checkTag tells whether the verdicts of a solution on the tests agree with its tag, and if not, why
+func checkTag(tag string, verdicts []string) (bool, string) {
+	expected := tagExpectations[tag]
+	failed := false
+	for i, verdict := range verdicts {
+		if verdict == VerdictAccepted {
+			continue
+		}
+		if verdict == VerdictSystemError || (expected.failures != nil && !slices.Contains(expected.failures, verdict)) {
+			if verdict == VerdictCompilationError {
+				return false, verdict
+			}
+			return false, fmt.Sprintf("%s on test #%d", verdict, i+1)
+		}
+		failed = true
+	}
+	if expected.mustFail && !failed {
+		return false, "Passed all tests"
+	}
+	return true, ""
+}
+
+// runInvocation runs an invocation, converting a panic into an error like runJudge
+func runInvocation(invocationID uint) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while invoking: %v", r)
+			database.DB.Model(&models.Invocation{}).Where("id = ?", invocationID).
+				Updates(map[string]interface{}{"status": JobFailed, "error": err.Error()})
+		}
+	}()
+	return Invoke(invocationID)
+}
+
+// Invoke judges the author solution and every reference solution of the problem on all tests
+// and stores the matrix of verdicts, one solution at a time. A calibration only runs the correct
+// solutions and then proposes a time limit.
+func Invoke(invocationID uint) error {
+	var invocation models.Invocation
+	if err := database.DB.First(&invocation, invocationID).Error; err != nil {
+		return fmt.Errorf("invocation not found: %v", err)
+	}
+
+	var problem models.Problem
+	// Columns of the matrix in the order the tests were added
+	byID := func(db *gorm.DB) *gorm.DB { return db.Order("id") }
+	if err := database.DB.Preload("TestCases", byID).Preload("Solutions", byID).
+		First(&problem, invocation.ProblemID).Error; err != nil {
+		return fmt.Errorf("problem not found: %v", err)
+	}
+
+	// A reclaimed job starts over
+	invocation.Status = JobRunning
+	invocation.Error = ""
+	invocation.TimeLimit = problem.TimeLimit
+	invocation.Solutions = []models.InvocationSolution{}
+	invocation.TestCaseIDs = make([]uint, len(problem.TestCases))
+	for i, tc := range problem.TestCases {
+		invocation.TestCaseIDs[i] = tc.ID
+	}
+	database.DB.Save(&invocation)
+
+	// Past this point the invocation shows as running, so an error must mark it failed:
+	// the worker only fails the job, and reclaiming only restarts jobs that are still running
+	fail := func(err error) error {
+		invocation.Status = JobFailed
+		invocation.Error = err.Error()
+		database.DB.Save(&invocation)
+		return err
+	}
+
+	var solutions []models.ProblemSolution
+	if problem.AuthorSourceCode != "" {
+		solutions = append(solutions, models.ProblemSolution{Name: "main", SourceCode: problem.AuthorSourceCode, Language: problem.AuthorLanguage, Tag: TagAccepted})
+	}
+	calibrating := invocation.Runs > 0
+	for _, solution := range problem.Solutions {
+		if !calibrating || solution.Tag == TagAccepted {
+			solutions = append(solutions, solution)
+		}
+	}
+
+	// Measured solutions get room to show how slow they really are
+	limits := runLimits{timeLimit: problem.TimeLimit, runs: 1}
+	if calibrating {
+		limits = runLimits{timeLimit: calibrationTimeLimit, runs: invocation.Runs}
+	}
+
+	runner, err := newTestRunner(problem)
+	if err != nil {
+		return fail(err)
+	}
+	defer runner.Close()
+
+	for _, solution := range solutions {
+		invocation.Solutions = append(invocation.Solutions, invokeSolution(runner, problem, solution, limits))
+		database.DB.Save(&invocation)
+	}
+
+	if calibrating {
+		proposed, err := proposeTimeLimit(invocation.Solutions, invocation.Factor)
+		if err != nil {
+			invocation.Error = err.Error()
+		} else {
+			invocation.ProposedTimeLimit = proposed
+			if invocation.Apply {
+				err := database.DB.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Model(&problem).Update("time_limit", proposed).Error; err != nil {
+						return err
+					}
+					_, err := revision.Record(tx, problem.ID, invocation.UserID, fmt.Sprintf("Time limit calibrated to %gs", proposed))
+					return err
+				})
+				if err != nil {
+					return fail(fmt.Errorf("failed to apply the time limit: %v", err))
+				}
+				invocation.Applied = true
+			}
+		}
+	}
+
+	invocation.Status = JobDone
+	return database.DB.Save(&invocation).Error
+}
+
+// runLimits is how an invocation runs each solution
+type runLimits struct {
+	timeLimit float64 // Seconds, before the language multiplier
+	runs      int     // Runs per test; the slowest one counts
+}
+
+// invokeSolution judges one solution on every test, without stopping at the first failure
+func invokeSolution(runner *testRunner, problem models.Problem, solution models.ProblemSolution, limits runLimits) models.InvocationSolution {
+	row := models.InvocationSolution{
+		SolutionID: solution.ID,
+		Name:       solution.Name,
+		Language:   solution.Language,
+		Tag:        solution.Tag,
+		Verdict:    VerdictAccepted,
+		Tests:      []models.InvocationTest{},
+	}
+
+	session, err := compiler.NewSession(compiler.CompilerSubmission{
+		SourceCode:  solution.SourceCode,
+		Language:    solution.Language,
+		TimeLimit:   limits.timeLimit,
+		MemoryLimit: problem.MemoryLimit,
+		OutputLimit: problem.OutputLimit,
+	})
+	if err != nil {
+		row.Verdict = VerdictSystemError
+		row.CompileOutput = err.Error()
+		row.Matches, row.Problem = checkTag(solution.Tag, []string{row.Verdict})
+		return row
+	}
+	defer session.Close()
+
+	compileResult, err := session.Compile()
+	if err == nil && compileResult != nil {
+		row.CompileOutput = compiler.Preview(compileResult.Output, detailPreviewLimit)
+		if !compileResult.Success {
+			row.Verdict = VerdictCompilationError
+		}
+	}
+	if err != nil {
+		row.Verdict = VerdictSystemError
+		row.CompileOutput = err.Error()
+	}
+	if row.Verdict != VerdictAccepted {
+		row.Matches, row.Problem = checkTag(solution.Tag, []string{row.Verdict})
+		return row
+	}
+
+	verdicts := make([]string, 0, len(problem.TestCases))
+	for _, tc := range problem.TestCases {
+		cell := models.InvocationTest{TestCaseID: tc.ID, Verdict: VerdictAccepted}
+		for run := 0; run < limits.runs; run++ {
+			tested := runner.run(session, tc)
+			cell.Time = max(cell.Time, tested.Result.CPUTime)
+			cell.Memory = max(cell.Memory, tested.Result.MemoryUsed)
+			if tested.Status != VerdictAccepted {
+				cell.Verdict = tested.Status
+				break
+			}
+		}
+		row.Tests = append(row.Tests, cell)
+		verdicts = append(verdicts, cell.Verdict)
+
+		row.MaxTime = max(row.MaxTime, cell.Time)
+		row.MaxMemory = max(row.MaxMemory, cell.Memory)
+		if cell.Verdict != VerdictAccepted && row.Verdict == VerdictAccepted {
+			row.Verdict = cell.Verdict
+		}
+	}
+
+	row.Matches, row.Problem = checkTag(solution.Tag, verdicts)
+
+	// A correct solution should have room to spare, or small differences between runs decide the verdict
+	if row.Matches && solution.Tag == TagAccepted && limits.runs == 1 && float64(row.MaxTime) > problem.TimeLimit*1000/2 {
+		row.Warning = fmt.Sprintf("Uses more than half of the time limit (%d ms)", row.MaxTime)
+	}
+	return row
+}
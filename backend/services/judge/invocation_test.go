@@ -0,0 +1,77 @@
+package judge
+
+import "testing"
+
+func TestCheckTag(t *testing.T) {
+	const (
+		ok  = VerdictAccepted
+		wa  = VerdictWrongAnswer
+		pe  = VerdictPresentationError
+		pc  = VerdictPartiallyCorrect
+		tle = VerdictTimeLimitExceeded
+		mle = VerdictMemoryLimitExceeded
+		re  = VerdictRuntimeError
+		ole = VerdictOutputLimitExceeded
+		ce  = VerdictCompilationError
+		se  = VerdictSystemError
+	)
+
+	tests := []struct {
+		tag      string
+		verdicts []string
+		matches  bool
+		problem  string
+	}{
+		{TagAccepted, []string{ok, ok, ok}, true, ""},
+		{TagAccepted, []string{}, true, ""},
+		{TagAccepted, []string{ok, wa, ok}, false, wa + " on test #2"},
+		{TagAccepted, []string{tle}, false, tle + " on test #1"},
+		{TagAccepted, []string{ce}, false, ce},
+
+		{TagWrongAnswer, []string{ok, wa}, true, ""},
+		{TagWrongAnswer, []string{pe, ok}, true, ""},
+		{TagWrongAnswer, []string{pc}, true, ""},
+		{TagWrongAnswer, []string{ok, ok}, false, "Passed all tests"},
+		{TagWrongAnswer, []string{wa, tle}, false, tle + " on test #2"},
+		{TagWrongAnswer, []string{ce}, false, ce},
+
+		{TagTimeLimit, []string{ok, tle, tle}, true, ""},
+		{TagTimeLimit, []string{ok}, false, "Passed all tests"},
+		{TagTimeLimit, []string{tle, wa}, false, wa + " on test #2"},
+
+		{TagMemoryLimit, []string{mle}, true, ""},
+		{TagMemoryLimit, []string{ok, re}, false, re + " on test #2"},
+
+		{TagRuntimeError, []string{ok, re}, true, ""},
+		{TagRuntimeError, []string{ok, ok}, false, "Passed all tests"},
+		{TagRuntimeError, []string{mle}, false, mle + " on test #1"},
+
+		{TagTimeOrAccepted, []string{ok, ok}, true, ""},
+		{TagTimeOrAccepted, []string{ok, tle}, true, ""},
+		{TagTimeOrAccepted, []string{ok, wa}, false, wa + " on test #2"},
+
+		{TagRejected, []string{ok, wa}, true, ""},
+		{TagRejected, []string{tle, mle, re, ole}, true, ""},
+		{TagRejected, []string{ce}, true, ""},
+		{TagRejected, []string{ok, ok}, false, "Passed all tests"},
+		{TagRejected, []string{ok, se}, false, se + " on test #2"},
+	}
+
+	for _, tt := range tests {
+		matches, problem := checkTag(tt.tag, tt.verdicts)
+		if matches != tt.matches || problem != tt.problem {
+			t.Errorf("checkTag(%q, %q) = %v, %q; want %v, %q", tt.tag, tt.verdicts, matches, problem, tt.matches, tt.problem)
+		}
+	}
+}
+
+func TestValidTag(t *testing.T) {
+	for tag := range tagExpectations {
+		if !ValidTag(tag) {
+			t.Errorf("ValidTag(%q) = false", tag)
+		}
+	}
+	if ValidTag("main") || ValidTag("") {
+		t.Error("unknown tags are valid")
+	}
+}
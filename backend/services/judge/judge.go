@@ -0,0 +1,183 @@
+package judge
+
+import (
+	"fmt"
+	"log"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/revision"
+
+	"gorm.io/gorm"
+)
+
+const (
+	logPreviewLimit    = 200      // Bytes of test data shown in the log of a failed test
+	detailPreviewLimit = 4 * 1024 // Bytes of stderr and checker message stored per test
+)
+
+// runJudge judges a submission, converting a panic into an error so one bad job cannot kill the worker
+func runJudge(submissionID uint) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while judging: %v", r)
+			database.DB.Model(&models.Submission{}).Where("id = ?", submissionID).Update("status", VerdictSystemError)
+			publish(Event{Type: EventFinal, SubmissionID: submissionID, Status: VerdictSystemError})
+		}
+	}()
+	return Judge(submissionID)
+}
+
+// Judge runs a submission against all test cases of its problem and stores the verdicts
+func Judge(submissionID uint) error {
+	var submission models.Submission
+	if err := database.DB.First(&submission, submissionID).Error; err != nil {
+		return fmt.Errorf("submission not found: %v", err)
+	}
+
+	var problem models.Problem
+	// Tests run in the order they were added, which decides the first failing one;
+	// subtasks are stored in index order, see SetSubtasks
+	byID := func(db *gorm.DB) *gorm.DB { return db.Order("id") }
+	if err := database.DB.Preload("TestCases", byID).Preload("Subtasks", byID).
+		First(&problem, submission.ProblemID).Error; err != nil {
+		// Left Pending, the submission would wait for a verdict that never comes
+		err = fmt.Errorf("problem not found: %v", err)
+		submission.Status = VerdictSystemError
+		submission.CompileOutput = err.Error()
+		database.DB.Save(&submission)
+		publish(Event{Type: EventFinal, SubmissionID: submission.ID, Status: submission.Status})
+		return err
+	}
+
+	// A reclaimed job may have partial results from the previous attempt
+	database.DB.Where("submission_id = ?", submission.ID).Delete(&models.SubmissionDetail{})
+	submission.CompileOutput = ""
+	submission.Score = 0
+	submission.SubtaskScores = nil
+
+	// Later edits of the problem do not change what this verdict was judged against
+	submission.RevisionID = revision.LatestID(database.DB, problem.ID)
+	submission.Status = "Running"
+	database.DB.Save(&submission)
+	publish(Event{Type: EventStatus, SubmissionID: submission.ID, Status: submission.Status})
+
+	compSubmission := compiler.CompilerSubmission{
+		SourceCode:  submission.SourceCode,
+		Language:    submission.Language,
+		TimeLimit:   problem.TimeLimit,
+		MemoryLimit: problem.MemoryLimit,
+		OutputLimit: problem.OutputLimit,
+	}
+
+	// Problems with subtasks are scored, so every test has to run
+	scored := len(problem.Subtasks) > 0
+	testScores := map[uint]float64{}
+
+	finalStatus := VerdictAccepted
+	totalTime := ""
+	var maxCPUTime int64
+	var peakMemory int64
+
+	// finish stores the final verdict and notifies subscribers
+	finish := func() error {
+		submission.Status = finalStatus
+		submission.ExecutionTime = totalTime
+		submission.Memory = peakMemory
+		if scored {
+			submission.SubtaskScores, submission.Score = scoreSubtasks(problem.Subtasks, problem.TestCases, testScores)
+		}
+		err := database.DB.Save(&submission).Error
+		publish(Event{Type: EventFinal, SubmissionID: submission.ID, Status: submission.Status, ExecutionTime: submission.ExecutionTime})
+		return err
+	}
+
+	// One sandbox per submission: compile once, then run every test in it
+	session, err := compiler.NewSession(compSubmission)
+	if err != nil {
+		finalStatus = VerdictSystemError
+		submission.CompileOutput = err.Error()
+		return finish()
+	}
+	defer session.Close()
+
+	publish(Event{Type: EventCompiling, SubmissionID: submission.ID})
+
+	compileResult, err := session.Compile()
+	if err != nil {
+		finalStatus = VerdictSystemError
+		submission.CompileOutput = err.Error()
+		return finish()
+	}
+	if compileResult != nil {
+		submission.CompileOutput = compileResult.Output
+		if !compileResult.Success {
+			// Nothing was run, so there is no per-test detail
+			finalStatus = VerdictCompilationError
+			return finish()
+		}
+	}
+
+	// The special judge and the interactor are compiled once, like the submission
+	runner, err := newTestRunner(problem)
+	if err != nil {
+		finalStatus = VerdictSystemError
+		submission.CompileOutput = err.Error()
+		return finish()
+	}
+	defer runner.Close()
+
+	for i, tc := range problem.TestCases {
+		publish(Event{Type: EventRunning, SubmissionID: submission.ID, Test: i + 1, Total: len(problem.TestCases)})
+
+		tested := runner.run(session, tc)
+		status, result, testScore := tested.Status, tested.Result, tested.Score
+		if tested.expected != nil {
+			log.Printf("Submission %d failed test #%d (input %s): expected %q, got %q", submission.ID, i+1,
+				tc.InputHash, compiler.Preview(*tested.expected, logPreviewLimit), compiler.Preview(result.Stdout, logPreviewLimit))
+		}
+
+		// Save Detail
+		detail := models.SubmissionDetail{
+			SubmissionID:   submission.ID,
+			TestCaseID:     tc.ID,
+			Status:         status,
+			ExecutionTime:  result.ExecutionTime,
+			Memory:         result.MemoryUsed,
+			Score:          testScore,
+			ExitCode:       result.ExitCode,
+			Signal:         result.Signal,
+			Stderr:         compiler.Preview(result.Stderr, detailPreviewLimit),
+			CheckerMessage: compiler.Preview(tested.CheckerMessage, detailPreviewLimit),
+			IsSample:       tc.IsSample,
+		}
+		database.DB.Create(&detail)
+		publish(Event{Type: EventDetail, SubmissionID: submission.ID, Test: i + 1, Total: len(problem.TestCases), Detail: &detail})
+
+		// Report the slowest test, like most judges do
+		if result.CPUTime > maxCPUTime {
+			maxCPUTime = result.CPUTime
+		}
+		totalTime = fmt.Sprintf("%dms", maxCPUTime)
+		if result.Outcome == compiler.OutcomeTimeLimit {
+			totalTime = result.ExecutionTime
+		}
+		if result.MemoryUsed > peakMemory {
+			peakMemory = result.MemoryUsed
+		}
+
+		testScores[tc.ID] = testScore
+
+		if status != VerdictAccepted {
+			// The first failed test decides the verdict; only scored problems keep going
+			if finalStatus == VerdictAccepted {
+				finalStatus = status
+			}
+			if !scored {
+				break
+			}
+		}
+	}
+
+	return finish()
+}
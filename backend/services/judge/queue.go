@@ -0,0 +1,153 @@
+package judge
+
+import (
+	"errors"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job statuses
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// maxAttempts is how many times a job is retried after its worker died
+const maxAttempts = 3
+
+var errNoJob = errors.New("no queued judge jobs")
+
+// wakeup lets Notify nudge idle workers in this process instead of waiting for the next poll
+var wakeup = make(chan struct{}, 1)
+
+// Enqueue adds a judge job for the submission using the given transaction,
+// so the job is only visible once the submission itself is committed.
+// Call Notify once the transaction has committed.
+func Enqueue(tx *gorm.DB, submissionID uint) error {
+	job := models.JudgeJob{
+		SubmissionID: submissionID,
+		Status:       JobQueued,
+	}
+	return tx.Create(&job).Error
+}
+
+// EnqueueInvocation adds a job running the invocation, like Enqueue does for submissions.
+// Call Notify once the transaction has committed.
+func EnqueueInvocation(tx *gorm.DB, invocationID uint) error {
+	job := models.JudgeJob{
+		InvocationID: invocationID,
+		Status:       JobQueued,
+	}
+	return tx.Create(&job).Error
+}
+
+// Notify wakes an idle worker of this process. Woken before the commit, it would find no job
+// and go back to sleep until the next poll.
+func Notify() {
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// claim locks the oldest queued job and marks it as running by workerID.
+// Submissions go before invocations, which are only claimed if withInvocations is set.
+func claim(workerID string, withInvocations bool) (models.JudgeJob, error) {
+	var job models.JudgeJob
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", JobQueued)
+		if !withInvocations {
+			query = query.Where("invocation_id = 0")
+		}
+		result := query.
+			Order("invocation_id <> 0, id").
+			Limit(1).
+			Find(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNoJob
+		}
+
+		now := time.Now()
+		job.Status = JobRunning
+		job.WorkerID = workerID
+		job.LockedAt = &now
+		job.Attempts++
+		return tx.Save(&job).Error
+	})
+	return job, err
+}
+
+// heartbeat refreshes the lock so the job is not reclaimed while it is being judged
+func heartbeat(job models.JudgeJob) {
+	database.DB.Model(&models.JudgeJob{}).
+		Where("id = ? AND worker_id = ?", job.ID, job.WorkerID).
+		Update("locked_at", time.Now())
+}
+
+// finish records the outcome of a claimed job
+func finish(job models.JudgeJob, judgeErr error) {
+	updates := map[string]interface{}{"status": JobDone, "last_error": ""}
+	if judgeErr != nil {
+		updates["status"] = JobFailed
+		updates["last_error"] = judgeErr.Error()
+	}
+	database.DB.Model(&models.JudgeJob{}).
+		Where("id = ? AND worker_id = ?", job.ID, job.WorkerID).
+		Updates(updates)
+}
+
+// reclaim puts running jobs whose worker stopped sending heartbeats back in the queue.
+// Jobs that already used up their attempts are failed together with their submission or invocation.
+func reclaim(staleAfter time.Duration) {
+	cutoff := time.Now().Add(-staleAfter)
+
+	var stale []models.JudgeJob
+	database.DB.Where("status = ? AND locked_at < ?", JobRunning, cutoff).Find(&stale)
+
+	for _, job := range stale {
+		if job.Attempts >= maxAttempts {
+			database.DB.Model(&models.JudgeJob{}).
+				Where("id = ? AND status = ?", job.ID, JobRunning).
+				Updates(map[string]interface{}{"status": JobFailed, "last_error": "worker stopped responding"})
+			if job.InvocationID != 0 {
+				database.DB.Model(&models.Invocation{}).
+					Where("id = ?", job.InvocationID).
+					Updates(map[string]interface{}{"status": JobFailed, "error": "worker stopped responding"})
+				continue
+			}
+			database.DB.Model(&models.Submission{}).
+				Where("id = ?", job.SubmissionID).
+				Update("status", VerdictSystemError)
+			publish(Event{Type: EventFinal, SubmissionID: job.SubmissionID, Status: VerdictSystemError})
+			continue
+		}
+
+		result := database.DB.Model(&models.JudgeJob{}).
+			Where("id = ? AND status = ? AND locked_at < ?", job.ID, JobRunning, cutoff).
+			Updates(map[string]interface{}{"status": JobQueued, "worker_id": ""})
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if job.InvocationID != 0 {
+			database.DB.Model(&models.Invocation{}).
+				Where("id = ?", job.InvocationID).
+				Update("status", JobQueued)
+		} else {
+			database.DB.Model(&models.Submission{}).
+				Where("id = ?", job.SubmissionID).
+				Update("status", "Pending")
+			publish(Event{Type: EventStatus, SubmissionID: job.SubmissionID, Status: "Pending"})
+		}
+		Notify()
+	}
+}
@@ -0,0 +1,131 @@
+package judge
+
+import (
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/comparator"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/storage"
+)
+
+// testRunner judges compiled programs on the tests of a problem, with the problem's
+// checker, interactor or built-in comparison
+type testRunner struct {
+	problem    models.Problem
+	checker    *compiler.Checker
+	interactor *compiler.Interactor
+}
+
+// testResult is the verdict of a program on one test
+type testResult struct {
+	Status         string
+	Result         compiler.ExecutionResult
+	CheckerMessage string
+	Score          float64 // Fraction of the test passed
+
+	expected *string // The answer of a failed built-in comparison, for the log
+}
+
+// newTestRunner compiles the checker or interactor of the problem, if any. The caller must Close it.
+func newTestRunner(problem models.Problem) (*testRunner, error) {
+	r := &testRunner{problem: problem}
+
+	var err error
+	if problem.CheckerSourceCode != "" {
+		if r.checker, err = compiler.NewChecker(problem.CheckerSourceCode, problem.CheckerLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	// Interactive problems run every test against the interactor instead of a fixed input
+	if problem.InteractorSourceCode != "" {
+		if r.interactor, err = compiler.NewInteractor(problem.InteractorSourceCode, problem.InteractorLanguage); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// run runs the compiled session on one test and checks its output
+func (r *testRunner) run(session *compiler.Session, tc models.TestCase) testResult {
+	// The input is streamed from the test storage straight into the sandbox
+	var result compiler.ExecutionResult
+	var check compiler.CheckResult
+	input, err := storage.Open(tc.InputHash)
+	if err == nil {
+		if r.interactor != nil {
+			result, check, err = r.interactor.Interact(session, input)
+		} else {
+			result, err = session.Run(input)
+		}
+		input.Close()
+	}
+
+	tested := testResult{Status: VerdictAccepted, Result: result}
+	switch {
+	case err != nil:
+		tested.Status = VerdictSystemError
+		tested.Result.Stderr = err.Error()
+	case r.interactor != nil:
+		tested.Status = verdictForInteraction(result.Outcome, check.Verdict)
+		tested.CheckerMessage = check.Message
+		if tested.Status == VerdictAccepted || tested.Status == VerdictPartiallyCorrect {
+			tested.Score = check.Score
+		}
+	case result.Outcome != compiler.OutcomeOK:
+		tested.Status = verdictForOutcome(result.Outcome)
+	case r.checker != nil:
+		check, err := checkTest(r.checker, tc, result.Stdout)
+		if err != nil {
+			tested.Status = VerdictSystemError
+			tested.CheckerMessage = err.Error()
+		} else {
+			tested.Status = verdictForCheck(check.Verdict)
+			tested.CheckerMessage = check.Message
+			tested.Score = check.Score
+		}
+	default:
+		expected, err := storage.ReadAll(tc.OutputHash)
+		if err != nil {
+			tested.Status = VerdictSystemError
+			tested.CheckerMessage = err.Error()
+			break
+		}
+		compared := comparator.Compare(comparator.Mode(r.problem.CheckerMode), r.problem.CheckerEpsilon, result.Stdout, expected)
+		tested.Status = verdictForComparison(compared.Verdict)
+		tested.CheckerMessage = compared.Message
+		if tested.Status == VerdictAccepted {
+			tested.Score = 1
+		} else {
+			tested.expected = &expected
+		}
+	}
+	return tested
+}
+
+// Close destroys the sandboxes of the checker and the interactor
+func (r *testRunner) Close() {
+	if r.checker != nil {
+		r.checker.Close()
+	}
+	if r.interactor != nil {
+		r.interactor.Close()
+	}
+}
+
+// checkTest streams the input and the answer of a test into the checker
+func checkTest(checker *compiler.Checker, tc models.TestCase, output string) (compiler.CheckResult, error) {
+	input, err := storage.Open(tc.InputHash)
+	if err != nil {
+		return compiler.CheckResult{}, err
+	}
+	defer input.Close()
+
+	answer, err := storage.Open(tc.OutputHash)
+	if err != nil {
+		return compiler.CheckResult{}, err
+	}
+	defer answer.Close()
+
+	return checker.Check(input, output, answer)
+}
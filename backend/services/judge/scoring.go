@@ -0,0 +1,92 @@
+package judge
+
+import (
+	"math"
+	"onlineJudge/backend/app/models"
+)
+
+// Subtask scoring modes
+const (
+	ScoringAll = "all" // Full points only if every test passes
+	ScoringMin = "min" // Points times the lowest test score
+	ScoringAvg = "avg" // Points times the average test score
+)
+
+// scoreSubtasks turns per-test scores (keyed by test case ID, missing means 0) into subtask scores.
+// A subtask is judged on its own tests plus the tests of the subtasks it depends on.
+func scoreSubtasks(subtasks []models.Subtask, tests []models.TestCase, testScores map[uint]float64) ([]models.SubtaskScore, float64) {
+	testsOf := map[int][]uint{}
+	for _, tc := range tests {
+		if tc.Subtask > 0 {
+			testsOf[tc.Subtask] = append(testsOf[tc.Subtask], tc.ID)
+		}
+	}
+
+	byIndex := map[int]models.Subtask{}
+	for _, st := range subtasks {
+		byIndex[st.Index] = st
+	}
+
+	scores := make([]models.SubtaskScore, 0, len(subtasks))
+	total := 0.0
+	for _, st := range subtasks {
+		var values []float64
+		for index := range dependencyClosure(st.Index, byIndex) {
+			for _, id := range testsOf[index] {
+				values = append(values, testScores[id])
+			}
+		}
+
+		score := st.Points * subtaskFraction(st.Scoring, values)
+		// Keep stored scores readable despite float arithmetic
+		score = math.Round(score*1e6) / 1e6
+		scores = append(scores, models.SubtaskScore{Index: st.Index, Score: score, Points: st.Points})
+		total += score
+	}
+	return scores, math.Round(total*1e6) / 1e6
+}
+
+// subtaskFraction aggregates test scores; a subtask without tests earns nothing
+func subtaskFraction(scoring string, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	switch scoring {
+	case ScoringAvg:
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	case ScoringMin:
+		lowest := 1.0
+		for _, v := range values {
+			lowest = math.Min(lowest, v)
+		}
+		return lowest
+	default:
+		for _, v := range values {
+			if v < 1 {
+				return 0
+			}
+		}
+		return 1
+	}
+}
+
+// dependencyClosure returns the subtask itself and everything it depends on, directly or not
+func dependencyClosure(index int, byIndex map[int]models.Subtask) map[int]bool {
+	seen := map[int]bool{}
+	stack := []int{index}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		stack = append(stack, byIndex[current].Dependencies...)
+	}
+	return seen
+}
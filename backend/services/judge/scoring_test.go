@@ -0,0 +1,84 @@
+package judge
+
+import (
+	"onlineJudge/backend/app/models"
+	"reflect"
+	"testing"
+)
+
+func TestScoreSubtasks(t *testing.T) {
+	// Tests 1-2 are in subtask 1, 3-4 in subtask 2, 5 in subtask 3 and 6 in none
+	tests := []models.TestCase{
+		{ID: 1, Subtask: 1}, {ID: 2, Subtask: 1},
+		{ID: 3, Subtask: 2}, {ID: 4, Subtask: 2},
+		{ID: 5, Subtask: 3},
+		{ID: 6, Subtask: 0},
+	}
+	all := map[uint]float64{1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1}
+	subtask := func(index int, points float64, scoring string, dependencies ...int) models.Subtask {
+		return models.Subtask{Index: index, Points: points, Scoring: scoring, Dependencies: dependencies}
+	}
+	with := func(changes map[uint]float64) map[uint]float64 {
+		scores := map[uint]float64{}
+		for id, score := range all {
+			scores[id] = score
+		}
+		for id, score := range changes {
+			scores[id] = score
+		}
+		return scores
+	}
+
+	cases := []struct {
+		name     string
+		subtasks []models.Subtask
+		scores   map[uint]float64
+		expected []float64
+		total    float64
+	}{
+		{"all tests pass", []models.Subtask{subtask(1, 30, ScoringAll), subtask(2, 70, ScoringAll)}, all, []float64{30, 70}, 100},
+		{"one failure zeroes its group", []models.Subtask{subtask(1, 30, ScoringAll), subtask(2, 70, ScoringAll)},
+			with(map[uint]float64{4: 0}), []float64{30, 0}, 30},
+		{"empty scoring means all", []models.Subtask{subtask(1, 30, "")}, with(map[uint]float64{1: 0.99}), []float64{0}, 0},
+		{"missing scores count as failed", []models.Subtask{subtask(1, 30, ScoringAll), subtask(2, 70, ScoringMin)},
+			map[uint]float64{1: 1, 2: 1, 3: 1}, []float64{30, 0}, 30},
+		{"tests outside subtasks are ignored", []models.Subtask{subtask(1, 30, ScoringAll)}, with(map[uint]float64{6: 0}), []float64{30}, 30},
+		{"subtask without tests earns nothing", []models.Subtask{subtask(4, 50, ScoringAll)}, all, []float64{0}, 0},
+
+		{"min of partial points", []models.Subtask{subtask(2, 70, ScoringMin)}, with(map[uint]float64{3: 0.5, 4: 0.8}), []float64{35}, 35},
+		{"min with all passed", []models.Subtask{subtask(2, 70, ScoringMin)}, all, []float64{70}, 70},
+		{"avg of partial points", []models.Subtask{subtask(2, 70, ScoringAvg)}, with(map[uint]float64{3: 0.5, 4: 0}), []float64{17.5}, 17.5},
+		{"partial points fail an all group", []models.Subtask{subtask(2, 70, ScoringAll)}, with(map[uint]float64{3: 0.5}), []float64{0}, 0},
+
+		{"dependency failure", []models.Subtask{subtask(1, 30, ScoringAll), subtask(2, 70, ScoringAll, 1)},
+			with(map[uint]float64{1: 0}), []float64{0, 0}, 0},
+		{"dependency does not affect the subtask it depends on", []models.Subtask{subtask(1, 30, ScoringAll), subtask(2, 70, ScoringAll, 1)},
+			with(map[uint]float64{3: 0}), []float64{30, 0}, 30},
+		{"transitive dependency", []models.Subtask{subtask(1, 20, ScoringAll), subtask(2, 30, ScoringAll, 1), subtask(3, 50, ScoringAll, 2)},
+			with(map[uint]float64{2: 0}), []float64{0, 0, 0}, 0},
+		{"min over own and dependency tests", []models.Subtask{subtask(1, 30, ScoringMin), subtask(2, 70, ScoringMin, 1)},
+			with(map[uint]float64{1: 0.4, 3: 0.6}), []float64{12, 28}, 40},
+		{"avg over own and dependency tests", []models.Subtask{subtask(1, 30, ScoringAll), subtask(2, 80, ScoringAvg, 1)},
+			with(map[uint]float64{1: 0}), []float64{0, 60}, 60},
+		{"dependency cycle terminates", []models.Subtask{subtask(1, 50, ScoringAll, 2), subtask(2, 50, ScoringAll, 1)},
+			with(map[uint]float64{3: 0}), []float64{0, 0}, 0},
+
+		{"scores are rounded", []models.Subtask{subtask(3, 100, ScoringMin)}, with(map[uint]float64{5: 1.0 / 3}), []float64{33.333333}, 33.333333},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			scores, total := scoreSubtasks(tt.subtasks, tests, tt.scores)
+			got := make([]float64, len(scores))
+			for i, score := range scores {
+				got[i] = score.Score
+				if score.Index != tt.subtasks[i].Index || score.Points != tt.subtasks[i].Points {
+					t.Errorf("subtask %d reported as %+v", tt.subtasks[i].Index, score)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) || total != tt.total {
+				t.Errorf("scores = %v, total %v; want %v, total %v", got, total, tt.expected, tt.total)
+			}
+		})
+	}
+}
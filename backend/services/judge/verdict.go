@@ -0,0 +1,84 @@
+package judge
+
+import (
+	"onlineJudge/backend/services/comparator"
+	"onlineJudge/backend/services/compiler"
+)
+
+// Verdicts stored in Submission.Status and SubmissionDetail.Status
+const (
+	VerdictAccepted            = "Accepted"
+	VerdictWrongAnswer         = "Wrong Answer"
+	VerdictPresentationError   = "Presentation Error"
+	VerdictPartiallyCorrect    = "Partially Correct"
+	VerdictCompilationError    = "Compilation Error"
+	VerdictRuntimeError        = "Runtime Error"
+	VerdictTimeLimitExceeded   = "Time Limit Exceeded"
+	VerdictMemoryLimitExceeded = "Memory Limit Exceeded"
+	VerdictOutputLimitExceeded = "Output Limit Exceeded"
+	VerdictSystemError         = "System Error"
+)
+
+// verdictForOutcome maps a sandbox outcome to a verdict.
+// OutcomeOK has no verdict of its own: the output still has to be checked.
+func verdictForOutcome(outcome compiler.Outcome) string {
+	switch outcome {
+	case compiler.OutcomeCompilationError:
+		return VerdictCompilationError
+	case compiler.OutcomeRuntimeError:
+		return VerdictRuntimeError
+	case compiler.OutcomeTimeLimit:
+		return VerdictTimeLimitExceeded
+	case compiler.OutcomeMemoryLimit:
+		return VerdictMemoryLimitExceeded
+	case compiler.OutcomeOutputLimit:
+		return VerdictOutputLimitExceeded
+	default:
+		return VerdictSystemError
+	}
+}
+
+// verdictForCheck maps a checker verdict to a test verdict
+func verdictForCheck(verdict compiler.CheckVerdict) string {
+	switch verdict {
+	case compiler.CheckOK:
+		return VerdictAccepted
+	case compiler.CheckWrongAnswer:
+		return VerdictWrongAnswer
+	case compiler.CheckPresentationError:
+		return VerdictPresentationError
+	case compiler.CheckPartial:
+		return VerdictPartiallyCorrect
+	default:
+		return VerdictSystemError
+	}
+}
+
+// verdictForComparison maps a built-in comparison to a test verdict
+func verdictForComparison(verdict comparator.Verdict) string {
+	switch verdict {
+	case comparator.Accepted:
+		return VerdictAccepted
+	case comparator.PresentationError:
+		return VerdictPresentationError
+	default:
+		return VerdictWrongAnswer
+	}
+}
+
+// verdictForInteraction combines the contestant's outcome with the interactor's verdict.
+// Exceeded limits come first; a wrong answer beats the runtime error the contestant
+// gets when the interactor hangs up on it.
+func verdictForInteraction(outcome compiler.Outcome, verdict compiler.CheckVerdict) string {
+	switch {
+	case outcome == compiler.OutcomeTimeLimit || outcome == compiler.OutcomeMemoryLimit ||
+		outcome == compiler.OutcomeOutputLimit || outcome == compiler.OutcomeSystemError:
+		return verdictForOutcome(outcome)
+	case verdict != compiler.CheckOK:
+		return verdictForCheck(verdict)
+	case outcome != compiler.OutcomeOK:
+		return verdictForOutcome(outcome)
+	default:
+		return VerdictAccepted
+	}
+}
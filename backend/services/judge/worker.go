@@ -0,0 +1,105 @@
+package judge
+
+import (
+	"fmt"
+	"log"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/config"
+	"os"
+	"time"
+)
+
+const (
+	pollInterval      = 2 * time.Second
+	heartbeatInterval = 30 * time.Second
+)
+
+// invokeSlots holds a token for each worker running an invocation, up to config.InvokeWorkers
+var invokeSlots chan struct{}
+
+// StartWorkers launches the judge worker pool and the reclaimer of stuck jobs.
+// Must be called after database.Connect.
+func StartWorkers(count int) {
+	hostname, _ := os.Hostname()
+	invokeSlots = make(chan struct{}, max(config.InvokeWorkers, 0))
+
+	// Jobs left "running" by a previous process are picked up again once they go stale
+	reclaim(config.JudgeStaleAfter)
+	go func() {
+		for range time.Tick(config.JudgeStaleAfter / 2) {
+			reclaim(config.JudgeStaleAfter)
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		workerID := fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i+1)
+		go work(workerID)
+	}
+	log.Printf("Started %d judge workers", count)
+}
+
+func work(workerID string) {
+	for {
+		// A slot is taken before claiming, so the workers of this process cannot exceed the limit together
+		withInvocations := false
+		select {
+		case invokeSlots <- struct{}{}:
+			withInvocations = true
+		default:
+		}
+
+		job, err := claim(workerID, withInvocations)
+		if withInvocations && (err != nil || job.InvocationID == 0) {
+			<-invokeSlots
+		}
+		if err == errNoJob {
+			select {
+			case <-wakeup:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Judge worker %s: failed to claim job: %v", workerID, err)
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		process(job)
+		if job.InvocationID != 0 {
+			<-invokeSlots
+		}
+	}
+}
+
+// process judges a claimed job, or runs its invocation, while keeping its lock alive
+func process(job models.JudgeJob) {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				heartbeat(job)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var err error
+	if job.InvocationID != 0 {
+		err = runInvocation(job.InvocationID)
+	} else {
+		err = runJudge(job.SubmissionID)
+	}
+	close(stop)
+
+	if err != nil && job.InvocationID != 0 {
+		log.Printf("Judge worker %s: invocation #%d failed: %v", job.WorkerID, job.InvocationID, err)
+	} else if err != nil {
+		log.Printf("Judge worker %s: submission #%d failed: %v", job.WorkerID, job.SubmissionID, err)
+	}
+	finish(job, err)
+}
@@ -0,0 +1,550 @@
+// Package problempkg reads and writes problem packages: ZIP archives with everything a problem
+// consists of. Our own format has a versioned problem.json manifest; Polygon packages with a
+// problem.xml descriptor can be imported too.
+package problempkg
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/comparator"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/judge"
+	"onlineJudge/backend/services/storage"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Package formats
+const (
+	FormatNative  = "onlinejudge"
+	FormatPolygon = "polygon"
+)
+
+// Version of the native format written by Export. Packages of later versions are refused,
+// so that nothing they add is silently lost.
+const Version = 1
+
+// manifestName is the manifest of a native package, polygonName the descriptor of a Polygon one
+const (
+	manifestName = "problem.json"
+	polygonName  = "problem.xml"
+)
+
+// maxSourceSize caps a source file read from a package
+const maxSourceSize = 1 << 20
+
+// Manifest is problem.json of a native package. Paths are relative to the manifest.
+type Manifest struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	TimeLimit   float64 `json:"time_limit"`
+	MemoryLimit int     `json:"memory_limit"`
+	OutputLimit int     `json:"output_limit,omitempty"`
+
+	CheckerMode    string  `json:"checker_mode,omitempty"`
+	CheckerEpsilon float64 `json:"checker_epsilon,omitempty"`
+
+	Tags       []string `json:"tags,omitempty"`
+	Difficulty int      `json:"difficulty,omitempty"`
+
+	Author     *File  `json:"author,omitempty"`
+	Checker    *File  `json:"checker,omitempty"`
+	Interactor *File  `json:"interactor,omitempty"`
+	Validator  *File  `json:"validator,omitempty"`
+	Generators []File `json:"generators,omitempty"`
+	Solutions  []File `json:"solutions,omitempty"`
+
+	Subtasks []Subtask      `json:"subtasks,omitempty"`
+	Tests    []ManifestTest `json:"tests"`
+}
+
+// File is a program of the package
+type File struct {
+	Name     string `json:"name,omitempty"` // Generators and solutions only
+	Language string `json:"language"`
+	Path     string `json:"path"`
+	Tag      string `json:"tag,omitempty"` // Solutions only, see judge.Tag*
+}
+
+// Subtask is a subtask in the manifest
+type Subtask struct {
+	Index        int     `json:"index"`
+	Name         string  `json:"name,omitempty"`
+	Points       float64 `json:"points"`
+	Scoring      string  `json:"scoring,omitempty"`
+	Dependencies []int   `json:"dependencies,omitempty"`
+}
+
+// ManifestTest is a test in the manifest. A test without an input is generated by its script;
+// a test without an answer gets one from the author solution.
+type ManifestTest struct {
+	Input    string `json:"input,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Script   string `json:"script,omitempty"`
+	IsSample bool   `json:"is_sample,omitempty"`
+	Subtask  int    `json:"subtask,omitempty"`
+}
+
+// Package is a problem read from a package
+type Package struct {
+	Format   string
+	Problem  models.Problem // Statement, limits, programs, subtasks, generators and solutions; no tests
+	Tests    []Test
+	Warnings []string // What could not be imported
+}
+
+// Test is a test of a package; its data stays in the archive until it is stored
+type Test struct {
+	Name     string
+	Input    *zip.File // Nil for a test generated by Script
+	Output   *zip.File // Nil if the author solution must produce the answer
+	Script   string
+	IsSample bool
+	Subtask  int
+}
+
+// Summary describes what importing a package creates
+type Summary struct {
+	Format           string            `json:"format"`
+	Title            string            `json:"title"`
+	TimeLimit        float64           `json:"time_limit"`
+	MemoryLimit      int               `json:"memory_limit"`
+	CheckerMode      string            `json:"checker_mode"`
+	Checker          bool              `json:"checker"` // A custom checker replaces the checker mode
+	Interactor       bool              `json:"interactor"`
+	Validator        bool              `json:"validator"`
+	AuthorLanguage   string            `json:"author_language"`
+	Tags             []string          `json:"tags"`
+	Difficulty       int               `json:"difficulty"`
+	Tests            int               `json:"tests"`
+	Samples          int               `json:"samples"`
+	GeneratedInputs  int               `json:"generated_inputs"`  // Tests made by generators
+	GeneratedAnswers int               `json:"generated_answers"` // Answers produced by the author solution
+	Subtasks         []Subtask         `json:"subtasks"`
+	Generators       []string          `json:"generators"`
+	Solutions        []SummarySolution `json:"solutions"`
+	Warnings         []string          `json:"warnings"`
+}
+
+// SummarySolution is a reference solution in a summary
+type SummarySolution struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Tag      string `json:"tag"`
+}
+
+// Read detects the format of a package and reads it. problem.json or problem.xml may be
+// at the root of the archive or in a single top-level directory.
+func Read(archive *zip.Reader) (*Package, error) {
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			files[f.Name] = f
+		}
+	}
+
+	for _, root := range []string{"", topDirectory(archive)} {
+		if f := files[root+manifestName]; f != nil {
+			return readNative(files, root, f)
+		}
+		if f := files[root+polygonName]; f != nil {
+			return readPolygon(files, root, f)
+		}
+	}
+	return nil, fmt.Errorf("package has neither %s nor %s", manifestName, polygonName)
+}
+
+// topDirectory is the directory all entries of the archive are in, e.g. "a-plus-b/", or empty
+func topDirectory(archive *zip.Reader) string {
+	top := ""
+	for _, f := range archive.File {
+		dir, _, found := strings.Cut(f.Name, "/")
+		if !found || (top != "" && top != dir+"/") {
+			return ""
+		}
+		top = dir + "/"
+	}
+	return top
+}
+
+// readNative reads a package in our own format
+func readNative(files map[string]*zip.File, root string, manifestFile *zip.File) (*Package, error) {
+	data, err := readFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(data), &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", manifestName, err)
+	}
+	if manifest.Format != FormatNative {
+		return nil, fmt.Errorf("%s is not an %s package", manifestName, FormatNative)
+	}
+	if manifest.Version < 1 || manifest.Version > Version {
+		return nil, fmt.Errorf("package version %d is not supported, the latest is %d", manifest.Version, Version)
+	}
+
+	pkg := &Package{Format: FormatNative}
+	pkg.Problem = models.Problem{
+		Title:          manifest.Title,
+		Description:    manifest.Description,
+		TimeLimit:      manifest.TimeLimit,
+		MemoryLimit:    manifest.MemoryLimit,
+		OutputLimit:    manifest.OutputLimit,
+		CheckerMode:    manifest.CheckerMode,
+		CheckerEpsilon: manifest.CheckerEpsilon,
+		Tags:           manifest.Tags,
+		Difficulty:     manifest.Difficulty,
+	}
+
+	jury := []struct {
+		file             *File
+		role             string
+		source, language *string
+	}{
+		{manifest.Author, "author solution", &pkg.Problem.AuthorSourceCode, &pkg.Problem.AuthorLanguage},
+		{manifest.Checker, "checker", &pkg.Problem.CheckerSourceCode, &pkg.Problem.CheckerLanguage},
+		{manifest.Interactor, "interactor", &pkg.Problem.InteractorSourceCode, &pkg.Problem.InteractorLanguage},
+		{manifest.Validator, "validator", &pkg.Problem.ValidatorSourceCode, &pkg.Problem.ValidatorLanguage},
+	}
+	for _, program := range jury {
+		if program.file == nil {
+			continue
+		}
+		if !compiler.IsSupported(program.file.Language) {
+			return nil, fmt.Errorf("%s: unsupported language %q", program.role, program.file.Language)
+		}
+		if *program.source, err = readPath(files, root, program.file.Path); err != nil {
+			return nil, err
+		}
+		*program.language = program.file.Language
+	}
+
+	for _, gen := range manifest.Generators {
+		if !compiler.IsSupported(gen.Language) {
+			pkg.warn("Generator %s skipped: unsupported language %q", gen.Name, gen.Language)
+			continue
+		}
+		source, err := readPath(files, root, gen.Path)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Problem.Generators = append(pkg.Problem.Generators, models.Generator{Name: gen.Name, SourceCode: source, Language: gen.Language})
+	}
+
+	for _, sol := range manifest.Solutions {
+		if !compiler.IsSupported(sol.Language) {
+			pkg.warn("Solution %s skipped: unsupported language %q", sol.Name, sol.Language)
+			continue
+		}
+		source, err := readPath(files, root, sol.Path)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Problem.Solutions = append(pkg.Problem.Solutions, models.ProblemSolution{Name: sol.Name, SourceCode: source, Language: sol.Language, Tag: sol.Tag})
+	}
+
+	for _, st := range manifest.Subtasks {
+		pkg.Problem.Subtasks = append(pkg.Problem.Subtasks, models.Subtask{
+			Index: st.Index, Name: st.Name, Points: st.Points, Scoring: st.Scoring, Dependencies: st.Dependencies,
+		})
+	}
+
+	for i, test := range manifest.Tests {
+		t := Test{Name: strconv.Itoa(i + 1), Script: test.Script, IsSample: test.IsSample, Subtask: test.Subtask}
+		if test.Input != "" {
+			if t.Input = files[root+test.Input]; t.Input == nil {
+				return nil, fmt.Errorf("test %d: %s is missing", i+1, test.Input)
+			}
+			t.Name = strings.TrimSuffix(test.Input, path.Ext(test.Input))
+		}
+		if test.Output != "" {
+			if t.Output = files[root+test.Output]; t.Output == nil {
+				return nil, fmt.Errorf("test %d: %s is missing", i+1, test.Output)
+			}
+		}
+		pkg.Tests = append(pkg.Tests, t)
+	}
+
+	if err := pkg.check(); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// check validates what both formats have in common
+func (p *Package) check() error {
+	problem := &p.Problem
+	if strings.TrimSpace(problem.Title) == "" {
+		return fmt.Errorf("problem has no title")
+	}
+	if problem.TimeLimit <= 0 || problem.MemoryLimit <= 0 {
+		return fmt.Errorf("problem has no time or memory limit")
+	}
+	if !comparator.Valid(comparator.Mode(problem.CheckerMode)) || problem.CheckerEpsilon < 0 {
+		return fmt.Errorf("invalid checker mode %q", problem.CheckerMode)
+	}
+
+	seen := map[int]bool{}
+	for _, st := range problem.Subtasks {
+		if st.Index < 1 || seen[st.Index] {
+			return fmt.Errorf("subtask index %d is invalid or duplicated", st.Index)
+		}
+		if st.Points < 0 {
+			return fmt.Errorf("subtask %d has negative points", st.Index)
+		}
+		if st.Scoring != "" && st.Scoring != judge.ScoringAll && st.Scoring != judge.ScoringMin && st.Scoring != judge.ScoringAvg {
+			return fmt.Errorf("subtask %d has unknown scoring %q", st.Index, st.Scoring)
+		}
+		for _, dep := range st.Dependencies {
+			if dep >= st.Index || !seen[dep] {
+				return fmt.Errorf("subtask %d cannot depend on subtask %d", st.Index, dep)
+			}
+		}
+		seen[st.Index] = true
+	}
+
+	generators := map[string]bool{}
+	for _, gen := range problem.Generators {
+		if gen.Name == "" || generators[gen.Name] {
+			return fmt.Errorf("generator name %q is invalid or duplicated", gen.Name)
+		}
+		generators[gen.Name] = true
+	}
+
+	for _, sol := range problem.Solutions {
+		if !judge.ValidTag(sol.Tag) {
+			return fmt.Errorf("solution %s has unknown tag %q", sol.Name, sol.Tag)
+		}
+	}
+
+	if len(p.Tests) == 0 {
+		return fmt.Errorf("package has no tests")
+	}
+	for _, test := range p.Tests {
+		if test.Subtask != 0 && !seen[test.Subtask] {
+			return fmt.Errorf("test %s: subtask %d does not exist", test.Name, test.Subtask)
+		}
+		if test.Input == nil {
+			fields := strings.Fields(test.Script)
+			if len(fields) == 0 {
+				return fmt.Errorf("test %s has neither an input nor a generator command", test.Name)
+			}
+			if !generators[fields[0]] {
+				return fmt.Errorf("test %s: unknown generator %s", test.Name, fields[0])
+			}
+		}
+	}
+	return nil
+}
+
+// Summary tells what importing the package creates
+func (p *Package) Summary() Summary {
+	problem := p.Problem
+	summary := Summary{
+		Format:         p.Format,
+		Title:          problem.Title,
+		TimeLimit:      problem.TimeLimit,
+		MemoryLimit:    problem.MemoryLimit,
+		CheckerMode:    problem.CheckerMode,
+		Checker:        problem.CheckerSourceCode != "",
+		Interactor:     problem.InteractorSourceCode != "",
+		Validator:      problem.ValidatorSourceCode != "",
+		AuthorLanguage: problem.AuthorLanguage,
+		Tags:           problem.Tags,
+		Difficulty:     problem.Difficulty,
+		Tests:          len(p.Tests),
+		Subtasks:       []Subtask{},
+		Generators:     []string{},
+		Solutions:      []SummarySolution{},
+		Warnings:       p.Warnings,
+	}
+	if summary.CheckerMode == "" {
+		summary.CheckerMode = string(comparator.DefaultMode)
+	}
+	if summary.Warnings == nil {
+		summary.Warnings = []string{}
+	}
+
+	for _, test := range p.Tests {
+		if test.IsSample {
+			summary.Samples++
+		}
+		if test.Input == nil {
+			summary.GeneratedInputs++
+		}
+		if test.Output == nil && !summary.Interactor {
+			summary.GeneratedAnswers++
+		}
+	}
+	for _, st := range problem.Subtasks {
+		summary.Subtasks = append(summary.Subtasks, Subtask{
+			Index: st.Index, Name: st.Name, Points: st.Points, Scoring: st.Scoring, Dependencies: st.Dependencies,
+		})
+	}
+	for _, gen := range problem.Generators {
+		summary.Generators = append(summary.Generators, gen.Name)
+	}
+	for _, sol := range problem.Solutions {
+		summary.Solutions = append(summary.Solutions, SummarySolution{Name: sol.Name, Language: sol.Language, Tag: sol.Tag})
+	}
+	return summary
+}
+
+// Export writes a problem as a native package. The problem must have its test cases, subtasks,
+// generators and solutions loaded; test data is read from the test storage.
+func Export(w io.Writer, problem models.Problem) error {
+	archive := zip.NewWriter(w)
+	manifest := Manifest{
+		Format:         FormatNative,
+		Version:        Version,
+		Title:          problem.Title,
+		Description:    problem.Description,
+		TimeLimit:      problem.TimeLimit,
+		MemoryLimit:    problem.MemoryLimit,
+		OutputLimit:    problem.OutputLimit,
+		CheckerMode:    problem.CheckerMode,
+		CheckerEpsilon: problem.CheckerEpsilon,
+		Tags:           problem.Tags,
+		Difficulty:     problem.Difficulty,
+		Tests:          []ManifestTest{},
+	}
+
+	program := func(name, source, language string) (*File, error) {
+		if source == "" {
+			return nil, nil
+		}
+		file := &File{Language: language, Path: name + compiler.SourceExtension(language)}
+		return file, writeString(archive, file.Path, source)
+	}
+
+	var err error
+	if manifest.Author, err = program("solutions/main", problem.AuthorSourceCode, problem.AuthorLanguage); err != nil {
+		return err
+	}
+	if manifest.Checker, err = program("files/checker", problem.CheckerSourceCode, problem.CheckerLanguage); err != nil {
+		return err
+	}
+	if manifest.Interactor, err = program("files/interactor", problem.InteractorSourceCode, problem.InteractorLanguage); err != nil {
+		return err
+	}
+	if manifest.Validator, err = program("files/validator", problem.ValidatorSourceCode, problem.ValidatorLanguage); err != nil {
+		return err
+	}
+
+	for _, gen := range problem.Generators {
+		file, err := program("generators/"+gen.Name, gen.SourceCode, gen.Language)
+		if err != nil {
+			return err
+		}
+		file.Name = gen.Name
+		manifest.Generators = append(manifest.Generators, *file)
+	}
+
+	// Solution names are free text, so files are numbered
+	for i, sol := range problem.Solutions {
+		file, err := program(fmt.Sprintf("solutions/%02d", i+1), sol.SourceCode, sol.Language)
+		if err != nil {
+			return err
+		}
+		file.Name, file.Tag = sol.Name, sol.Tag
+		manifest.Solutions = append(manifest.Solutions, *file)
+	}
+
+	for _, st := range problem.Subtasks {
+		manifest.Subtasks = append(manifest.Subtasks, Subtask{
+			Index: st.Index, Name: st.Name, Points: st.Points, Scoring: st.Scoring, Dependencies: st.Dependencies,
+		})
+	}
+
+	width := max(2, len(strconv.Itoa(len(problem.TestCases))))
+	for i, tc := range problem.TestCases {
+		test := ManifestTest{
+			Input:    fmt.Sprintf("tests/%0*d.in", width, i+1),
+			Script:   tc.Script,
+			IsSample: tc.IsSample,
+			Subtask:  tc.Subtask,
+		}
+		if err := writeObject(archive, test.Input, tc.InputHash); err != nil {
+			return err
+		}
+		if tc.OutputHash != "" {
+			test.Output = fmt.Sprintf("tests/%0*d.out", width, i+1)
+			if err := writeObject(archive, test.Output, tc.OutputHash); err != nil {
+				return err
+			}
+		}
+		manifest.Tests = append(manifest.Tests, test)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeString(archive, manifestName, string(data)+"\n"); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// warn records something that could not be imported
+func (p *Package) warn(format string, args ...any) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+// readPath reads a source file of the package
+func readPath(files map[string]*zip.File, root, name string) (string, error) {
+	f := files[root+name]
+	if f == nil {
+		return "", fmt.Errorf("%s is missing", name)
+	}
+	return readFile(f)
+}
+
+// readFile reads a small file of the package, such as a source or the manifest
+func readFile(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > maxSourceSize {
+		return "", fmt.Errorf("%s is larger than %d KB", f.Name, maxSourceSize/1024)
+	}
+	reader, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", f.Name, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", f.Name, err)
+	}
+	return string(data), nil
+}
+
+func writeString(archive *zip.Writer, name, content string) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, content)
+	return err
+}
+
+// writeObject copies test data from the storage into the archive
+func writeObject(archive *zip.Writer, name, hash string) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	data, err := storage.Open(hash)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+	_, err = io.Copy(w, data)
+	return err
+}
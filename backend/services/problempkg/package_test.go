@@ -0,0 +1,370 @@
+package problempkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/config"
+	"onlineJudge/backend/services/comparator"
+	"onlineJudge/backend/services/judge"
+	"onlineJudge/backend/services/storage"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipOf builds an archive in memory from file names and contents
+func zipOf(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		if err := writeString(w, name, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return readerOf(t, buf.Bytes())
+}
+
+func readerOf(t *testing.T, data []byte) *zip.Reader {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// contentOf reads a file of a package, "" for none
+func contentOf(t *testing.T, f *zip.File) string {
+	t.Helper()
+	if f == nil {
+		return ""
+	}
+	reader, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+const polygonXML = `<?xml version="1.0" encoding="utf-8" standalone="no"?>
+<problem revision="12" short-name="a-plus-b">
+  <names>
+    <name language="english" value="A plus B"/>
+    <name language="russian" value="A плюс B"/>
+  </names>
+  <statements>
+    <statement charset="UTF-8" language="russian" path="statements/russian/problem.tex" type="application/x-tex"/>
+  </statements>
+  <judging cpu-name="Intel" cpu-speed="3600" input-file="" output-file="">
+    <testset name="pretests">
+      <time-limit>1000</time-limit>
+      <memory-limit>268435456</memory-limit>
+      <test-count>0</test-count>
+      <input-path-pattern>pretests/%02d</input-path-pattern>
+      <tests/>
+    </testset>
+    <testset name="tests">
+      <time-limit>2000</time-limit>
+      <memory-limit>268435456</memory-limit>
+      <test-count>4</test-count>
+      <input-path-pattern>tests/%02d</input-path-pattern>
+      <answer-path-pattern>tests/%02d.a</answer-path-pattern>
+      <tests>
+        <test method="manual" sample="true" group="samples"/>
+        <test cmd="gen  10   5" method="generated" group="small" points="15"/>
+        <test cmd="gen 1000 7" method="generated" group="small" points="15"/>
+        <test method="manual" group="large"/>
+      </tests>
+      <groups>
+        <group feedback-policy="complete" name="samples" points="0" points-policy="complete-group"/>
+        <group feedback-policy="icpc" name="small" points-policy="each-test">
+          <dependencies><dependency group="samples"/></dependencies>
+        </group>
+        <group feedback-policy="icpc" name="large" points="70" points-policy="complete-group">
+          <dependencies><dependency group="samples"/><dependency group="small"/></dependencies>
+        </group>
+      </groups>
+    </testset>
+  </judging>
+  <files>
+    <executables>
+      <executable><source path="files/gen.cpp" type="cpp.g++17"/></executable>
+      <executable><source path="files/val.cpp" type="cpp.g++17"/></executable>
+    </executables>
+  </files>
+  <assets>
+    <checker name="std::rcmp6.cpp" type="testlib"><source path="files/check.cpp" type="cpp.g++17"/></checker>
+    <validators><validator><source path="files/val.cpp" type="cpp.g++17"/></validator></validators>
+    <solutions>
+      <solution tag="main"><source path="solutions/main.cpp" type="cpp.g++17"/></solution>
+      <solution tag="wrong-answer"><source path="solutions/wa.py" type="python.3"/></solution>
+      <solution tag="time-limit-exceeded-or-accepted"><source path="solutions/slow.java" type="java21"/></solution>
+      <solution tag="accepted"><source path="solutions/ok.pas" type="pas.fpc"/></solution>
+    </solutions>
+  </assets>
+  <tags>
+    <tag value="math"/>
+    <tag value="implementation"/>
+  </tags>
+</problem>
+`
+
+func polygonFiles() map[string]string {
+	return map[string]string{
+		"problem.xml":                           polygonXML,
+		"statement-sections/russian/legend.tex": "Сложите два числа.\n",
+		"statement-sections/russian/input.tex":  "Два числа $a$ и $b$.\n",
+		"statement-sections/russian/output.tex": "Их сумма.\n",
+		"statement-sections/english/legend.tex": "Add two numbers.\n",
+		"statements/russian/problem.tex":        "\\begin{problem}...",
+		"files/gen.cpp":                         "// generator",
+		"files/val.cpp":                         "// validator",
+		"files/check.cpp":                       "// rcmp6",
+		"solutions/main.cpp":                    "// main",
+		"solutions/wa.py":                       "print(0)",
+		"solutions/slow.java":                   "class Main {}",
+		"solutions/ok.pas":                      "begin end.",
+		"tests/01":                              "1 2\n",
+		"tests/01.a":                            "3\n",
+		"tests/03":                              "500 500\n", // A generated test the package also contains
+		"tests/04":                              "1000000 1000000\n",
+		"tests/04.a":                            "2000000\n",
+	}
+}
+
+func TestReadPolygon(t *testing.T) {
+	files := polygonFiles()
+	// The package may come in a directory of its own
+	packaged := map[string]string{}
+	for name, content := range files {
+		packaged["a-plus-b/"+name] = content
+	}
+
+	for name, archive := range map[string]map[string]string{"root": files, "directory": packaged} {
+		t.Run(name, func(t *testing.T) {
+			pkg, err := Read(zipOf(t, archive))
+			if err != nil {
+				t.Fatal(err)
+			}
+			problem := pkg.Problem
+
+			if pkg.Format != FormatPolygon || problem.Title != "A плюс B" {
+				t.Errorf("format %q, title %q", pkg.Format, problem.Title)
+			}
+			if want := "Сложите два числа.\n\nВходные данные\nДва числа $a$ и $b$.\n\nВыходные данные\nИх сумма."; problem.Description != want {
+				t.Errorf("description = %q, want %q", problem.Description, want)
+			}
+			if !reflect.DeepEqual(problem.Tags, []string{"math", "implementation"}) {
+				t.Errorf("tags = %v", problem.Tags)
+			}
+			if problem.TimeLimit != 2 || problem.MemoryLimit != 256 {
+				t.Errorf("limits = %vs, %d MB; want the tests testset's", problem.TimeLimit, problem.MemoryLimit)
+			}
+
+			// A standard checker becomes a comparison mode and needs no source
+			if problem.CheckerMode != string(comparator.ModeFloat) || problem.CheckerEpsilon != 1e-6 || problem.CheckerSourceCode != "" {
+				t.Errorf("checker = %q, %v, source %q", problem.CheckerMode, problem.CheckerEpsilon, problem.CheckerSourceCode)
+			}
+			if problem.ValidatorSourceCode != "// validator" || problem.ValidatorLanguage != "cpp" {
+				t.Errorf("validator = %q in %q", problem.ValidatorSourceCode, problem.ValidatorLanguage)
+			}
+			if problem.AuthorSourceCode != "// main" || problem.AuthorLanguage != "cpp" {
+				t.Errorf("author solution = %q in %q", problem.AuthorSourceCode, problem.AuthorLanguage)
+			}
+
+			// The validator and checker are executables too, but not generators
+			if len(problem.Generators) != 1 || problem.Generators[0] != (models.Generator{Name: "gen", SourceCode: "// generator", Language: "cpp"}) {
+				t.Errorf("generators = %+v", problem.Generators)
+			}
+			solutions := []models.ProblemSolution{
+				{Name: "wa", SourceCode: "print(0)", Language: "python", Tag: judge.TagWrongAnswer},
+				{Name: "slow", SourceCode: "class Main {}", Language: "java", Tag: judge.TagTimeOrAccepted},
+			}
+			if !reflect.DeepEqual(problem.Solutions, solutions) {
+				t.Errorf("solutions = %+v, want %+v", problem.Solutions, solutions)
+			}
+
+			subtasks := []models.Subtask{
+				{Index: 1, Name: "samples", Points: 0, Scoring: judge.ScoringAll},
+				{Index: 2, Name: "small", Points: 30, Scoring: judge.ScoringAvg, Dependencies: []int{1}},
+				{Index: 3, Name: "large", Points: 70, Scoring: judge.ScoringAll, Dependencies: []int{1, 2}},
+			}
+			if !reflect.DeepEqual(problem.Subtasks, subtasks) {
+				t.Errorf("subtasks = %+v, want %+v", problem.Subtasks, subtasks)
+			}
+
+			type test struct {
+				name, input, output, script string
+				sample                      bool
+				subtask                     int
+				generated                   bool
+			}
+			expected := []test{
+				{"tests/01", "1 2\n", "3\n", "", true, 1, false},
+				{"tests/02", "", "", "gen 10 5", false, 2, true},
+				{"tests/03", "500 500\n", "", "gen 1000 7", false, 2, false},
+				{"tests/04", "1000000 1000000\n", "2000000\n", "", false, 3, false},
+			}
+			var got []test
+			for _, tt := range pkg.Tests {
+				got = append(got, test{tt.Name, contentOf(t, tt.Input), contentOf(t, tt.Output), tt.Script, tt.IsSample, tt.Subtask, tt.Input == nil})
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("tests = %+v\nwant %+v", got, expected)
+			}
+
+			warnings := strings.Join(pkg.Warnings, "\n")
+			for _, warning := range []string{`Only testset "tests" is imported`, `Solution ok skipped: unsupported language "pas.fpc"`} {
+				if !strings.Contains(warnings, warning) {
+					t.Errorf("warnings %q lack %q", pkg.Warnings, warning)
+				}
+			}
+
+			summary := pkg.Summary()
+			if summary.Tests != 4 || summary.Samples != 1 || summary.GeneratedInputs != 1 || summary.GeneratedAnswers != 2 {
+				t.Errorf("summary counts = %+v", summary)
+			}
+		})
+	}
+}
+
+func TestReadPolygonErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(files map[string]string)
+		message string
+	}{
+		{"manual test missing", func(files map[string]string) { delete(files, "tests/04") }, "test 4: tests/04 is missing"},
+		{"undeclared group", func(files map[string]string) {
+			files["problem.xml"] = strings.Replace(files["problem.xml"], `group="large"/>`, `group="huge"/>`, 1)
+		}, `test 4: group "huge" is not declared`},
+		{"custom checker in an unsupported language", func(files map[string]string) {
+			files["problem.xml"] = strings.Replace(files["problem.xml"],
+				`name="std::rcmp6.cpp" type="testlib"><source path="files/check.cpp" type="cpp.g++17"/>`,
+				`name="check.pas" type="testlib"><source path="files/check.pas" type="pas.fpc"/>`, 1)
+		}, "checker: unsupported language"},
+		{"no descriptor", func(files map[string]string) { delete(files, "problem.xml") }, "neither problem.json nor problem.xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := polygonFiles()
+			tt.change(files)
+			_, err := Read(zipOf(t, files))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error = %v, want one containing %q", err, tt.message)
+			}
+		})
+	}
+}
+
+// storageForTest points the test storage at a temporary directory
+func storageForTest(t *testing.T) {
+	t.Helper()
+	previousBackend, previousDir := config.TestStorage, config.TestStorageDir
+	t.Cleanup(func() { config.TestStorage, config.TestStorageDir = previousBackend, previousDir })
+	config.TestStorage, config.TestStorageDir = storage.BackendLocal, t.TempDir()
+	if err := storage.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	storageForTest(t)
+	put := func(content string) storage.Object {
+		object, err := storage.PutString(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return object
+	}
+
+	problem := models.Problem{
+		Title:             "A + B",
+		Description:       "Add two numbers",
+		TimeLimit:         1.5,
+		MemoryLimit:       128,
+		OutputLimit:       16,
+		CheckerMode:       string(comparator.ModeTokens),
+		Tags:              []string{"math"},
+		Difficulty:        800,
+		AuthorSourceCode:  "print(sum(map(int, input().split())))",
+		AuthorLanguage:    "python",
+		CheckerSourceCode: "// checker",
+		CheckerLanguage:   "cpp",
+		Subtasks: []models.Subtask{
+			{Index: 1, Name: "small", Points: 40, Scoring: judge.ScoringMin},
+			{Index: 2, Name: "large", Points: 60, Scoring: judge.ScoringAll, Dependencies: []int{1}},
+		},
+		Generators: []models.Generator{{Name: "gen", SourceCode: "// gen", Language: "cpp"}},
+		Solutions: []models.ProblemSolution{
+			{Name: "brute force", SourceCode: "// slow", Language: "cpp", Tag: judge.TagTimeLimit},
+			{Name: "wrong", SourceCode: "print(0)", Language: "python", Tag: judge.TagWrongAnswer},
+		},
+	}
+	inputs := []string{"1 2\n", "5 7\n", "100 200\n"}
+	outputs := []string{"3\n", "12\n", ""}
+	for i := range inputs {
+		tc := models.TestCase{IsSample: i == 0, Subtask: 1 + i/2}
+		in := put(inputs[i])
+		tc.InputHash, tc.InputSize = in.Hash, in.Size
+		if outputs[i] != "" {
+			out := put(outputs[i])
+			tc.OutputHash, tc.OutputSize = out.Hash, out.Size
+		}
+		if i == 2 {
+			tc.Script = "gen 100 200"
+		}
+		problem.TestCases = append(problem.TestCases, tc)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, problem); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := Read(readerOf(t, buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Format != FormatNative || len(pkg.Warnings) != 0 {
+		t.Errorf("format %q, warnings %q", pkg.Format, pkg.Warnings)
+	}
+
+	// Everything but the tests, which the package keeps apart, comes back as it was
+	expected := problem
+	expected.TestCases = nil
+	if !reflect.DeepEqual(pkg.Problem, expected) {
+		t.Errorf("imported problem = %+v\nwant %+v", pkg.Problem, expected)
+	}
+
+	if len(pkg.Tests) != len(inputs) {
+		t.Fatalf("%d tests imported, want %d", len(pkg.Tests), len(inputs))
+	}
+	for i, test := range pkg.Tests {
+		tc := problem.TestCases[i]
+		if contentOf(t, test.Input) != inputs[i] || contentOf(t, test.Output) != outputs[i] {
+			t.Errorf("test %d data = %q, %q; want %q, %q", i+1, contentOf(t, test.Input), contentOf(t, test.Output), inputs[i], outputs[i])
+		}
+		if test.IsSample != tc.IsSample || test.Subtask != tc.Subtask || test.Script != tc.Script {
+			t.Errorf("test %d = %+v, want sample %v, subtask %d, script %q", i+1, test, tc.IsSample, tc.Subtask, tc.Script)
+		}
+	}
+}
+
+func TestReadNativeVersion(t *testing.T) {
+	manifest := `{"format": "onlinejudge", "version": 2, "title": "A", "time_limit": 1, "memory_limit": 64, "tests": []}`
+	_, err := Read(zipOf(t, map[string]string{"problem.json": manifest}))
+	if err == nil || !strings.Contains(err.Error(), "version 2 is not supported") {
+		t.Errorf("error = %v, want the version refused", err)
+	}
+}
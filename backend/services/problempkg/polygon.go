@@ -0,0 +1,364 @@
+package problempkg
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/services/comparator"
+	"onlineJudge/backend/services/compiler"
+	"onlineJudge/backend/services/judge"
+	"path"
+	"strings"
+)
+
+// polygonProblem is the part of problem.xml that is imported
+type polygonProblem struct {
+	Names []struct {
+		Language string `xml:"language,attr"`
+		Value    string `xml:"value,attr"`
+	} `xml:"names>name"`
+	Tags []struct {
+		Value string `xml:"value,attr"`
+	} `xml:"tags>tag"`
+	Statements []struct {
+		Language string `xml:"language,attr"`
+		Path     string `xml:"path,attr"`
+		Type     string `xml:"type,attr"`
+	} `xml:"statements>statement"`
+	Testsets    []polygonTestset `xml:"judging>testset"`
+	Executables []struct {
+		Source polygonSource `xml:"source"`
+	} `xml:"files>executables>executable"`
+	Checker *struct {
+		Name   string        `xml:"name,attr"`
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>checker"`
+	Interactor *struct {
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>interactor"`
+	Validators []struct {
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>validators>validator"`
+	Solutions []struct {
+		Tag    string        `xml:"tag,attr"`
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>solutions>solution"`
+}
+
+type polygonSource struct {
+	Path string `xml:"path,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type polygonTestset struct {
+	Name          string `xml:"name,attr"`
+	TimeLimit     int    `xml:"time-limit"`   // ms
+	MemoryLimit   int64  `xml:"memory-limit"` // bytes
+	InputPattern  string `xml:"input-path-pattern"`
+	AnswerPattern string `xml:"answer-path-pattern"`
+	Tests         []struct {
+		Method string  `xml:"method,attr"`
+		Cmd    string  `xml:"cmd,attr"`
+		Sample bool    `xml:"sample,attr"`
+		Group  string  `xml:"group,attr"`
+		Points float64 `xml:"points,attr"`
+	} `xml:"tests>test"`
+	Groups []struct {
+		Name         string   `xml:"name,attr"`
+		Points       *float64 `xml:"points,attr"`
+		PointsPolicy string   `xml:"points-policy,attr"`
+		Dependencies []struct {
+			Group string `xml:"group,attr"`
+		} `xml:"dependencies>dependency"`
+	} `xml:"groups>group"`
+}
+
+// polygonCheckers maps the standard testlib checkers to the built-in comparison modes
+var polygonCheckers = map[string]struct {
+	mode    comparator.Mode
+	epsilon float64
+}{
+	"wcmp":   {comparator.ModeTokens, 0},
+	"ncmp":   {comparator.ModeTokens, 0},
+	"hcmp":   {comparator.ModeTokens, 0},
+	"lcmp":   {comparator.ModeTokens, 0}, // Line breaks are not compared, which is more lenient
+	"fcmp":   {comparator.ModeExact, 0},
+	"yesno":  {comparator.ModeCaseInsensitive, 0},
+	"nyesno": {comparator.ModeCaseInsensitive, 0},
+	"rcmp4":  {comparator.ModeFloat, 1e-4},
+	"rcmp6":  {comparator.ModeFloat, 1e-6},
+	"rcmp9":  {comparator.ModeFloat, 1e-9},
+	"rcmp":   {comparator.ModeFloat, 1.5e-6},
+}
+
+// polygonTags maps Polygon solution tags to ours; "main" is the author solution
+var polygonTags = map[string]string{
+	"accepted":                        judge.TagAccepted,
+	"wrong-answer":                    judge.TagWrongAnswer,
+	"presentation-error":              judge.TagWrongAnswer,
+	"time-limit-exceeded":             judge.TagTimeLimit,
+	"memory-limit-exceeded":           judge.TagMemoryLimit,
+	"time-limit-exceeded-or-accepted": judge.TagTimeOrAccepted,
+	"rejected":                        judge.TagRejected,
+	"failed":                          judge.TagRejected,
+	"time-limit-exceeded-or-memory-limit-exceeded": judge.TagRejected,
+}
+
+// polygonSections are the statement sections joined into the description, with their headings
+var polygonSections = []struct {
+	file             string
+	russian, english string
+}{
+	{"legend.tex", "", ""},
+	{"input.tex", "Входные данные", "Input"},
+	{"output.tex", "Выходные данные", "Output"},
+	{"interaction.tex", "Протокол взаимодействия", "Interaction"},
+	{"notes.tex", "Примечание", "Note"},
+}
+
+// readPolygon reads a Polygon package. Tests come from the "tests" testset; inputs missing from
+// the package are generated by their command, and missing answers come from the main solution.
+func readPolygon(files map[string]*zip.File, root string, descriptor *zip.File) (*Package, error) {
+	data, err := readFile(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	var xp polygonProblem
+	if err := xml.Unmarshal([]byte(data), &xp); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", polygonName, err)
+	}
+
+	pkg := &Package{Format: FormatPolygon}
+	problem := &pkg.Problem
+
+	language := ""
+	for _, preferred := range []string{"russian", "english"} {
+		for _, name := range xp.Names {
+			if language == "" && name.Language == preferred {
+				language = preferred
+			}
+		}
+	}
+	if language == "" && len(xp.Names) > 0 {
+		language = xp.Names[0].Language
+	}
+	for _, name := range xp.Names {
+		if name.Language == language {
+			problem.Title = name.Value
+		}
+	}
+	for _, tag := range xp.Tags {
+		problem.Tags = append(problem.Tags, tag.Value)
+	}
+	problem.Description = polygonStatement(files, root, xp, language)
+	if problem.Description == "" {
+		pkg.warn("Statement not found")
+	}
+
+	if len(xp.Testsets) == 0 {
+		return nil, fmt.Errorf("%s has no testset", polygonName)
+	}
+	testset := xp.Testsets[0]
+	for _, ts := range xp.Testsets {
+		if ts.Name == "tests" {
+			testset = ts
+		}
+	}
+	if len(xp.Testsets) > 1 {
+		pkg.warn("Only testset %q is imported", testset.Name)
+	}
+	if testset.InputPattern == "" {
+		testset.InputPattern = "tests/%02d"
+	}
+	problem.TimeLimit = float64(testset.TimeLimit) / 1000
+	problem.MemoryLimit = int(testset.MemoryLimit / (1024 * 1024))
+
+	// Sources of the jury programs, so that they are not taken for generators
+	jury := map[string]bool{}
+
+	if checker := xp.Checker; checker != nil {
+		jury[checker.Source.Path] = true
+		name := strings.TrimSuffix(strings.TrimPrefix(checker.Name, "std::"), ".cpp")
+		if std, ok := polygonCheckers[name]; ok && strings.HasPrefix(checker.Name, "std::") {
+			problem.CheckerMode, problem.CheckerEpsilon = string(std.mode), std.epsilon
+		} else if problem.CheckerSourceCode, problem.CheckerLanguage, err = polygonProgram(files, root, checker.Source); err != nil {
+			return nil, fmt.Errorf("checker: %v", err)
+		}
+	}
+	if interactor := xp.Interactor; interactor != nil {
+		jury[interactor.Source.Path] = true
+		if problem.InteractorSourceCode, problem.InteractorLanguage, err = polygonProgram(files, root, interactor.Source); err != nil {
+			return nil, fmt.Errorf("interactor: %v", err)
+		}
+	}
+	for i, validator := range xp.Validators {
+		jury[validator.Source.Path] = true
+		if i > 0 {
+			pkg.warn("Validator %s skipped: only one validator is supported", validator.Source.Path)
+			continue
+		}
+		if problem.ValidatorSourceCode, problem.ValidatorLanguage, err = polygonProgram(files, root, validator.Source); err != nil {
+			pkg.warn("Validator skipped: %v", err)
+		}
+	}
+
+	for _, sol := range xp.Solutions {
+		name := strings.TrimSuffix(path.Base(sol.Source.Path), path.Ext(sol.Source.Path))
+		source, lang, err := polygonProgram(files, root, sol.Source)
+		if err != nil {
+			pkg.warn("Solution %s skipped: %v", name, err)
+			continue
+		}
+		if sol.Tag == "main" {
+			problem.AuthorSourceCode, problem.AuthorLanguage = source, lang
+			continue
+		}
+		tag, ok := polygonTags[sol.Tag]
+		if !ok {
+			pkg.warn("Solution %s: unknown tag %q, imported as %s", name, sol.Tag, judge.TagRejected)
+			tag = judge.TagRejected
+		}
+		problem.Solutions = append(problem.Solutions, models.ProblemSolution{Name: name, SourceCode: source, Language: lang, Tag: tag})
+	}
+
+	for _, exe := range xp.Executables {
+		if jury[exe.Source.Path] {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(exe.Source.Path), path.Ext(exe.Source.Path))
+		source, lang, err := polygonProgram(files, root, exe.Source)
+		if err != nil {
+			pkg.warn("Generator %s skipped: %v", name, err)
+			continue
+		}
+		problem.Generators = append(problem.Generators, models.Generator{Name: name, SourceCode: source, Language: lang})
+	}
+
+	// Groups become subtasks, numbered in the order they are declared
+	groups := map[string]int{}
+	for i, group := range testset.Groups {
+		groups[group.Name] = i + 1
+	}
+	for i, group := range testset.Groups {
+		st := models.Subtask{Index: i + 1, Name: group.Name, Scoring: judge.ScoringAll}
+		if group.PointsPolicy == "each-test" {
+			st.Scoring = judge.ScoringAvg
+		}
+		if group.Points != nil {
+			st.Points = *group.Points
+		} else {
+			for _, test := range testset.Tests {
+				if test.Group == group.Name {
+					st.Points += test.Points
+				}
+			}
+		}
+		for _, dep := range group.Dependencies {
+			if index, ok := groups[dep.Group]; ok {
+				st.Dependencies = append(st.Dependencies, index)
+			}
+		}
+		problem.Subtasks = append(problem.Subtasks, st)
+	}
+
+	interactive := problem.InteractorSourceCode != ""
+	for i, test := range testset.Tests {
+		t := Test{Name: fmt.Sprintf(testset.InputPattern, i+1), IsSample: test.Sample}
+		if test.Group != "" {
+			if t.Subtask = groups[test.Group]; t.Subtask == 0 {
+				return nil, fmt.Errorf("test %d: group %q is not declared", i+1, test.Group)
+			}
+		}
+		t.Input = files[root+t.Name]
+		if t.Input == nil {
+			if test.Method != "generated" {
+				return nil, fmt.Errorf("test %d: %s is missing", i+1, t.Name)
+			}
+			t.Script = strings.Join(strings.Fields(test.Cmd), " ")
+		} else if test.Method == "generated" {
+			t.Script = strings.Join(strings.Fields(test.Cmd), " ")
+		}
+		if !interactive && testset.AnswerPattern != "" {
+			t.Output = files[root+fmt.Sprintf(testset.AnswerPattern, i+1)]
+		}
+		pkg.Tests = append(pkg.Tests, t)
+	}
+
+	if err := pkg.check(); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// polygonStatement builds the description from the statement sections in the language,
+// falling back to the whole LaTeX statement
+func polygonStatement(files map[string]*zip.File, root string, xp polygonProblem, language string) string {
+	var parts []string
+	for _, section := range polygonSections {
+		text, err := readPath(files, root, "statement-sections/"+language+"/"+section.file)
+		if err != nil || strings.TrimSpace(text) == "" {
+			continue
+		}
+		heading := section.english
+		if language == "russian" {
+			heading = section.russian
+		}
+		if heading != "" {
+			text = heading + "\n" + strings.TrimSpace(text)
+		}
+		parts = append(parts, strings.TrimSpace(text))
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "\n\n")
+	}
+
+	for _, statement := range xp.Statements {
+		if statement.Language == language && statement.Type == "application/x-tex" {
+			if text, err := readPath(files, root, statement.Path); err == nil {
+				return strings.TrimSpace(text)
+			}
+		}
+	}
+	return ""
+}
+
+// polygonProgram reads a source of the package, mapping its Polygon type such as
+// "cpp.g++17" or "python.3" to one of our languages
+func polygonProgram(files map[string]*zip.File, root string, source polygonSource) (string, string, error) {
+	language := polygonLanguage(source.Type)
+	if !compiler.IsSupported(language) {
+		return "", "", fmt.Errorf("unsupported language %q", source.Type)
+	}
+	text, err := readPath(files, root, source.Path)
+	if err != nil {
+		return "", "", err
+	}
+	return text, language, nil
+}
+
+func polygonLanguage(sourceType string) string {
+	switch {
+	case strings.HasPrefix(sourceType, "cpp."):
+		return "cpp"
+	case strings.HasPrefix(sourceType, "c."):
+		return "c"
+	case strings.HasPrefix(sourceType, "java"):
+		return "java"
+	case strings.HasPrefix(sourceType, "kotlin"):
+		return "kotlin"
+	case strings.HasPrefix(sourceType, "csharp"):
+		return "csharp"
+	case sourceType == "python.pypy3":
+		return "pypy"
+	case sourceType == "python.3":
+		return "python"
+	case strings.HasPrefix(sourceType, "go"):
+		return "go"
+	case strings.HasPrefix(sourceType, "rust"):
+		return "rust"
+	case strings.HasPrefix(sourceType, "js"):
+		return "javascript"
+	}
+	return ""
+}
@@ -0,0 +1,369 @@
+// Package revision records the history of a problem as immutable revisions, compares
+// them and rolls a problem back to one of them.
+package revision
+
+import (
+	"encoding/json"
+	"fmt"
+	"onlineJudge/backend/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Record takes a revision of the problem as it is now, unless nothing changed since the
+// latest one, and returns the latest revision. Concurrent records of a problem are
+// serialized by locking its row, so tx should be a transaction.
+func Record(tx *gorm.DB, problemID, userID uint, change string) (models.ProblemRevision, error) {
+	var problem models.Problem
+	byID := func(db *gorm.DB) *gorm.DB { return db.Order("id") }
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Subtasks", byID).Preload("TestCases", byID).
+		Preload("Generators", byID).Preload("Solutions", byID).
+		First(&problem, problemID).Error
+	if err != nil {
+		return models.ProblemRevision{}, err
+	}
+
+	current := snapshot(problem)
+	latest, err := Latest(tx, problemID, true)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return models.ProblemRevision{}, err
+	}
+	if err == nil && Compare(latest, current).Empty() {
+		return latest, nil
+	}
+
+	current.Number = latest.Number + 1
+	current.UserID = userID
+	current.Change = change
+	if err := tx.Create(&current).Error; err != nil {
+		return models.ProblemRevision{}, err
+	}
+	return current, nil
+}
+
+// Latest returns the latest revision of a problem, with its tests if withTests is set
+func Latest(db *gorm.DB, problemID uint, withTests bool) (models.ProblemRevision, error) {
+	var rev models.ProblemRevision
+	query := db.Where("problem_id = ?", problemID).Order("number DESC")
+	if withTests {
+		query = query.Preload("Tests", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	}
+	err := query.First(&rev).Error
+	return rev, err
+}
+
+// LatestID is the ID of the latest revision of a problem, 0 if it has none
+func LatestID(db *gorm.DB, problemID uint) uint {
+	var id uint
+	db.Model(&models.ProblemRevision{}).Where("problem_id = ?", problemID).
+		Order("number DESC").Limit(1).Pluck("id", &id)
+	return id
+}
+
+// snapshot copies what a revision keeps from a problem with its subtasks and tests loaded
+func snapshot(problem models.Problem) models.ProblemRevision {
+	rev := models.ProblemRevision{
+		ProblemID:            problem.ID,
+		Title:                problem.Title,
+		Description:          problem.Description,
+		TimeLimit:            problem.TimeLimit,
+		MemoryLimit:          problem.MemoryLimit,
+		OutputLimit:          problem.OutputLimit,
+		CheckerMode:          problem.CheckerMode,
+		CheckerEpsilon:       problem.CheckerEpsilon,
+		Tags:                 problem.Tags,
+		Difficulty:           problem.Difficulty,
+		AuthorSourceCode:     problem.AuthorSourceCode,
+		AuthorLanguage:       problem.AuthorLanguage,
+		CheckerSourceCode:    problem.CheckerSourceCode,
+		CheckerLanguage:      problem.CheckerLanguage,
+		InteractorSourceCode: problem.InteractorSourceCode,
+		InteractorLanguage:   problem.InteractorLanguage,
+		ValidatorSourceCode:  problem.ValidatorSourceCode,
+		ValidatorLanguage:    problem.ValidatorLanguage,
+		Subtasks:             []models.Subtask{},
+		Generators:           []models.Generator{},
+		Solutions:            []models.ProblemSolution{},
+		Tests:                []models.RevisionTest{},
+	}
+
+	if rev.Tags == nil {
+		rev.Tags = []string{}
+	}
+	// Subtask rows are replaced on every change, so only their content is kept
+	for _, st := range problem.Subtasks {
+		st.ID, st.ProblemID = 0, 0
+		if st.Dependencies == nil {
+			st.Dependencies = []int{}
+		}
+		rev.Subtasks = append(rev.Subtasks, st)
+	}
+	// Generators and reference solutions are replaced as a whole too
+	for _, g := range problem.Generators {
+		g.ID, g.ProblemID = 0, 0
+		rev.Generators = append(rev.Generators, g)
+	}
+	for _, solution := range problem.Solutions {
+		solution.ID, solution.ProblemID = 0, 0
+		rev.Solutions = append(rev.Solutions, solution)
+	}
+	for _, tc := range problem.TestCases {
+		rev.Tests = append(rev.Tests, models.RevisionTest{
+			TestCaseID: tc.ID,
+			InputHash:  tc.InputHash,
+			InputSize:  tc.InputSize,
+			OutputHash: tc.OutputHash,
+			OutputSize: tc.OutputSize,
+			IsSample:   tc.IsSample,
+			Subtask:    tc.Subtask,
+			Script:     tc.Script,
+		})
+	}
+	return rev
+}
+
+// Restore sets the problem back to the state of a revision, which must have its tests loaded.
+// Generators and reference solutions are recreated, so they get new IDs like subtasks.
+// Deleted tests come back with their old IDs, so earlier submission details still refer to them.
+func Restore(tx *gorm.DB, rev models.ProblemRevision) error {
+	tags, err := json.Marshal(rev.Tags)
+	if err != nil {
+		return err
+	}
+	// A map, so that zero values are written too
+	err = tx.Model(&models.Problem{}).Where("id = ?", rev.ProblemID).Updates(map[string]interface{}{
+		"title":                  rev.Title,
+		"description":            rev.Description,
+		"time_limit":             rev.TimeLimit,
+		"memory_limit":           rev.MemoryLimit,
+		"output_limit":           rev.OutputLimit,
+		"checker_mode":           rev.CheckerMode,
+		"checker_epsilon":        rev.CheckerEpsilon,
+		"tags":                   string(tags),
+		"difficulty":             rev.Difficulty,
+		"author_source_code":     rev.AuthorSourceCode,
+		"author_language":        rev.AuthorLanguage,
+		"checker_source_code":    rev.CheckerSourceCode,
+		"checker_language":       rev.CheckerLanguage,
+		"interactor_source_code": rev.InteractorSourceCode,
+		"interactor_language":    rev.InteractorLanguage,
+		"validator_source_code":  rev.ValidatorSourceCode,
+		"validator_language":     rev.ValidatorLanguage,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Where("problem_id = ?", rev.ProblemID).Delete(&models.Subtask{}).Error; err != nil {
+		return err
+	}
+	if len(rev.Subtasks) > 0 {
+		subtasks := make([]models.Subtask, len(rev.Subtasks))
+		for i, st := range rev.Subtasks {
+			st.ID, st.ProblemID = 0, rev.ProblemID
+			subtasks[i] = st
+		}
+		if err := tx.Create(&subtasks).Error; err != nil {
+			return err
+		}
+	}
+
+	// Revisions taken before generators and reference solutions were kept leave them as they are
+	if rev.Generators != nil {
+		if err := tx.Where("problem_id = ?", rev.ProblemID).Delete(&models.Generator{}).Error; err != nil {
+			return err
+		}
+		if len(rev.Generators) > 0 {
+			generators := make([]models.Generator, len(rev.Generators))
+			for i, g := range rev.Generators {
+				g.ID, g.ProblemID = 0, rev.ProblemID
+				generators[i] = g
+			}
+			if err := tx.Create(&generators).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if rev.Solutions != nil {
+		if err := tx.Where("problem_id = ?", rev.ProblemID).Delete(&models.ProblemSolution{}).Error; err != nil {
+			return err
+		}
+		if len(rev.Solutions) > 0 {
+			solutions := make([]models.ProblemSolution, len(rev.Solutions))
+			for i, solution := range rev.Solutions {
+				solution.ID, solution.ProblemID = 0, rev.ProblemID
+				solutions[i] = solution
+			}
+			if err := tx.Create(&solutions).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	kept := make([]uint, 0, len(rev.Tests))
+	for _, test := range rev.Tests {
+		kept = append(kept, test.TestCaseID)
+	}
+	removed := tx.Where("problem_id = ?", rev.ProblemID)
+	if len(kept) > 0 {
+		removed = removed.Where("id NOT IN ?", kept)
+	}
+	if err := removed.Delete(&models.TestCase{}).Error; err != nil {
+		return err
+	}
+
+	for _, test := range rev.Tests {
+		testCase := models.TestCase{
+			ID:         test.TestCaseID,
+			ProblemID:  rev.ProblemID,
+			InputHash:  test.InputHash,
+			InputSize:  test.InputSize,
+			OutputHash: test.OutputHash,
+			OutputSize: test.OutputSize,
+			IsSample:   test.IsSample,
+			Subtask:    test.Subtask,
+			Script:     test.Script,
+		}
+		// Save updates the test if it still exists and creates it otherwise
+		if err := tx.Save(&testCase).Error; err != nil {
+			return fmt.Errorf("test %d: %v", test.TestCaseID, err)
+		}
+	}
+	return nil
+}
+
+// FieldChange is a field that differs between two revisions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// TestChange is a test present in both revisions that differs between them
+type TestChange struct {
+	TestCaseID uint          `json:"test_case_id"`
+	Fields     []FieldChange `json:"fields"`
+}
+
+// Diff lists the differences between two revisions
+type Diff struct {
+	From         int                   `json:"from"`
+	To           int                   `json:"to"`
+	Fields       []FieldChange         `json:"fields"`
+	AddedTests   []models.RevisionTest `json:"added_tests"`
+	RemovedTests []models.RevisionTest `json:"removed_tests"`
+	ChangedTests []TestChange          `json:"changed_tests"`
+}
+
+// Empty reports whether the revisions are the same
+func (d Diff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.AddedTests) == 0 && len(d.RemovedTests) == 0 && len(d.ChangedTests) == 0
+}
+
+// revisionFields are the compared fields of a revision, by their JSON names
+var revisionFields = []struct {
+	name  string
+	value func(rev *models.ProblemRevision) interface{}
+}{
+	{"title", func(r *models.ProblemRevision) interface{} { return r.Title }},
+	{"description", func(r *models.ProblemRevision) interface{} { return r.Description }},
+	{"time_limit", func(r *models.ProblemRevision) interface{} { return r.TimeLimit }},
+	{"memory_limit", func(r *models.ProblemRevision) interface{} { return r.MemoryLimit }},
+	{"output_limit", func(r *models.ProblemRevision) interface{} { return r.OutputLimit }},
+	{"checker_mode", func(r *models.ProblemRevision) interface{} { return r.CheckerMode }},
+	{"checker_epsilon", func(r *models.ProblemRevision) interface{} { return r.CheckerEpsilon }},
+	{"tags", func(r *models.ProblemRevision) interface{} { return r.Tags }},
+	{"difficulty", func(r *models.ProblemRevision) interface{} { return r.Difficulty }},
+	{"author_source_code", func(r *models.ProblemRevision) interface{} { return r.AuthorSourceCode }},
+	{"author_language", func(r *models.ProblemRevision) interface{} { return r.AuthorLanguage }},
+	{"checker_source_code", func(r *models.ProblemRevision) interface{} { return r.CheckerSourceCode }},
+	{"checker_language", func(r *models.ProblemRevision) interface{} { return r.CheckerLanguage }},
+	{"interactor_source_code", func(r *models.ProblemRevision) interface{} { return r.InteractorSourceCode }},
+	{"interactor_language", func(r *models.ProblemRevision) interface{} { return r.InteractorLanguage }},
+	{"validator_source_code", func(r *models.ProblemRevision) interface{} { return r.ValidatorSourceCode }},
+	{"validator_language", func(r *models.ProblemRevision) interface{} { return r.ValidatorLanguage }},
+	{"subtasks", func(r *models.ProblemRevision) interface{} { return r.Subtasks }},
+	{"generators", func(r *models.ProblemRevision) interface{} { return untracked(r.Generators) }},
+	{"solutions", func(r *models.ProblemRevision) interface{} { return untracked(r.Solutions) }},
+}
+
+// untracked compares a list missing from an older revision as empty
+func untracked[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+// testFields are the compared fields of a test
+var testFields = []struct {
+	name  string
+	value func(test *models.RevisionTest) interface{}
+}{
+	{"input_hash", func(t *models.RevisionTest) interface{} { return t.InputHash }},
+	{"output_hash", func(t *models.RevisionTest) interface{} { return t.OutputHash }},
+	{"is_sample", func(t *models.RevisionTest) interface{} { return t.IsSample }},
+	{"subtask", func(t *models.RevisionTest) interface{} { return t.Subtask }},
+	{"script", func(t *models.RevisionTest) interface{} { return t.Script }},
+}
+
+// Compare lists what changed from one revision to another; both must have their tests loaded.
+// Tests are matched by their test case ID.
+func Compare(from, to models.ProblemRevision) Diff {
+	diff := Diff{
+		From:         from.Number,
+		To:           to.Number,
+		Fields:       []FieldChange{},
+		AddedTests:   []models.RevisionTest{},
+		RemovedTests: []models.RevisionTest{},
+		ChangedTests: []TestChange{},
+	}
+
+	for _, field := range revisionFields {
+		a, b := field.value(&from), field.value(&to)
+		if !sameJSON(a, b) {
+			diff.Fields = append(diff.Fields, FieldChange{Field: field.name, From: a, To: b})
+		}
+	}
+
+	before := map[uint]*models.RevisionTest{}
+	for i := range from.Tests {
+		before[from.Tests[i].TestCaseID] = &from.Tests[i]
+	}
+	after := map[uint]bool{}
+	for i := range to.Tests {
+		test := &to.Tests[i]
+		after[test.TestCaseID] = true
+		old, ok := before[test.TestCaseID]
+		if !ok {
+			diff.AddedTests = append(diff.AddedTests, *test)
+			continue
+		}
+		var fields []FieldChange
+		for _, field := range testFields {
+			a, b := field.value(old), field.value(test)
+			if a != b {
+				fields = append(fields, FieldChange{Field: field.name, From: a, To: b})
+			}
+		}
+		if len(fields) > 0 {
+			diff.ChangedTests = append(diff.ChangedTests, TestChange{TestCaseID: test.TestCaseID, Fields: fields})
+		}
+	}
+	for _, test := range from.Tests {
+		if !after[test.TestCaseID] {
+			diff.RemovedTests = append(diff.RemovedTests, test)
+		}
+	}
+	return diff
+}
+
+// sameJSON compares values by their encoding, which treats equal subtask lists alike
+// whether they were just loaded or read back from a revision
+func sameJSON(a, b interface{}) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && string(x) == string(y)
+}
@@ -0,0 +1,185 @@
+package revision
+
+import (
+	"onlineJudge/backend/app/models"
+	"reflect"
+	"testing"
+)
+
+// base is a revision that the cases of TestCompare change one thing at a time
+func base() models.ProblemRevision {
+	return models.ProblemRevision{
+		Number:      1,
+		Title:       "A + B",
+		Description: "Add two numbers",
+		TimeLimit:   1,
+		MemoryLimit: 256,
+		CheckerMode: "exact",
+		Tags:        []string{"math"},
+		Subtasks:    []models.Subtask{{Index: 1, Points: 100, Dependencies: []int{}}},
+		Generators:  []models.Generator{{Name: "gen", SourceCode: "print(1)", Language: "python"}},
+		Solutions:   []models.ProblemSolution{{Name: "slow", SourceCode: "...", Language: "python", Tag: "time_limit"}},
+		Tests: []models.RevisionTest{
+			{TestCaseID: 1, InputHash: "in1", OutputHash: "out1", IsSample: true},
+			{TestCaseID: 2, InputHash: "in2", OutputHash: "out2"},
+		},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(rev *models.ProblemRevision)
+		fields  []string
+		added   []uint
+		removed []uint
+		changed map[uint][]string
+	}{
+		{name: "identical", change: func(rev *models.ProblemRevision) {}},
+		{name: "statement and limits", change: func(rev *models.ProblemRevision) {
+			rev.Description = "Add two integers"
+			rev.TimeLimit = 2
+		}, fields: []string{"description", "time_limit"}},
+		{name: "tags", change: func(rev *models.ProblemRevision) { rev.Tags = append(rev.Tags, "implementation") }, fields: []string{"tags"}},
+		{name: "subtask points", change: func(rev *models.ProblemRevision) { rev.Subtasks[0].Points = 50 }, fields: []string{"subtasks"}},
+		{name: "generator source", change: func(rev *models.ProblemRevision) {
+			rev.Generators = []models.Generator{{Name: "gen", SourceCode: "print(2)", Language: "python"}}
+		}, fields: []string{"generators"}},
+		{name: "solution tag", change: func(rev *models.ProblemRevision) {
+			rev.Solutions = []models.ProblemSolution{{Name: "slow", SourceCode: "...", Language: "python", Tag: "time_or_accepted"}}
+		}, fields: []string{"solutions"}},
+		{name: "solutions removed", change: func(rev *models.ProblemRevision) { rev.Solutions = []models.ProblemSolution{} }, fields: []string{"solutions"}},
+		{name: "test added", change: func(rev *models.ProblemRevision) {
+			rev.Tests = append(rev.Tests, models.RevisionTest{TestCaseID: 3, InputHash: "in3", OutputHash: "out3"})
+		}, added: []uint{3}},
+		{name: "test removed", change: func(rev *models.ProblemRevision) { rev.Tests = rev.Tests[1:] }, removed: []uint{1}},
+		{name: "test answer and flags", change: func(rev *models.ProblemRevision) {
+			rev.Tests[0].IsSample = false
+			rev.Tests[1].OutputHash = "out2b"
+			rev.Tests[1].Subtask = 1
+		}, changed: map[uint][]string{1: {"is_sample"}, 2: {"output_hash", "subtask"}}},
+		{name: "tests are matched by id, not position", change: func(rev *models.ProblemRevision) {
+			rev.Tests[0], rev.Tests[1] = rev.Tests[1], rev.Tests[0]
+		}},
+		{name: "test replaced under a new id", change: func(rev *models.ProblemRevision) {
+			rev.Tests[1].TestCaseID = 4
+		}, added: []uint{4}, removed: []uint{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := base(), base()
+			to.Number = 2
+			tt.change(&to)
+
+			diff := Compare(from, to)
+			if diff.From != 1 || diff.To != 2 {
+				t.Errorf("diff is from %d to %d", diff.From, diff.To)
+			}
+
+			var fields []string
+			for _, field := range diff.Fields {
+				fields = append(fields, field.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("changed fields = %v, want %v", fields, tt.fields)
+			}
+			if ids := testIDs(diff.AddedTests); !reflect.DeepEqual(ids, tt.added) {
+				t.Errorf("added tests = %v, want %v", ids, tt.added)
+			}
+			if ids := testIDs(diff.RemovedTests); !reflect.DeepEqual(ids, tt.removed) {
+				t.Errorf("removed tests = %v, want %v", ids, tt.removed)
+			}
+
+			var changed map[uint][]string
+			for _, test := range diff.ChangedTests {
+				if changed == nil {
+					changed = map[uint][]string{}
+				}
+				for _, field := range test.Fields {
+					changed[test.TestCaseID] = append(changed[test.TestCaseID], field.Field)
+				}
+			}
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("changed tests = %v, want %v", changed, tt.changed)
+			}
+
+			if diff.Empty() != (tt.fields == nil && tt.added == nil && tt.removed == nil && tt.changed == nil) {
+				t.Errorf("Empty() = %v", diff.Empty())
+			}
+		})
+	}
+}
+
+func testIDs(tests []models.RevisionTest) []uint {
+	var ids []uint
+	for _, test := range tests {
+		ids = append(ids, test.TestCaseID)
+	}
+	return ids
+}
+
+func TestCompareFieldValues(t *testing.T) {
+	from, to := base(), base()
+	to.TimeLimit = 2.5
+
+	diff := Compare(from, to)
+	want := []FieldChange{{Field: "time_limit", From: 1.0, To: 2.5}}
+	if !reflect.DeepEqual(diff.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", diff.Fields, want)
+	}
+}
+
+func TestCompareUntrackedLists(t *testing.T) {
+	// Revisions taken before generators and solutions were kept have neither
+	old := base()
+	old.Generators, old.Solutions = nil, nil
+
+	current := base()
+	current.Generators, current.Solutions = []models.Generator{}, []models.ProblemSolution{}
+	if diff := Compare(old, current); !diff.Empty() {
+		t.Errorf("missing lists differ from empty ones: %+v", diff.Fields)
+	}
+	if diff := Compare(old, base()); len(diff.Fields) != 2 {
+		t.Errorf("added generators and solutions not reported: %+v", diff.Fields)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	problem := models.Problem{
+		ID:       7,
+		Title:    "A + B",
+		Subtasks: []models.Subtask{{ID: 10, ProblemID: 7, Index: 1, Points: 100}},
+		Generators: []models.Generator{
+			{ID: 20, ProblemID: 7, Name: "gen", SourceCode: "print(1)", Language: "python"},
+		},
+		Solutions: []models.ProblemSolution{{ID: 30, ProblemID: 7, Name: "wa", Language: "cpp", Tag: "wrong_answer"}},
+		TestCases: []models.TestCase{{ID: 40, ProblemID: 7, InputHash: "in", OutputHash: "out", Subtask: 1}},
+	}
+
+	rev := snapshot(problem)
+	if rev.ProblemID != 7 || rev.Title != "A + B" {
+		t.Errorf("problem fields not copied: %+v", rev)
+	}
+	if rev.Tags == nil || rev.Subtasks[0].Dependencies == nil {
+		t.Error("nil lists are not stored as empty")
+	}
+	if st := rev.Subtasks[0]; st.ID != 0 || st.ProblemID != 0 || st.Points != 100 {
+		t.Errorf("subtask = %+v, want its content only", st)
+	}
+	if g := rev.Generators[0]; g.ID != 0 || g.ProblemID != 0 || g.Name != "gen" {
+		t.Errorf("generator = %+v, want its content only", g)
+	}
+	if s := rev.Solutions[0]; s.ID != 0 || s.ProblemID != 0 || s.Tag != "wrong_answer" {
+		t.Errorf("solution = %+v, want its content only", s)
+	}
+	if len(rev.Tests) != 1 || rev.Tests[0].TestCaseID != 40 || rev.Tests[0].Subtask != 1 {
+		t.Errorf("tests = %+v", rev.Tests)
+	}
+
+	// A problem without generators or solutions still tracks them, as empty lists
+	empty := snapshot(models.Problem{ID: 7})
+	if empty.Generators == nil || empty.Solutions == nil {
+		t.Error("a snapshot leaves generators or solutions untracked")
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// localStore keeps objects in a directory, which has to be shared by all backend instances
+type localStore struct {
+	dir string
+}
+
+func newLocalStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &localStore{dir: dir}, nil
+}
+
+func (s *localStore) Put(content io.Reader) (Object, error) {
+	// Spooled next to the objects, so the final rename stays on one filesystem
+	file, object, err := spool(content, s.dir)
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	if err := claim(object.Hash); err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+
+	path := filepath.Join(s.dir, filepath.FromSlash(objectKey(object.Hash)))
+	if _, err := os.Stat(path); err == nil {
+		return object, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+	return object, nil
+}
+
+func (s *localStore) Open(hash string) (io.ReadCloser, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(objectKey(hash))))
+	if err != nil {
+		return nil, fmt.Errorf("test data %s not found: %v", hash, err)
+	}
+	return file, nil
+}
+
+func (s *localStore) Delete(hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(objectKey(hash))))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
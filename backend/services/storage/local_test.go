@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readObject reads a whole object from a store
+func readObject(t *testing.T, s Store, hash string) string {
+	t.Helper()
+	reader, err := s.Open(hash)
+	if err != nil {
+		t.Fatalf("Open(%s): %v", hash, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "1 2\n3 4\n"
+	object, err := s.Put(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Hash != Hash(content) || object.Size != int64(len(content)) {
+		t.Errorf("Put = %+v, want hash %s and size %d", object, Hash(content), len(content))
+	}
+	if _, err := os.Stat(filepath.Join(dir, object.Hash[:2], object.Hash)); err != nil {
+		t.Errorf("object not stored under its prefix: %v", err)
+	}
+	if got := readObject(t, s, object.Hash); got != content {
+		t.Errorf("Open = %q, want %q", got, content)
+	}
+
+	// Storing the same content again is a no-op
+	again, err := s.Put(strings.NewReader(content))
+	if err != nil || again != object {
+		t.Errorf("second Put = %+v, %v; want %+v", again, err, object)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "upload-") {
+			t.Errorf("spooled upload %s left behind", entry.Name())
+		}
+	}
+
+	empty, err := s.Put(strings.NewReader(""))
+	if err != nil || empty.Size != 0 || readObject(t, s, empty.Hash) != "" {
+		t.Errorf("empty Put = %+v, %v", empty, err)
+	}
+
+	if err := s.Delete(object.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(object.Hash); err == nil {
+		t.Error("Open of a deleted object succeeded")
+	}
+	if err := s.Delete(object.Hash); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsInvalidHashes(t *testing.T) {
+	s, err := newLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"abc", "../../etc/passwd", strings.Repeat("z", 64), strings.Repeat("../", 21) + "a"} {
+		if _, err := s.Open(hash); err == nil {
+			t.Errorf("Open(%q) succeeded", hash)
+		}
+		if err := s.Delete(hash); err == nil {
+			t.Errorf("Delete(%q) succeeded", hash)
+		}
+	}
+}
+
+func TestPutClaimsObject(t *testing.T) {
+	s, err := newLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousStore, previousClaim := store, claim
+	t.Cleanup(func() { store, claim = previousStore, previousClaim })
+	store = s
+
+	var claimed []string
+	SetClaim(func(hash string) error {
+		// The object must not exist yet when it is claimed
+		if _, err := os.Stat(filepath.Join(s.(*localStore).dir, objectKey(hash))); err == nil {
+			t.Errorf("object %s written before it was claimed", hash)
+		}
+		claimed = append(claimed, hash)
+		return nil
+	})
+	object, err := PutString("claimed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0] != object.Hash {
+		t.Errorf("claimed %v, want [%s]", claimed, object.Hash)
+	}
+
+	SetClaim(func(hash string) error { return os.ErrPermission })
+	if _, err := PutString("refused"); err == nil {
+		t.Error("Put succeeded although the claim failed")
+	}
+}
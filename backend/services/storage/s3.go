@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Store keeps objects in a bucket of an S3-compatible service (AWS, MinIO, ...).
+// Requests use path-style URLs and are signed with AWS Signature Version 4.
+type s3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func newS3Store(endpoint, bucket, region, accessKey, secretKey string) (Store, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is not set")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &s3Store{
+		endpoint:  parsed,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 10 * time.Minute},
+	}, nil
+}
+
+func (s *s3Store) Put(content io.Reader) (Object, error) {
+	file, object, err := spool(content, "")
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if err := claim(object.Hash); err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+
+	// Content-addressed objects never change, so an existing one is already right
+	resp, err := s.do(http.MethodHead, object.Hash, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return object, nil
+	}
+
+	// The content hash doubles as the signed payload hash
+	resp, err = s.do(http.MethodPut, object.Hash, file, object.Size, object.Hash)
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to store test data: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Object{}, fmt.Errorf("failed to store test data: %v", s3Error(resp))
+	}
+	return object, nil
+}
+
+func (s *s3Store) Open(hash string) (io.ReadCloser, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(http.MethodGet, hash, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test data %s: %v", hash, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("failed to read test data %s: %v", hash, s3Error(resp))
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Store) Delete(hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, hash, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// do sends a signed request for the object of hash
+func (s *s3Store) do(method, hash string, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	target := *s.endpoint
+	target.Path += "/" + s.bucket + "/" + objectKey(hash)
+
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering host, date and payload hash
+func (s *s3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Error describes a failed response with the start of its XML error document
+func s3Error(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 responded %s: %s", resp.Status, strings.TrimSpace(string(message)))
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minio-secret"
+	testBucket    = "tests"
+	testRegion    = "eu-west-1"
+)
+
+// authorization is the layout of a SigV4 Authorization header
+var authorization = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+// fakeS3 is a MinIO-like stand-in that checks signatures and keeps objects in memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	methods []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.methods = append(f.methods, r.Method)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if problem := f.verify(r, body); problem != "" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+problem+"</Message></Error>")
+		return
+	}
+
+	key, found := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !found {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		content, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	case http.MethodPut:
+		f.objects[key] = body
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes the signature of a request the way S3 does and tells what is wrong with it
+func (f *fakeS3) verify(r *http.Request, body []byte) string {
+	match := authorization.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return "malformed Authorization header"
+	}
+	accessKey, date, region, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+	if accessKey != testAccessKey || region != testRegion {
+		return "unknown credential"
+	}
+	amzDate := r.Header.Get("x-amz-date")
+	if !strings.HasPrefix(amzDate, date) {
+		return "x-amz-date does not match the credential scope"
+	}
+	payloadHash := sha256.Sum256(body)
+	if r.Header.Get("x-amz-content-sha256") != hex.EncodeToString(payloadHash[:]) {
+		return "x-amz-content-sha256 does not match the body"
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonical := strings.Join([]string{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers.String(),
+		signedHeaders, r.Header.Get("x-amz-content-sha256")}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if hex.EncodeToString(hmacSHA256(key, toSign)) != signature {
+		return "signature does not match"
+	}
+	return ""
+}
+
+// s3ForTest starts the stand-in and a store pointing at it, signing with secretKey
+func s3ForTest(t *testing.T, secretKey string) (*fakeS3, Store) {
+	t.Helper()
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s, err := newS3Store(server.URL+"/", testBucket, testRegion, testAccessKey, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, s
+}
+
+func TestS3Store(t *testing.T) {
+	fake, s := s3ForTest(t, testSecretKey)
+
+	content := "3\n1 2 3\n"
+	object, err := s.Put(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Hash != Hash(content) || object.Size != int64(len(content)) {
+		t.Errorf("Put = %+v, want hash %s and size %d", object, Hash(content), len(content))
+	}
+	if got := string(fake.objects[objectKey(object.Hash)]); got != content {
+		t.Errorf("stored %q under %s, want %q", got, objectKey(object.Hash), content)
+	}
+	if got := readObject(t, s, object.Hash); got != content {
+		t.Errorf("Open = %q, want %q", got, content)
+	}
+
+	// An existing object is found with HEAD and not uploaded again
+	fake.methods = nil
+	if again, err := s.Put(strings.NewReader(content)); err != nil || again != object {
+		t.Errorf("second Put = %+v, %v; want %+v", again, err, object)
+	}
+	if strings.Join(fake.methods, " ") != "HEAD" {
+		t.Errorf("second Put sent %v, want only HEAD", fake.methods)
+	}
+
+	if err := s.Delete(object.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objects[objectKey(object.Hash)]; ok {
+		t.Error("object still stored after Delete")
+	}
+	if _, err := s.Open(object.Hash); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Open of a deleted object: %v, want a 404", err)
+	}
+	if err := s.Delete(object.Hash); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+
+	if _, err := s.Open("../" + object.Hash[3:]); err == nil {
+		t.Error("Open accepted an invalid hash")
+	}
+}
+
+func TestS3StoreRejectedSignature(t *testing.T) {
+	_, s := s3ForTest(t, "wrong-secret")
+
+	_, err := s.Put(strings.NewReader("data"))
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Put with a wrong secret: %v, want SignatureDoesNotMatch", err)
+	}
+	if err := s.Delete(Hash("data")); err == nil {
+		t.Error("Delete with a wrong secret succeeded")
+	}
+}
+
+func TestNewS3StoreValidation(t *testing.T) {
+	if _, err := newS3Store("not a url", testBucket, "", "", ""); err == nil {
+		t.Error("accepted an endpoint without a host")
+	}
+	if _, err := newS3Store("http://localhost:9000", "", "", "", ""); err == nil {
+		t.Error("accepted an empty bucket")
+	}
+	s, err := newS3Store("http://localhost:9000/", testBucket, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region := s.(*s3Store).region; region != "us-east-1" {
+		t.Errorf("default region = %q", region)
+	}
+}
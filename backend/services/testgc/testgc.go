@@ -0,0 +1,124 @@
+// Package testgc deletes stored test data that no test case or revision refers to any more.
+//
+// Objects are shared by content, so a request storing a new test may be writing the very
+// object another request is releasing. Released objects are therefore only marked, and a
+// periodic sweep deletes them once they have stayed unreferenced for a grace period.
+// Storing content claims its object first, which removes the mark, see Claim.
+package testgc
+
+import (
+	"fmt"
+	"log"
+	"onlineJudge/backend/app/models"
+	"onlineJudge/backend/database"
+	"onlineJudge/backend/services/storage"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// gracePeriod is how long a released object is kept. Content stored again must be
+	// referenced by a test within it, which covers the longest test import.
+	gracePeriod = time.Hour
+	// sweepInterval is how often released objects are collected
+	sweepInterval = 10 * time.Minute
+	// sweepBatch caps the objects examined by one sweep
+	sweepBatch = 1000
+)
+
+// Release marks the data of removed tests, or of stored data that ended up unused,
+// for collection. Objects that are still referenced survive the sweep.
+func Release(hashes ...string) {
+	now := time.Now()
+	seen := map[string]bool{}
+	var objects []models.ReleasedObject
+	for _, hash := range hashes {
+		if hash == "" || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		objects = append(objects, models.ReleasedObject{Hash: hash, ReleasedAt: now})
+	}
+	if len(objects) == 0 {
+		return
+	}
+
+	// Releasing again restarts the grace period
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"released_at"}),
+	}).Create(&objects).Error
+	if err != nil {
+		log.Printf("Failed to release test data: %v", err)
+	}
+}
+
+// Claim keeps an object from being collected. The storage calls it before writing content,
+// see storage.SetClaim; if a sweep is deleting the object, Claim waits for it to finish,
+// and the content is then written anew.
+func Claim(hash string) error {
+	return database.DB.Where("hash = ?", hash).Delete(&models.ReleasedObject{}).Error
+}
+
+// StartSweeper collects released objects in the background. Must be called after database.Connect.
+func StartSweeper() {
+	go func() {
+		for {
+			if err := Sweep(); err != nil {
+				log.Printf("Test data sweep failed: %v", err)
+			}
+			time.Sleep(sweepInterval)
+		}
+	}()
+}
+
+// Sweep deletes the objects released before the grace period that are still unreferenced
+func Sweep() error {
+	cutoff := time.Now().Add(-gracePeriod)
+
+	var hashes []string
+	err := database.DB.Model(&models.ReleasedObject{}).Where("released_at < ?", cutoff).
+		Order("released_at").Limit(sweepBatch).Pluck("hash", &hashes).Error
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if err := database.DB.Transaction(func(tx *gorm.DB) error { return collect(tx, hash, cutoff) }); err != nil {
+			return fmt.Errorf("test data %s: %v", hash, err)
+		}
+	}
+	return nil
+}
+
+// collect deletes one released object unless it is referenced again. The mark stays locked
+// until the object is gone, so a concurrent Claim cannot see it before the deletion.
+func collect(tx *gorm.DB, hash string, cutoff time.Time) error {
+	var released models.ReleasedObject
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("hash = ? AND released_at < ?", hash, cutoff).First(&released).Error
+	if err == gorm.ErrRecordNotFound {
+		// Claimed or released again meanwhile, or taken by another instance's sweep
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Revisions keep the data of tests that were replaced or deleted since
+	var tests, revisions int64
+	if err := tx.Model(&models.TestCase{}).Where("input_hash = ? OR output_hash = ?", hash, hash).Count(&tests).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.RevisionTest{}).Where("input_hash = ? OR output_hash = ?", hash, hash).Count(&revisions).Error; err != nil {
+		return err
+	}
+	if tests == 0 && revisions == 0 {
+		if err := storage.Delete(hash); err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&released).Error
+}
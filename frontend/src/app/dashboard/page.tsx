@@ -23,11 +23,11 @@ export default function Dashboard() {
     setUser(JSON.parse(userData));
 
     // Fetch My Problems
-    fetch(`${API_URL}/problems?filter=my`, {
+    fetch(`${API_URL}/problems?filter=my&page_size=100`, {
       headers: { 'Authorization': `Bearer ${token}` }
     })
       .then((res) => res.json())
-      .then(setProblems)
+      .then((data) => setProblems(data.problems || []))
       .catch(console.error);
 
     // Fetch My Contests
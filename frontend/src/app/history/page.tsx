@@ -62,6 +62,9 @@ function HistoryContent() {
                   }`}>
                     {sub.status}
                   </span>
+                  {sub.subtask_scores && (
+                    <span className="ml-2 text-sm text-gray-700">{sub.score} б.</span>
+                  )}
                 </td>
                 <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">{sub.language}</td>
                 <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">{sub.execution_time}</td>
@@ -90,6 +93,22 @@ function HistoryContent() {
               <pre className="bg-gray-100 p-4 rounded overflow-x-auto text-sm">{selectedSubmission.source_code}</pre>
             </div>
 
+            {selectedSubmission.subtask_scores && (
+              <div className="mb-4">
+                <h4 className="font-bold mb-2">Подзадачи: {selectedSubmission.score} б.</h4>
+                <div className="grid grid-cols-1 gap-2">
+                  {selectedSubmission.subtask_scores.map((s: any) => (
+                    <div key={s.index} className="border p-2 rounded flex justify-between text-sm">
+                      <span>Подзадача {s.index}</span>
+                      <span className={s.score >= s.points ? 'text-green-600' : s.score > 0 ? 'text-yellow-600' : 'text-red-600'}>
+                        {s.score} / {s.points}
+                      </span>
+                    </div>
+                  ))}
+                </div>
+              </div>
+            )}
+
             {selectedSubmission.details && (
               <div>
                 <h4 className="font-bold mb-2">Тесты:</h4>
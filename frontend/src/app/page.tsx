@@ -7,7 +7,7 @@ export const dynamic = 'force-dynamic';
 export default async function Home() {
   let problems = [];
   try {
-    problems = await getProblems();
+    problems = (await getProblems('page_size=6')).problems;
   } catch (e) {
     console.error(e);
   }
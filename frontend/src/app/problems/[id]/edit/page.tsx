@@ -4,27 +4,52 @@ import { useEffect, useState } from 'react';
 import { useRouter, useParams } from 'next/navigation';
 import Editor from '@monaco-editor/react';
 import { API_URL } from '@/lib/api';
+import LanguageSelect, { editorLanguage, useLanguages } from '@/components/LanguageSelect';
 
 export default function EditProblem() {
   const router = useRouter();
   const { id } = useParams();
   const [loading, setLoading] = useState(true);
+  const languages = useLanguages();
   const [formData, setFormData] = useState({
     title: '',
     description: '',
     time_limit: 1.0,
     memory_limit: 256,
+    output_limit: 64,
+    tags: '',
+    difficulty: 0,
     visibility: 'private',
     status: 'draft',
     moderation_comment: '',
     author_source_code: '// Write correct solution here to generate outputs',
     author_language: 'python',
+    checker_mode: 'tokens',
+    checker_epsilon: 0,
+    checker_source_code: '',
+    checker_language: 'cpp',
+    interactor_source_code: '',
+    interactor_language: 'cpp',
+    validator_source_code: '',
+    validator_language: 'cpp',
     share_token: ''
   });
   const [testCases, setTestCases] = useState<any[]>([]);
-  const [newTest, setNewTest] = useState({ input: '', is_sample: false });
+  const [newTest, setNewTest] = useState({ input: '', is_sample: false, subtask: 0 });
+  const [subtasks, setSubtasks] = useState<any[]>([]);
   const [addingTest, setAddingTest] = useState(false);
+  const [archive, setArchive] = useState<{ file: File | null, samples: string, generate: boolean }>({ file: null, samples: '', generate: false });
+  const [importing, setImporting] = useState(false);
+  const [importReport, setImportReport] = useState<any>(null);
+  const [generators, setGenerators] = useState<any[]>([]);
+  const [script, setScript] = useState({ script: '', is_sample: false });
+  const [generating, setGenerating] = useState(false);
+  const [solutions, setSolutions] = useState<any[]>([]);
+  const [invocation, setInvocation] = useState<any>(null);
+  const [calibration, setCalibration] = useState({ runs: 3, factor: 2, apply: false });
   const [shareEmail, setShareEmail] = useState('');
+  const [revisions, setRevisions] = useState<any[]>([]);
+  const [revisionDiff, setRevisionDiff] = useState<any>(null);
 
   useEffect(() => {
     const token = localStorage.getItem('token');
@@ -46,31 +71,80 @@ export default function EditProblem() {
           description: data.description,
           time_limit: data.time_limit,
           memory_limit: data.memory_limit,
+          output_limit: data.output_limit || 64,
+          tags: (data.tags || []).join(', '),
+          difficulty: data.difficulty || 0,
           visibility: data.visibility,
           status: data.status,
           moderation_comment: data.moderation_comment || '',
           author_source_code: data.author_source_code || '// Write correct solution here to generate outputs',
           author_language: data.author_language || 'python',
+          checker_mode: data.checker_mode || 'tokens',
+          checker_epsilon: data.checker_epsilon || 0,
+          checker_source_code: data.checker_source_code || '',
+          checker_language: data.checker_language || 'cpp',
+          interactor_source_code: data.interactor_source_code || '',
+          interactor_language: data.interactor_language || 'cpp',
+          validator_source_code: data.validator_source_code || '',
+          validator_language: data.validator_language || 'cpp',
           share_token: data.share_token || ''
         });
         setTestCases(data.test_cases || []);
+        setSubtasks(data.subtasks || []);
+        setGenerators(data.generators || []);
+        setSolutions(data.solutions || []);
       })
       .catch(console.error)
       .finally(() => setLoading(false));
+
+    fetch(`${API_URL}/problems/${id}/invocations`, {
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+      .then((res) => res.ok ? res.json() : [])
+      .then((data) => setInvocation(data[0] || null))
+      .catch(console.error);
+
+    fetch(`${API_URL}/problems/${id}/revisions`, {
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+      .then((res) => res.ok ? res.json() : [])
+      .then(setRevisions)
+      .catch(console.error);
   }, [id]);
 
+  // Invocations run in the judge queue; poll until the matrix is complete
+  useEffect(() => {
+    if (!invocation || (invocation.status !== 'queued' && invocation.status !== 'running')) return;
+    const timer = setTimeout(() => {
+      const token = localStorage.getItem('token');
+      fetch(`${API_URL}/problems/${id}/invocations/${invocation.id}`, {
+        headers: { 'Authorization': `Bearer ${token}` }
+      })
+        .then((res) => res.ok ? res.json() : invocation)
+        .then((data) => {
+          setInvocation(data);
+          // Keep the form from saving the old limit back
+          if (data.applied) {
+            setFormData((form) => ({ ...form, time_limit: data.proposed_time_limit }));
+          }
+        })
+        .catch(console.error);
+    }, 2000);
+    return () => clearTimeout(timer);
+  }, [id, invocation]);
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     const token = localStorage.getItem('token');
     
     try {
       const res = await fetch(`${API_URL}/problems/${id}`, {
-        method: 'PUT',
+        method: 'PATCH',
         headers: {
           'Content-Type': 'application/json',
           'Authorization': `Bearer ${token}`
         },
-        body: JSON.stringify(formData),
+        body: JSON.stringify({ ...formData, tags: formData.tags.split(',').map((t) => t.trim()).filter(Boolean) }),
       });
       
       if (res.ok) {
@@ -79,7 +153,12 @@ export default function EditProblem() {
           alert('Задача отправлена на модерацию. Ожидайте решения администратора.');
         }
       } else {
-        alert('Ошибка при обновлении');
+        const data = await res.json().catch(() => ({}));
+        if (data.fields) {
+          alert('Ошибка при обновлении:\n' + data.fields.map((f: any) => `${f.field}: ${f.message}`).join('\n'));
+        } else {
+          alert(data.error ? `Ошибка при обновлении: ${data.error}` : 'Ошибка при обновлении');
+        }
       }
     } catch (error) {
       console.error(error);
@@ -122,7 +201,7 @@ export default function EditProblem() {
       if (res.ok) {
         const addedTest = await res.json();
         setTestCases([...testCases, addedTest]);
-        setNewTest({ input: '', is_sample: false });
+        setNewTest({ ...newTest, input: '' });
       } else {
         const errorData = await res.json();
         alert(errorData.error || 'Ошибка при добавлении теста');
@@ -135,6 +214,176 @@ export default function EditProblem() {
     }
   };
 
+  const handleImportArchive = async () => {
+    if (!archive.file) {
+      alert('Выберите ZIP-архив');
+      return;
+    }
+
+    setImporting(true);
+    setImportReport(null);
+    const token = localStorage.getItem('token');
+    const body = new FormData();
+    body.append('archive', archive.file);
+    body.append('samples', archive.samples);
+    body.append('generate_outputs', String(archive.generate));
+    body.append('subtask', String(newTest.subtask));
+
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/testcases/archive`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` },
+        body,
+      });
+      const data = await res.json();
+      if (res.ok) {
+        setTestCases(data.test_cases || []);
+        setImportReport(data);
+      } else {
+        alert(data.error || 'Ошибка при загрузке архива');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    } finally {
+      setImporting(false);
+    }
+  };
+
+  const handleSaveGenerators = async () => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/generators`, {
+        method: 'PUT',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify(generators),
+      });
+
+      const data = await res.json();
+      if (res.ok) {
+        setGenerators(data);
+        alert('Генераторы сохранены');
+      } else {
+        alert(data.error || 'Ошибка при сохранении генераторов');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const handleGenerateTests = async () => {
+    if (!script.script.trim()) {
+      alert('Введите скрипт генерации');
+      return;
+    }
+
+    setGenerating(true);
+    setImportReport(null);
+    const token = localStorage.getItem('token');
+
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/testcases/generate`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify({ ...script, subtask: newTest.subtask }),
+      });
+      const data = await res.json();
+      if (res.ok) {
+        setTestCases(data.test_cases || []);
+        setImportReport(data);
+      } else {
+        alert(data.error || 'Ошибка при генерации тестов');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    } finally {
+      setGenerating(false);
+    }
+  };
+
+  const handleSaveSolutions = async () => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/solutions`, {
+        method: 'PUT',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify(solutions),
+      });
+
+      const data = await res.json();
+      if (res.ok) {
+        setSolutions(data);
+        alert('Решения сохранены');
+      } else {
+        alert(data.error || 'Ошибка при сохранении решений');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const handleInvoke = async () => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/invocations`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` }
+      });
+      const data = await res.json();
+      if (res.ok) {
+        setInvocation(data);
+      } else {
+        alert(data.error || 'Ошибка при запуске проверки');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const handleCalibrate = async () => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/calibrations`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify(calibration),
+      });
+      const data = await res.json();
+      if (res.ok) {
+        setInvocation(data);
+      } else {
+        alert(data.error || 'Ошибка при запуске калибровки');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const updateSolution = (i: number, changes: any) => {
+    setSolutions(solutions.map((s, j) => j === i ? { ...s, ...changes } : s));
+  };
+
+  const updateGenerator = (i: number, changes: any) => {
+    setGenerators(generators.map((g, j) => j === i ? { ...g, ...changes } : g));
+  };
+
   const handleDeleteTest = async (testId: number) => {
     if (!confirm('Удалить тест?')) return;
     const token = localStorage.getItem('token');
@@ -152,6 +401,123 @@ export default function EditProblem() {
     }
   };
 
+  const handleSaveSubtasks = async () => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/subtasks`, {
+        method: 'PUT',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify(subtasks),
+      });
+
+      const data = await res.json();
+      if (res.ok) {
+        setSubtasks(data);
+        // Tests of removed subtasks are no longer scored
+        const indexes = data.map((s: any) => s.index);
+        setTestCases(testCases.map(t => indexes.includes(t.subtask) ? t : { ...t, subtask: 0 }));
+        alert('Подзадачи сохранены');
+      } else {
+        alert(data.error || 'Ошибка при сохранении подзадач');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const updateSubtask = (i: number, changes: any) => {
+    setSubtasks(subtasks.map((s, j) => j === i ? { ...s, ...changes } : s));
+  };
+
+  const handleTestSubtask = async (testCase: any, subtask: number) => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/testcases/${testCase.id}`, {
+        method: 'PUT',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify({ is_sample: testCase.is_sample, subtask }),
+      });
+
+      const data = await res.json();
+      if (res.ok) {
+        setTestCases(testCases.map(t => t.id === data.id ? data : t));
+      } else {
+        alert(data.error || 'Ошибка при обновлении теста');
+      }
+    } catch (error) {
+      console.error(error);
+    }
+  };
+
+  const handleExport = async () => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/package`, {
+        headers: { 'Authorization': `Bearer ${token}` },
+      });
+      if (!res.ok) {
+        const data = await res.json();
+        alert(data.error || 'Ошибка при экспорте');
+        return;
+      }
+      const url = URL.createObjectURL(await res.blob());
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `problem-${id}.zip`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const handleShowDiff = async (number: number) => {
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/revisions/${number}/diff`, {
+        headers: { 'Authorization': `Bearer ${token}` },
+      });
+      const data = await res.json();
+      if (res.ok) {
+        setRevisionDiff(data);
+      } else {
+        alert(data.error || 'Ошибка при сравнении ревизий');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
+  const handleRollback = async (number: number) => {
+    if (!confirm(`Откатить задачу к ревизии ${number}? Откат сохранится как новая ревизия.`)) return;
+    const token = localStorage.getItem('token');
+    try {
+      const res = await fetch(`${API_URL}/problems/${id}/revisions/${number}/rollback`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` },
+      });
+      if (res.ok) {
+        // Everything on the page may have changed
+        window.location.reload();
+      } else {
+        const data = await res.json();
+        alert(data.error || 'Ошибка при откате');
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    }
+  };
+
   const handleShare = async () => {
     if (!shareEmail) return;
     const token = localStorage.getItem('token');
@@ -197,6 +563,7 @@ export default function EditProblem() {
         <h1 className="text-3xl font-bold text-gray-900">Редактировать задачу</h1>
         <div className="space-x-4">
           <button onClick={() => router.push(`/problems/${id}`)} className="text-blue-600 hover:text-blue-800 font-medium">Просмотр</button>
+          <button onClick={handleExport} className="text-blue-600 hover:text-blue-800 font-medium">Экспорт пакета</button>
           <button onClick={handleDelete} className="bg-red-600 hover:bg-red-700 text-white px-4 py-2 rounded text-sm font-medium">Удалить задачу</button>
         </div>
       </div>
@@ -239,7 +606,7 @@ export default function EditProblem() {
               <label className="block text-sm font-medium text-gray-700">Описание</label>
               <textarea required rows={6} className="mt-1 block w-full rounded-md border-gray-300 shadow-sm border p-2" value={formData.description} onChange={(e) => setFormData({ ...formData, description: e.target.value })} />
             </div>
-            <div className="grid grid-cols-2 gap-4">
+            <div className="grid grid-cols-3 gap-4">
               <div>
                 <label className="block text-sm font-medium text-gray-700">Время (сек)</label>
                 <input type="number" step="0.1" required className="mt-1 block w-full rounded-md border-gray-300 shadow-sm border p-2" value={formData.time_limit} onChange={(e) => setFormData({ ...formData, time_limit: parseFloat(e.target.value) })} />
@@ -248,6 +615,20 @@ export default function EditProblem() {
                 <label className="block text-sm font-medium text-gray-700">Память (МБ)</label>
                 <input type="number" required className="mt-1 block w-full rounded-md border-gray-300 shadow-sm border p-2" value={formData.memory_limit} onChange={(e) => setFormData({ ...formData, memory_limit: parseInt(e.target.value) })} />
               </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700">Вывод (МБ)</label>
+                <input type="number" min={1} max={256} required className="mt-1 block w-full rounded-md border-gray-300 shadow-sm border p-2" value={formData.output_limit} onChange={(e) => setFormData({ ...formData, output_limit: parseInt(e.target.value) })} />
+              </div>
+            </div>
+            <div className="grid grid-cols-3 gap-4">
+              <div className="col-span-2">
+                <label className="block text-sm font-medium text-gray-700">Теги (через запятую)</label>
+                <input type="text" placeholder="dp, graphs, math" className="mt-1 block w-full rounded-md border-gray-300 shadow-sm border p-2" value={formData.tags} onChange={(e) => setFormData({ ...formData, tags: e.target.value })} />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700">Сложность (0 — без оценки)</label>
+                <input type="number" min={0} max={3500} step={100} className="mt-1 block w-full rounded-md border-gray-300 shadow-sm border p-2" value={formData.difficulty} onChange={(e) => setFormData({ ...formData, difficulty: parseInt(e.target.value) || 0 })} />
+              </div>
             </div>
             <div className="grid grid-cols-2 gap-4">
               <div>
@@ -324,26 +705,73 @@ export default function EditProblem() {
             </div>
           </div>
 
+          {/* Revision History */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-4">История изменений</h3>
+            {revisions.length === 0 ? (
+              <p className="text-sm text-gray-500">Ревизий пока нет.</p>
+            ) : (
+              <ul className="divide-y max-h-64 overflow-y-auto text-sm">
+                {revisions.map((rev, i) => (
+                  <li key={rev.id} className="py-2 flex items-center gap-3">
+                    <span className="font-mono text-gray-500">#{rev.number}</span>
+                    <span className="flex-grow">{rev.change}</span>
+                    <span className="text-xs text-gray-400">{new Date(rev.created_at).toLocaleString()}</span>
+                    <button onClick={() => handleShowDiff(rev.number)} className="text-blue-600 text-xs hover:underline">Изменения</button>
+                    {i > 0 && (
+                      <button onClick={() => handleRollback(rev.number)} className="text-red-600 text-xs hover:underline">Откатить</button>
+                    )}
+                  </li>
+                ))}
+              </ul>
+            )}
+            {revisionDiff && (
+              <div className="mt-4 border-t pt-4 text-sm space-y-2">
+                <div className="flex justify-between items-center">
+                  <span className="font-medium">Ревизия {revisionDiff.from} → {revisionDiff.to}</span>
+                  <button onClick={() => setRevisionDiff(null)} className="text-gray-500 text-xs hover:underline">Скрыть</button>
+                </div>
+                {revisionDiff.fields.map((f: any) => (
+                  <div key={f.field}>
+                    <span className="font-mono text-xs">{f.field}</span>:{' '}
+                    {typeof f.from === 'object' || String(f.from).length > 60 || String(f.to).length > 60 ? (
+                      <span className="text-gray-500">изменено</span>
+                    ) : (
+                      <span><span className="text-red-700 line-through">{String(f.from)}</span> → <span className="text-green-700">{String(f.to)}</span></span>
+                    )}
+                  </div>
+                ))}
+                {revisionDiff.added_tests.length > 0 && (
+                  <div className="text-green-700">Добавлены тесты: {revisionDiff.added_tests.map((t: any) => t.test_case_id).join(', ')}</div>
+                )}
+                {revisionDiff.removed_tests.length > 0 && (
+                  <div className="text-red-700">Удалены тесты: {revisionDiff.removed_tests.map((t: any) => t.test_case_id).join(', ')}</div>
+                )}
+                {revisionDiff.changed_tests.map((t: any) => (
+                  <div key={t.test_case_id}>
+                    Тест {t.test_case_id}: {t.fields.map((f: any) => f.field).join(', ')}
+                  </div>
+                ))}
+              </div>
+            )}
+          </div>
+
           {/* Author Solution Editor */}
           <div className="bg-white shadow rounded-lg p-6">
             <h3 className="text-lg font-medium text-gray-900 mb-4">Авторское решение (для генерации тестов)</h3>
             <div className="mb-4">
-              <select 
-                value={formData.author_language} 
-                onChange={(e) => setFormData({ ...formData, author_language: e.target.value })}
+              <LanguageSelect
+                value={formData.author_language}
+                onChange={(value) => setFormData({ ...formData, author_language: value })}
+                languages={languages}
                 className="border rounded px-2 py-1 text-sm bg-white"
-              >
-                <option value="python">Python</option>
-                <option value="cpp">C++</option>
-                <option value="java">Java</option>
-                <option value="go">Go</option>
-              </select>
+              />
             </div>
             <div className="h-64 border rounded">
               <Editor
                 height="100%"
                 defaultLanguage="python"
-                language={formData.author_language === 'cpp' ? 'cpp' : formData.author_language}
+                language={editorLanguage(languages, formData.author_language)}
                 value={formData.author_source_code}
                 onChange={(value) => setFormData({ ...formData, author_source_code: value || '' })}
                 theme="vs-light"
@@ -352,10 +780,364 @@ export default function EditProblem() {
             </div>
             <p className="text-xs text-gray-500 mt-2">Не забудьте нажать "Сохранить изменения", чтобы сохранить код решения.</p>
           </div>
+
+          {/* Output Checking */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-2">Проверка ответа</h3>
+            <div className="flex gap-4 items-end mb-4">
+              <div>
+                <label className="block text-xs font-medium text-gray-500 mb-1">Сравнение</label>
+                <select
+                  value={formData.checker_mode}
+                  onChange={(e) => setFormData({ ...formData, checker_mode: e.target.value })}
+                  className="border rounded px-2 py-1 text-sm bg-white"
+                >
+                  <option value="tokens">По токенам (пробелы не важны)</option>
+                  <option value="exact">Построчно (PE при отличии в пробелах)</option>
+                  <option value="float">Вещественные числа с погрешностью</option>
+                  <option value="case-insensitive">Без учёта регистра</option>
+                  <option value="line-set">Множество строк</option>
+                  <option value="line-multiset">Строки в любом порядке</option>
+                </select>
+              </div>
+              {formData.checker_mode === 'float' && (
+                <div>
+                  <label className="block text-xs font-medium text-gray-500 mb-1">Погрешность</label>
+                  <input
+                    type="number"
+                    step="any"
+                    min="0"
+                    placeholder="1e-6"
+                    value={formData.checker_epsilon || ''}
+                    onChange={(e) => setFormData({ ...formData, checker_epsilon: parseFloat(e.target.value) || 0 })}
+                    className="border rounded px-2 py-1 text-sm w-32"
+                  />
+                </div>
+              )}
+            </div>
+            <h4 className="text-sm font-medium text-gray-900 mb-2">Чекер (необязательно, заменяет сравнение)</h4>
+            <p className="text-xs text-gray-500 mb-4">
+              Для задач с несколькими правильными ответами. Запускается как <code>checker input.txt output.txt answer.txt</code> (совместим с testlib):
+              код выхода 0 — OK, 1 — WA, 2 — PE, 3 — ошибка жюри. Оставьте пустым для посимвольного сравнения.
+            </p>
+            <div className="mb-4">
+              <LanguageSelect
+                value={formData.checker_language}
+                onChange={(value) => setFormData({ ...formData, checker_language: value })}
+                languages={languages}
+                className="border rounded px-2 py-1 text-sm bg-white"
+              />
+            </div>
+            <div className="h-64 border rounded">
+              <Editor
+                height="100%"
+                defaultLanguage="cpp"
+                language={editorLanguage(languages, formData.checker_language)}
+                value={formData.checker_source_code}
+                onChange={(value) => setFormData({ ...formData, checker_source_code: value || '' })}
+                theme="vs-light"
+                options={{ minimap: { enabled: false }, fontSize: 14 }}
+              />
+            </div>
+          </div>
+
+          {/* Interactor Editor */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-2">Интерактор (для интерактивных задач)</h3>
+            <p className="text-xs text-gray-500 mb-4">
+              Запускается как <code>interactor input.txt output.txt</code> одновременно с решением: его вывод подаётся на ввод решения и наоборот.
+              Вердикт — по коду выхода, как у чекера. Тесты интерактивной задачи не имеют эталонного вывода. Оставьте пустым для обычной задачи.
+            </p>
+            <div className="mb-4">
+              <LanguageSelect
+                value={formData.interactor_language}
+                onChange={(value) => setFormData({ ...formData, interactor_language: value })}
+                languages={languages}
+                className="border rounded px-2 py-1 text-sm bg-white"
+              />
+            </div>
+            <div className="h-64 border rounded">
+              <Editor
+                height="100%"
+                defaultLanguage="cpp"
+                language={editorLanguage(languages, formData.interactor_language)}
+                value={formData.interactor_source_code}
+                onChange={(value) => setFormData({ ...formData, interactor_source_code: value || '' })}
+                theme="vs-light"
+                options={{ minimap: { enabled: false }, fontSize: 14 }}
+              />
+            </div>
+          </div>
+
+          {/* Validator Editor */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-2">Валидатор</h3>
+            <p className="text-xs text-gray-500 mb-4">
+              Читает ввод теста со стандартного ввода и завершается с кодом 0, если ввод удовлетворяет ограничениям (как <code>registerValidation</code> в testlib).
+              Каждый новый тест проверяется валидатором. Оставьте пустым, чтобы не проверять.
+            </p>
+            <div className="mb-4">
+              <LanguageSelect
+                value={formData.validator_language}
+                onChange={(value) => setFormData({ ...formData, validator_language: value })}
+                languages={languages}
+                className="border rounded px-2 py-1 text-sm bg-white"
+              />
+            </div>
+            <div className="h-64 border rounded">
+              <Editor
+                height="100%"
+                defaultLanguage="cpp"
+                language={editorLanguage(languages, formData.validator_language)}
+                value={formData.validator_source_code}
+                onChange={(value) => setFormData({ ...formData, validator_source_code: value || '' })}
+                theme="vs-light"
+                options={{ minimap: { enabled: false }, fontSize: 14 }}
+              />
+            </div>
+          </div>
+
+          {/* Reference Solutions */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-2">Решения</h3>
+            <p className="text-xs text-gray-500 mb-4">
+              Дополнительные решения с ожидаемым вердиктом: медленные правильные и заведомо неверные.
+              Проверка запускает их и авторское решение на всех тестах и отмечает решения, результат которых расходится с ожидаемым.
+            </p>
+            <div className="space-y-3 mb-4">
+              {solutions.map((sol: any, i: number) => (
+                <div key={i} className="border rounded p-3 text-sm space-y-2">
+                  <div className="flex gap-2">
+                    <input className="flex-1 border rounded px-2 py-1" placeholder="Название" value={sol.name} onChange={(e) => updateSolution(i, { name: e.target.value })} />
+                    <LanguageSelect
+                      value={sol.language}
+                      onChange={(value) => updateSolution(i, { language: value })}
+                      languages={languages}
+                      className="border rounded px-2 py-1 text-sm bg-white"
+                    />
+                    <select className="border rounded px-2 py-1 text-sm bg-white" value={sol.tag} onChange={(e) => updateSolution(i, { tag: e.target.value })}>
+                      <option value="accepted">Верное (OK)</option>
+                      <option value="time_or_accepted">Верное или TL</option>
+                      <option value="wrong_answer">Неверный ответ (WA)</option>
+                      <option value="time_limit">Превышение времени (TL)</option>
+                      <option value="memory_limit">Превышение памяти (ML)</option>
+                      <option value="runtime_error">Ошибка выполнения (RE)</option>
+                      <option value="rejected">Любая ошибка</option>
+                    </select>
+                    <button onClick={() => setSolutions(solutions.filter((_, j) => j !== i))} className="text-red-500 hover:text-red-700">X</button>
+                  </div>
+                  <div className="h-48 border rounded">
+                    <Editor
+                      height="100%"
+                      defaultLanguage="cpp"
+                      language={editorLanguage(languages, sol.language)}
+                      value={sol.source_code}
+                      onChange={(value) => updateSolution(i, { source_code: value || '' })}
+                      theme="vs-light"
+                      options={{ minimap: { enabled: false }, fontSize: 12 }}
+                    />
+                  </div>
+                </div>
+              ))}
+            </div>
+            <div className="flex gap-2 mb-4">
+              <button
+                onClick={() => setSolutions([...solutions, { name: '', language: formData.author_language, tag: 'accepted', source_code: '' }])}
+                className="flex-1 border border-gray-300 py-1 rounded text-sm hover:bg-gray-50"
+              >
+                Добавить решение
+              </button>
+              <button onClick={handleSaveSolutions} className="flex-1 bg-blue-600 text-white py-1 rounded text-sm hover:bg-blue-700">
+                Сохранить
+              </button>
+              <button
+                onClick={handleInvoke}
+                disabled={invocation && (invocation.status === 'queued' || invocation.status === 'running')}
+                className="flex-1 bg-green-600 text-white py-1 rounded text-sm hover:bg-green-700 disabled:bg-gray-400"
+              >
+                Запустить проверку
+              </button>
+            </div>
+
+            <div className="flex flex-wrap items-center gap-2 mb-4 text-sm">
+              <span className="text-gray-500">Подбор TL: запусков</span>
+              <input type="number" min={1} max={10} className="w-16 border rounded px-2 py-1" value={calibration.runs} onChange={(e) => setCalibration({...calibration, runs: parseInt(e.target.value) || 1})} />
+              <span className="text-gray-500">× запас</span>
+              <input type="number" min={1} max={10} step={0.5} className="w-16 border rounded px-2 py-1" value={calibration.factor} onChange={(e) => setCalibration({...calibration, factor: parseFloat(e.target.value) || 1})} />
+              <label className="flex items-center space-x-1">
+                <input type="checkbox" checked={calibration.apply} onChange={(e) => setCalibration({...calibration, apply: e.target.checked})} />
+                <span>применить</span>
+              </label>
+              <button
+                onClick={handleCalibrate}
+                disabled={invocation && (invocation.status === 'queued' || invocation.status === 'running')}
+                className="border border-gray-300 px-3 py-1 rounded hover:bg-gray-50 disabled:text-gray-400"
+              >
+                Подобрать TL
+              </button>
+            </div>
+
+            {invocation && (
+              <div className="text-sm">
+                <div className="text-xs text-gray-500 mb-2">
+                  {invocation.runs ? 'Калибровка' : 'Проверка'} #{invocation.id} от {new Date(invocation.created_at).toLocaleString()}: {invocation.status}
+                  {invocation.error && <span className="text-red-600"> — {invocation.error}</span>}
+                  {invocation.proposed_time_limit > 0 && (
+                    <span className="text-blue-700 font-medium">
+                      {' '}— предлагаемый TL: {invocation.proposed_time_limit} с{invocation.applied ? ' (применён)' : ''}
+                    </span>
+                  )}
+                </div>
+                <div className="overflow-x-auto">
+                  <table className="text-xs border-collapse">
+                    <thead>
+                      <tr>
+                        <th className="border px-2 py-1 text-left">Решение</th>
+                        <th className="border px-2 py-1">Итог</th>
+                        {(invocation.test_case_ids || []).map((_: number, i: number) => (
+                          <th key={i} className="border px-2 py-1">{i + 1}</th>
+                        ))}
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {(invocation.solutions || []).map((row: any, i: number) => (
+                        <tr key={i} className={row.matches ? '' : 'bg-red-50'}>
+                          <td className="border px-2 py-1 whitespace-nowrap" title={row.compile_output}>
+                            <div className="font-medium">{row.name} <span className="text-gray-400">{row.language}, {row.tag}</span></div>
+                            {row.problem && <div className="text-red-600">{row.problem}</div>}
+                            {row.warning && <div className="text-yellow-600">{row.warning}</div>}
+                          </td>
+                          <td className="border px-2 py-1 whitespace-nowrap">{row.verdict}<br />{row.max_time} ms</td>
+                          {(row.tests || []).map((cell: any) => (
+                            <td key={cell.test_case_id} className={`border px-2 py-1 whitespace-nowrap ${cell.verdict === 'Accepted' ? 'text-green-700' : 'text-red-600'}`} title={cell.verdict}>
+                              {cell.verdict === 'Accepted' ? 'OK' : cell.verdict.split(' ').map((w: string) => w[0]).join('')}
+                              <br />{cell.time} ms
+                            </td>
+                          ))}
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              </div>
+            )}
+          </div>
         </div>
 
         {/* Test Cases */}
         <div className="space-y-6">
+          {/* Subtasks */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-2">Подзадачи</h3>
+            <p className="text-xs text-gray-500 mb-4">
+              Задача с подзадачами оценивается в баллах (IOI): решение проходит все тесты, балл — сумма баллов подзадач.
+              Подзадача может зависеть только от предыдущих, их тесты тоже учитываются.
+            </p>
+            <div className="space-y-3 mb-4">
+              {subtasks.map((s: any, i: number) => (
+                <div key={i} className="border rounded p-3 text-sm space-y-2">
+                  <div className="flex justify-between font-bold">
+                    <span>Подзадача {s.index}</span>
+                    <button onClick={() => setSubtasks(subtasks.filter((_, j) => j !== i))} className="text-red-500 hover:text-red-700">X</button>
+                  </div>
+                  <input className="w-full border rounded px-2 py-1" placeholder="Название" value={s.name || ''} onChange={(e) => updateSubtask(i, { name: e.target.value })} />
+                  <div className="grid grid-cols-2 gap-2">
+                    <input type="number" min={0} className="border rounded px-2 py-1" value={s.points} onChange={(e) => updateSubtask(i, { points: parseFloat(e.target.value) || 0 })} />
+                    <select className="border rounded px-2 py-1 bg-white" value={s.scoring || 'all'} onChange={(e) => updateSubtask(i, { scoring: e.target.value })}>
+                      <option value="all">Все тесты</option>
+                      <option value="min">Минимум</option>
+                      <option value="avg">Среднее</option>
+                    </select>
+                  </div>
+                  <input
+                    className="w-full border rounded px-2 py-1"
+                    placeholder="Зависит от (например: 1, 2)"
+                    value={(s.dependencies || []).join(', ')}
+                    onChange={(e) => updateSubtask(i, {
+                      dependencies: e.target.value.split(',').map(v => parseInt(v.trim())).filter(v => !isNaN(v))
+                    })}
+                  />
+                </div>
+              ))}
+            </div>
+            <div className="flex gap-2">
+              <button
+                onClick={() => setSubtasks([...subtasks, {
+                  index: subtasks.reduce((max, s) => Math.max(max, s.index), 0) + 1,
+                  name: '', points: 0, scoring: 'all', dependencies: []
+                }])}
+                className="flex-1 border border-gray-300 py-1 rounded text-sm hover:bg-gray-50"
+              >
+                Добавить подзадачу
+              </button>
+              <button onClick={handleSaveSubtasks} className="flex-1 bg-blue-600 text-white py-1 rounded text-sm hover:bg-blue-700">
+                Сохранить
+              </button>
+            </div>
+          </div>
+
+          {/* Generators */}
+          <div className="bg-white shadow rounded-lg p-6">
+            <h3 className="text-lg font-medium text-gray-900 mb-2">Генераторы</h3>
+            <p className="text-xs text-gray-500 mb-4">
+              Генератор получает аргументы строки скрипта (например, <code>gen 100000 7</code>) и печатает ввод теста. Ответы строит авторское решение.
+            </p>
+            <div className="space-y-3 mb-4">
+              {generators.map((g: any, i: number) => (
+                <div key={i} className="border rounded p-3 text-sm space-y-2">
+                  <div className="flex gap-2">
+                    <input className="flex-1 border rounded px-2 py-1 font-mono" placeholder="gen" value={g.name} onChange={(e) => updateGenerator(i, { name: e.target.value })} />
+                    <LanguageSelect
+                      value={g.language}
+                      onChange={(value) => updateGenerator(i, { language: value })}
+                      languages={languages}
+                      className="border rounded px-2 py-1 text-sm bg-white"
+                    />
+                    <button onClick={() => setGenerators(generators.filter((_, j) => j !== i))} className="text-red-500 hover:text-red-700">X</button>
+                  </div>
+                  <div className="h-48 border rounded">
+                    <Editor
+                      height="100%"
+                      defaultLanguage="cpp"
+                      language={editorLanguage(languages, g.language)}
+                      value={g.source_code}
+                      onChange={(value) => updateGenerator(i, { source_code: value || '' })}
+                      theme="vs-light"
+                      options={{ minimap: { enabled: false }, fontSize: 12 }}
+                    />
+                  </div>
+                </div>
+              ))}
+            </div>
+            <div className="flex gap-2 mb-6">
+              <button
+                onClick={() => setGenerators([...generators, { name: 'gen', language: 'cpp', source_code: '' }])}
+                className="flex-1 border border-gray-300 py-1 rounded text-sm hover:bg-gray-50"
+              >
+                Добавить генератор
+              </button>
+              <button onClick={handleSaveGenerators} className="flex-1 bg-blue-600 text-white py-1 rounded text-sm hover:bg-blue-700">
+                Сохранить
+              </button>
+            </div>
+
+            <div className="space-y-3">
+              <label className="block text-xs font-medium text-gray-500">Скрипт: одна строка — один тест, <code>#</code> — комментарий</label>
+              <textarea className="w-full border rounded p-2 text-sm font-mono h-24" placeholder={'gen 10 1\ngen 100000 7'} value={script.script} onChange={(e) => setScript({...script, script: e.target.value})} />
+              <label className="flex items-center space-x-2 text-sm">
+                <input type="checkbox" checked={script.is_sample} onChange={(e) => setScript({...script, is_sample: e.target.checked})} />
+                <span>Показывать как примеры</span>
+              </label>
+              <button
+                onClick={handleGenerateTests}
+                disabled={generating}
+                className="w-full bg-green-600 text-white py-1 rounded text-sm hover:bg-green-700 disabled:bg-gray-400"
+              >
+                {generating ? 'Генерация...' : 'Сгенерировать тесты'}
+              </button>
+            </div>
+          </div>
+
           <div className="bg-white shadow rounded-lg p-6">
             <h3 className="text-lg font-medium text-gray-900 mb-4">Тесты</h3>
             
@@ -370,6 +1152,14 @@ export default function EditProblem() {
                 <input type="checkbox" checked={newTest.is_sample} onChange={(e) => setNewTest({...newTest, is_sample: e.target.checked})} />
                 <span>Показывать как пример</span>
               </label>
+              {subtasks.length > 0 && (
+                <select className="w-full border rounded px-2 py-1 text-sm bg-white" value={newTest.subtask} onChange={(e) => setNewTest({...newTest, subtask: parseInt(e.target.value)})}>
+                  <option value={0}>Без подзадачи</option>
+                  {subtasks.map((s: any) => (
+                    <option key={s.index} value={s.index}>Подзадача {s.index}</option>
+                  ))}
+                </select>
+              )}
               <button 
                 onClick={handleAddTest} 
                 disabled={addingTest}
@@ -379,18 +1169,52 @@ export default function EditProblem() {
               </button>
             </div>
 
+            {/* Archive Import */}
+            <div className="space-y-3 mb-6 border-b pb-6">
+              <label className="block text-xs font-medium text-gray-500">ZIP-архив (01.in/01.out, 01/01.a или input1.txt/output1.txt)</label>
+              <input type="file" accept=".zip" className="w-full text-sm" onChange={(e) => setArchive({...archive, file: e.target.files?.[0] || null})} />
+              <input type="text" placeholder="Примеры: 01,02 (или имена sample*)" className="w-full border rounded px-2 py-1 text-sm" value={archive.samples} onChange={(e) => setArchive({...archive, samples: e.target.value})} />
+              <label className="flex items-center space-x-2 text-sm">
+                <input type="checkbox" checked={archive.generate} onChange={(e) => setArchive({...archive, generate: e.target.checked})} />
+                <span>Сгенерировать ответы авторским решением</span>
+              </label>
+              <button
+                onClick={handleImportArchive}
+                disabled={importing}
+                className="w-full bg-green-600 text-white py-1 rounded text-sm hover:bg-green-700 disabled:bg-gray-400"
+              >
+                {importing ? 'Загрузка...' : 'Загрузить архив'}
+              </button>
+              {importReport && (
+                <div className="text-xs text-gray-600 space-y-1">
+                  <div>Добавлено: {importReport.added.length}, заменено: {importReport.replaced.length}, пропущено: {importReport.skipped.length}</div>
+                  {importReport.skipped.map((s: any) => (
+                    <div key={s.name} className="text-gray-500">{s.name}: {s.reason}</div>
+                  ))}
+                </div>
+              )}
+            </div>
+
             {/* List */}
             <div className="space-y-2 max-h-[500px] overflow-y-auto">
               {testCases.map((tc: any, i: number) => (
                 <div key={tc.id} className="border rounded p-3 text-sm relative group">
                   <div className="font-bold mb-1 flex justify-between">
-                    <span>Тест #{i + 1} {tc.is_sample && <span className="text-blue-600 text-xs">(Sample)</span>}</span>
+                    <span>Тест #{i + 1} {tc.is_sample && <span className="text-blue-600 text-xs">(Sample)</span>} {tc.script && <code className="text-gray-500 text-xs font-normal">{tc.script}</code>}</span>
                     <button onClick={() => handleDeleteTest(tc.id)} className="text-red-500 hover:text-red-700">X</button>
                   </div>
                   <div className="grid grid-cols-2 gap-2">
                     <div className="bg-gray-50 p-1 rounded truncate font-mono text-xs" title={tc.input}>In: {tc.input}</div>
                     <div className="bg-gray-50 p-1 rounded truncate font-mono text-xs" title={tc.expected_output}>Out: {tc.expected_output}</div>
                   </div>
+                  {subtasks.length > 0 && (
+                    <select className="mt-2 w-full border rounded px-2 py-1 text-xs bg-white" value={tc.subtask || 0} onChange={(e) => handleTestSubtask(tc, parseInt(e.target.value))}>
+                      <option value={0}>Без подзадачи</option>
+                      {subtasks.map((s: any) => (
+                        <option key={s.index} value={s.index}>Подзадача {s.index}</option>
+                      ))}
+                    </select>
+                  )}
                 </div>
               ))}
               {testCases.length === 0 && <p className="text-gray-500 text-center text-sm">Нет тестов</p>}
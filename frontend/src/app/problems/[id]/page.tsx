@@ -6,6 +6,7 @@ import Editor from '@monaco-editor/react';
 import Link from 'next/link';
 import { useToast } from '@/components/ToastProvider';
 import { API_URL } from '@/lib/api';
+import LanguageSelect, { editorLanguage, useLanguages } from '@/components/LanguageSelect';
 
 // Force dynamic rendering
 export const dynamic = 'force-dynamic';
@@ -14,6 +15,7 @@ export const dynamic = 'force-dynamic';
 function SubmissionDetailsModal({ submission, onClose }: { submission: any, onClose: () => void }) {
   const [details, setDetails] = useState<any>(null);
   const [loading, setLoading] = useState(true);
+  const languages = useLanguages();
 
   useEffect(() => {
     if (submission.details) {
@@ -61,7 +63,7 @@ function SubmissionDetailsModal({ submission, onClose }: { submission: any, onCl
             <div className="flex-grow border rounded-lg overflow-hidden bg-gray-50">
               <Editor
                 height="100%"
-                defaultLanguage={submission.language === 'cpp' ? 'cpp' : submission.language}
+                defaultLanguage={editorLanguage(languages, submission.language)}
                 value={submission.source_code}
                 theme="vs-light"
                 options={{ readOnly: true, minimap: { enabled: false }, fontSize: 13 }}
@@ -92,9 +94,46 @@ function SubmissionDetailsModal({ submission, onClose }: { submission: any, onCl
                   </div>
                   <div className="mt-2 text-sm text-gray-600 flex gap-4">
                     <span>Время: <span className="font-mono font-medium">{details.execution_time}</span></span>
+                    {details.memory > 0 && (
+                      <span>Память: <span className="font-mono font-medium">{(details.memory / 1024).toFixed(1)} MB</span></span>
+                    )}
+                    {details.subtask_scores && (
+                      <span>Баллы: <span className="font-mono font-medium">{details.score}</span></span>
+                    )}
                   </div>
                 </div>
 
+                {details.subtask_scores && (
+                  <div className="bg-white rounded-lg border border-gray-200 divide-y divide-gray-100">
+                    {details.subtask_scores.map((s: any) => (
+                      <div key={s.index} className="px-4 py-2 flex justify-between text-sm">
+                        <span className="font-medium text-gray-700">Подзадача {s.index}</span>
+                        <span className={`font-mono ${s.score >= s.points ? 'text-green-700' : s.score > 0 ? 'text-yellow-700' : 'text-red-700'}`}>
+                          {s.score} / {s.points}
+                        </span>
+                      </div>
+                    ))}
+                  </div>
+                )}
+
+                {details.compile_output && (
+                  <div className="bg-gray-900 text-gray-100 rounded-lg p-4 text-xs font-mono whitespace-pre-wrap max-h-[200px] overflow-y-auto">
+                    {details.compile_output}
+                  </div>
+                )}
+
+                {details.details && details.details.filter((d: any) => d.status !== 'Accepted' && d.checker_message).slice(0, 1).map((d: any) => (
+                  <div key={`checker-${d.id}`} className="bg-yellow-50 border border-yellow-200 text-yellow-800 rounded-lg p-3 text-xs font-mono whitespace-pre-wrap">
+                    Чекер: {d.checker_message}
+                  </div>
+                ))}
+
+                {details.details && details.details.filter((d: any) => d.stderr).slice(0, 1).map((d: any) => (
+                  <div key={d.id} className="bg-gray-900 text-red-200 rounded-lg p-4 text-xs font-mono whitespace-pre-wrap max-h-[200px] overflow-y-auto">
+                    {d.stderr}
+                  </div>
+                ))}
+
                 <div className="bg-white rounded-lg border border-gray-200 overflow-hidden">
                   <div className="bg-gray-50 px-4 py-2 border-b border-gray-200 text-xs font-bold text-gray-500 uppercase">
                     Тесты
@@ -105,9 +144,15 @@ function SubmissionDetailsModal({ submission, onClose }: { submission: any, onCl
                         <span className="text-sm font-medium text-gray-700">Test #{i + 1}</span>
                         <div className="flex items-center gap-3">
                           <span className="text-xs text-gray-500 font-mono">{d.execution_time}</span>
-                          <span className={`px-2.5 py-0.5 rounded-full text-xs font-bold ${
-                            d.status === 'Accepted' ? 'bg-green-100 text-green-700' : 'bg-red-100 text-red-700'
-                          }`}>
+                          {d.memory > 0 && (
+                            <span className="text-xs text-gray-500 font-mono">{(d.memory / 1024).toFixed(1)} MB</span>
+                          )}
+                          <span
+                            title={d.checker_message || undefined}
+                            className={`px-2.5 py-0.5 rounded-full text-xs font-bold ${
+                              d.status === 'Accepted' ? 'bg-green-100 text-green-700' : 'bg-red-100 text-red-700'
+                            }`}
+                          >
                             {d.status}
                           </span>
                         </div>
@@ -139,11 +184,13 @@ function ProblemDetailContent() {
   const [problem, setProblem] = useState<any>(null);
   const [code, setCode] = useState('// Write your code here');
   const [language, setLanguage] = useState('python');
+  const languages = useLanguages();
   const [result, setResult] = useState<any>(null);
   const [submitting, setSubmitting] = useState(false);
   const [history, setHistory] = useState<any[]>([]);
   const [selectedSubmission, setSelectedSubmission] = useState<any>(null);
   const [cooldown, setCooldown] = useState(0);
+  const [progress, setProgress] = useState('');
   const [user, setUser] = useState<any>(null);
 
   useEffect(() => {
@@ -196,6 +243,60 @@ function ProblemDetailContent() {
       .catch(console.error);
   };
 
+  const fetchSubmission = async (submissionId: number, token: string | null) => {
+    const res = await fetch(`${API_URL}/submission/${submissionId}`, {
+      headers: { 'Authorization': `Bearer ${token}` }
+    });
+    return res.ok ? res.json() : null;
+  };
+
+  // Reads the Server-Sent Events stream of a submission and shows per-test progress
+  const followSubmission = async (submissionId: number, token: string | null) => {
+    try {
+      const res = await fetch(`${API_URL}/submission/${submissionId}/stream`, {
+        headers: { 'Authorization': `Bearer ${token}` }
+      });
+      if (!res.ok || !res.body) return;
+
+      const reader = res.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = '';
+
+      while (true) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        buffer += decoder.decode(value, { stream: true });
+
+        let boundary;
+        while ((boundary = buffer.indexOf('\n\n')) !== -1) {
+          const message = buffer.slice(0, boundary);
+          buffer = buffer.slice(boundary + 2);
+
+          const dataLine = message.split('\n').find(line => line.startsWith('data: '));
+          if (!dataLine) continue;
+          const event = JSON.parse(dataLine.slice(6));
+
+          switch (event.type) {
+            case 'compiling':
+              setProgress('Компиляция...');
+              break;
+            case 'running':
+              setProgress(`Тест ${event.test}/${event.total}`);
+              break;
+            case 'status':
+              setProgress(event.status === 'Pending' ? 'В очереди...' : 'Проверка...');
+              break;
+            case 'final':
+              setProgress('');
+              return;
+          }
+        }
+      }
+    } catch (error) {
+      console.error(error);
+    }
+  };
+
   const handleSubmit = async () => {
     if (!user) {
       router.push('/auth/login');
@@ -222,10 +323,25 @@ function ProblemDetailContent() {
           source_code: code
         })
       });
-      const data = await res.json();
+      let data = await res.json();
       setResult(data);
       
       if (res.ok) {
+        // Submissions are judged asynchronously; follow the progress stream until a final verdict
+        await followSubmission(data.id, token);
+
+        // Fall back to polling if the stream was interrupted
+        let final = await fetchSubmission(data.id, token);
+        while (final && (final.status === 'Pending' || final.status === 'Running')) {
+          await new Promise(resolve => setTimeout(resolve, 1000));
+          final = await fetchSubmission(data.id, token);
+        }
+        if (final) {
+          data = final;
+          setResult(data);
+        }
+        setProgress('');
+
         if (data.status === 'Accepted') {
           showToast('Решение принято!', 'success');
         } else {
@@ -290,6 +406,17 @@ function ProblemDetailContent() {
           <div className="flex gap-3 mb-6 text-xs font-medium text-gray-600">
             <span className="bg-blue-50 text-blue-700 px-2.5 py-1 rounded-md border border-blue-100">Time: {problem.time_limit}s</span>
             <span className="bg-green-50 text-green-700 px-2.5 py-1 rounded-md border border-green-100">Memory: {problem.memory_limit}MB</span>
+            {problem.max_score > 0 && (
+              <span className="bg-yellow-50 text-yellow-700 px-2.5 py-1 rounded-md border border-yellow-100">
+                Score: {problem.best_score ?? 0} / {problem.max_score}
+              </span>
+            )}
+            {problem.difficulty > 0 && (
+              <span className="bg-purple-50 text-purple-700 px-2.5 py-1 rounded-md border border-purple-100">Difficulty: {problem.difficulty}</span>
+            )}
+            {problem.tags?.map((tag: string) => (
+              <span key={tag} className="bg-gray-100 text-gray-600 px-2.5 py-1 rounded-md border border-gray-200">{tag}</span>
+            ))}
           </div>
 
           <div className="prose prose-sm max-w-none mb-8 text-gray-800 whitespace-pre-wrap leading-relaxed">
@@ -373,17 +500,12 @@ function ProblemDetailContent() {
         <div className="sticky top-24 flex flex-col gap-4">
           <div className="bg-white rounded-xl shadow-sm border border-gray-200 p-4 flex flex-col">
             <div className="flex justify-between items-center mb-3">
-              <select 
-                value={language} 
-                onChange={(e) => setLanguage(e.target.value)}
+              <LanguageSelect
+                value={language}
+                onChange={setLanguage}
+                languages={languages}
                 className="border rounded px-3 py-1.5 text-sm bg-white shadow-sm focus:ring-2 focus:ring-blue-500 outline-none"
-              >
-                <option value="python">Python</option>
-                <option value="cpp">C++</option>
-                <option value="java">Java</option>
-                <option value="go">Go</option>
-                <option value="javascript">Node.js</option>
-              </select>
+              />
               
               {user ? (
                 <button 
@@ -397,7 +519,7 @@ function ProblemDetailContent() {
                         <circle className="opacity-25" cx="12" cy="12" r="10" stroke="currentColor" strokeWidth="4"></circle>
                         <path className="opacity-75" fill="currentColor" d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"></path>
                       </svg>
-                      Проверка...
+                      {progress || 'Проверка...'}
                     </>
                   ) : cooldown > 0 ? (
                     `Ждите ${cooldown}с`
@@ -416,7 +538,7 @@ function ProblemDetailContent() {
               <Editor
                 height="100%"
                 defaultLanguage="python"
-                language={language === 'cpp' ? 'cpp' : language}
+                language={editorLanguage(languages, language)}
                 value={code}
                 onChange={(value) => setCode(value || '')}
                 theme="vs-light"
@@ -2,11 +2,13 @@
 
 import { useState } from 'react';
 import { useRouter } from 'next/navigation';
-import { createProblem } from '@/lib/api';
+import { API_URL, createProblem } from '@/lib/api';
 import Editor from '@monaco-editor/react';
+import LanguageSelect, { editorLanguage, useLanguages } from '@/components/LanguageSelect';
 
 export default function CreateProblem() {
   const router = useRouter();
+  const languages = useLanguages();
   const [formData, setFormData] = useState({
     title: '',
     description: '',
@@ -16,6 +18,45 @@ export default function CreateProblem() {
     author_language: 'python'
   });
 
+  const [pkgFile, setPkgFile] = useState<File | null>(null);
+  const [pkgSummary, setPkgSummary] = useState<any>(null);
+  const [importing, setImporting] = useState(false);
+
+  // With dryRun the package is only checked and its summary shown
+  const handleImport = async (dryRun: boolean) => {
+    if (!pkgFile) {
+      alert('Выберите ZIP-пакет');
+      return;
+    }
+
+    setImporting(true);
+    const token = localStorage.getItem('token');
+    const body = new FormData();
+    body.append('package', pkgFile);
+    body.append('dry_run', String(dryRun));
+
+    try {
+      const res = await fetch(`${API_URL}/problems/import`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` },
+        body,
+      });
+      const data = await res.json();
+      if (!res.ok) {
+        alert(data.error || 'Ошибка при импорте');
+      } else if (dryRun) {
+        setPkgSummary(data.summary);
+      } else {
+        router.push(`/problems/${data.problem.id}/edit`);
+      }
+    } catch (error) {
+      console.error(error);
+      alert('Ошибка сети');
+    } finally {
+      setImporting(false);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     try {
@@ -29,6 +70,43 @@ export default function CreateProblem() {
   return (
     <div className="max-w-4xl mx-auto py-10 px-4">
       <h1 className="text-3xl font-bold mb-8">Создать задачу</h1>
+
+      {/* Package import */}
+      <div className="bg-white shadow rounded-lg p-6 mb-8">
+        <h3 className="text-lg font-medium text-gray-900 mb-2">Импорт пакета</h3>
+        <p className="text-xs text-gray-500 mb-4">ZIP-пакет, экспортированный из этой системы, или пакет Polygon с problem.xml. Задача создаётся как черновик.</p>
+        <div className="flex flex-wrap items-center gap-3">
+          <input type="file" accept=".zip" className="text-sm" onChange={(e) => { setPkgFile(e.target.files?.[0] || null); setPkgSummary(null); }} />
+          <button type="button" onClick={() => handleImport(true)} disabled={importing} className="border border-blue-600 text-blue-600 px-4 py-2 rounded text-sm hover:bg-blue-50 disabled:opacity-50">
+            Проверить
+          </button>
+          <button type="button" onClick={() => handleImport(false)} disabled={importing} className="bg-blue-600 text-white px-4 py-2 rounded text-sm hover:bg-blue-700 disabled:opacity-50">
+            {importing ? 'Импорт...' : 'Импортировать'}
+          </button>
+        </div>
+        {pkgSummary && (
+          <div className="mt-4 text-sm text-gray-700 space-y-1">
+            <div><span className="font-medium">{pkgSummary.title}</span> ({pkgSummary.format}), {pkgSummary.time_limit} сек, {pkgSummary.memory_limit} МБ</div>
+            <div>
+              Тестов: {pkgSummary.tests} (примеров: {pkgSummary.samples}, генерируемых: {pkgSummary.generated_inputs}, ответов от авторского решения: {pkgSummary.generated_answers})
+            </div>
+            <div>
+              Проверка: {pkgSummary.interactor ? 'интерактор' : pkgSummary.checker ? 'чекер' : pkgSummary.checker_mode}
+              {pkgSummary.validator && ', валидатор'}
+              {pkgSummary.author_language ? `, авторское решение (${pkgSummary.author_language})` : ', без авторского решения'}
+            </div>
+            {pkgSummary.subtasks.length > 0 && <div>Подзадач: {pkgSummary.subtasks.length}</div>}
+            {pkgSummary.generators.length > 0 && <div>Генераторы: {pkgSummary.generators.join(', ')}</div>}
+            {pkgSummary.solutions.length > 0 && (
+              <div>Решения: {pkgSummary.solutions.map((s: any) => `${s.name} (${s.tag})`).join(', ')}</div>
+            )}
+            {pkgSummary.warnings.map((w: string, i: number) => (
+              <div key={i} className="text-yellow-700">{w}</div>
+            ))}
+          </div>
+        )}
+      </div>
+
       <form onSubmit={handleSubmit} className="space-y-6">
         <div className="grid grid-cols-1 md:grid-cols-2 gap-6">
           <div className="space-y-6">
@@ -81,22 +159,18 @@ export default function CreateProblem() {
           <div className="bg-white shadow rounded-lg p-6 h-fit">
             <h3 className="text-lg font-medium text-gray-900 mb-4">Авторское решение (Обязательно)</h3>
             <div className="mb-4">
-              <select 
-                value={formData.author_language} 
-                onChange={(e) => setFormData({ ...formData, author_language: e.target.value })}
+              <LanguageSelect
+                value={formData.author_language}
+                onChange={(value) => setFormData({ ...formData, author_language: value })}
+                languages={languages}
                 className="border rounded px-2 py-1 text-sm bg-white"
-              >
-                <option value="python">Python</option>
-                <option value="cpp">C++</option>
-                <option value="java">Java</option>
-                <option value="go">Go</option>
-              </select>
+              />
             </div>
             <div className="h-64 border rounded">
               <Editor
                 height="100%"
                 defaultLanguage="python"
-                language={formData.author_language === 'cpp' ? 'cpp' : formData.author_language}
+                language={editorLanguage(languages, formData.author_language)}
                 value={formData.author_source_code}
                 onChange={(value) => setFormData({ ...formData, author_source_code: value || '' })}
                 theme="vs-light"
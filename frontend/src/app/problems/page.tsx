@@ -18,6 +18,15 @@ function ProblemsContent() {
   const [debouncedSearch, setDebouncedSearch] = useState('');
   const [filter, setFilter] = useState(initialFilter);
   const [user, setUser] = useState<any>(null);
+  const [tags, setTags] = useState<{ tag: string; count: number }[]>([]);
+  const [selectedTags, setSelectedTags] = useState<string[]>([]);
+  const [difficultyMin, setDifficultyMin] = useState('');
+  const [difficultyMax, setDifficultyMax] = useState('');
+  const [solved, setSolved] = useState('');
+  const [sort, setSort] = useState('newest');
+  const [page, setPage] = useState(1);
+  const [total, setTotal] = useState(0);
+  const pageSize = 20;
 
   useEffect(() => {
     const userData = localStorage.getItem('user');
@@ -33,6 +42,32 @@ function ProblemsContent() {
     }
   }, [searchParams]);
 
+  useEffect(() => {
+    const token = localStorage.getItem('token');
+    fetch(`${API_URL}/problems/tags`, { headers: token ? { 'Authorization': `Bearer ${token}` } : {} })
+      .then((res) => res.json())
+      .then(setTags)
+      .catch(console.error);
+  }, []);
+
+  // Any change of the filters starts from the first page
+  useEffect(() => {
+    setPage(1);
+  }, [debouncedSearch, filter, selectedTags, difficultyMin, difficultyMax, solved, sort]);
+
+  const toggleTag = (tag: string) => {
+    setSelectedTags(selectedTags.includes(tag) ? selectedTags.filter((t) => t !== tag) : [...selectedTags, tag]);
+  };
+
+  const resetFilters = () => {
+    setSearch('');
+    setFilter('all');
+    setSelectedTags([]);
+    setDifficultyMin('');
+    setDifficultyMax('');
+    setSolved('');
+  };
+
   useEffect(() => {
     const timer = setTimeout(() => setDebouncedSearch(search), 500);
     return () => clearTimeout(timer);
@@ -45,24 +80,43 @@ function ProblemsContent() {
       headers['Authorization'] = `Bearer ${token}`;
     }
 
-    let url = `${API_URL}/problems?filter=${filter}`;
+    let url = `${API_URL}/problems?filter=${filter}&sort=${sort}&page=${page}&page_size=${pageSize}`;
     if (debouncedSearch) {
       url += `&search=${encodeURIComponent(debouncedSearch)}`;
     }
+    if (selectedTags.length > 0) {
+      url += `&tags=${encodeURIComponent(selectedTags.join(','))}`;
+    }
+    if (difficultyMin) {
+      url += `&difficulty_min=${difficultyMin}`;
+    }
+    if (difficultyMax) {
+      url += `&difficulty_max=${difficultyMax}`;
+    }
+    if (solved) {
+      url += `&solved=${solved}`;
+    }
 
     setLoading(true);
     fetch(url, { headers })
       .then((res) => {
         if (res.status === 401) {
           setFilter('all');
-          return [];
+          setSolved('');
+          return { problems: [], total: 0 };
         }
         return res.json();
       })
-      .then(setProblems)
+      .then((data) => {
+        setProblems(data.problems || []);
+        setTotal(data.total || 0);
+      })
       .catch(console.error)
       .finally(() => setLoading(false));
-  }, [debouncedSearch, filter]);
+  }, [debouncedSearch, filter, selectedTags, difficultyMin, difficultyMax, solved, sort, page]);
+
+  const pages = Math.max(1, Math.ceil(total / pageSize));
+  const filtered = search || filter !== 'all' || selectedTags.length > 0 || difficultyMin || difficultyMax || solved;
 
   return (
     <div className="max-w-7xl mx-auto py-10 px-4">
@@ -122,6 +176,74 @@ function ProblemsContent() {
         </div>
       </div>
 
+      {/* Faceted Filters */}
+      <div className="bg-white border border-gray-200 rounded-lg p-4 mb-6 space-y-4">
+        {tags.length > 0 && (
+          <div className="flex flex-wrap gap-2">
+            {tags.map(({ tag, count }) => (
+              <button
+                key={tag}
+                onClick={() => toggleTag(tag)}
+                className={`text-xs px-3 py-1 rounded-full border transition ${
+                  selectedTags.includes(tag)
+                    ? 'bg-blue-600 text-white border-blue-600'
+                    : 'bg-gray-50 text-gray-700 border-gray-200 hover:border-blue-300'
+                }`}
+              >
+                {tag} <span className="opacity-60">{count}</span>
+              </button>
+            ))}
+          </div>
+        )}
+        <div className="flex flex-wrap items-center gap-4 text-sm">
+          <div className="flex items-center gap-2">
+            <span className="text-gray-500">Сложность</span>
+            <input
+              type="number"
+              min={800}
+              max={3500}
+              step={100}
+              placeholder="от"
+              className="w-24 px-2 py-1.5 border rounded-lg outline-none focus:ring-2 focus:ring-blue-500"
+              value={difficultyMin}
+              onChange={(e) => setDifficultyMin(e.target.value)}
+            />
+            <span className="text-gray-400">—</span>
+            <input
+              type="number"
+              min={800}
+              max={3500}
+              step={100}
+              placeholder="до"
+              className="w-24 px-2 py-1.5 border rounded-lg outline-none focus:ring-2 focus:ring-blue-500"
+              value={difficultyMax}
+              onChange={(e) => setDifficultyMax(e.target.value)}
+            />
+          </div>
+          {user && (
+            <select
+              className="px-2 py-1.5 border rounded-lg outline-none focus:ring-2 focus:ring-blue-500 bg-white"
+              value={solved}
+              onChange={(e) => setSolved(e.target.value)}
+            >
+              <option value="">Решённые и нерешённые</option>
+              <option value="true">Только решённые</option>
+              <option value="false">Только нерешённые</option>
+            </select>
+          )}
+          <select
+            className="px-2 py-1.5 border rounded-lg outline-none focus:ring-2 focus:ring-blue-500 bg-white"
+            value={sort}
+            onChange={(e) => setSort(e.target.value)}
+          >
+            <option value="newest">Сначала новые</option>
+            <option value="difficulty">По сложности</option>
+            <option value="solved">По числу решивших</option>
+          </select>
+          <span className="text-gray-500 ml-auto">Найдено: {total}</span>
+        </div>
+      </div>
+
       {loading ? (
         <div className="text-center py-20">
           <div className="animate-spin rounded-full h-12 w-12 border-b-2 border-blue-600 mx-auto"></div>
@@ -129,8 +251,8 @@ function ProblemsContent() {
       ) : problems.length === 0 ? (
         <div className="text-center py-20 bg-white rounded-xl border border-dashed border-gray-300">
           <p className="text-gray-500 text-lg">Задачи не найдены.</p>
-          {(search || filter !== 'all') && (
-            <button onClick={() => { setSearch(''); setFilter('all'); }} className="text-blue-600 mt-2 hover:underline">
+          {filtered && (
+            <button onClick={resetFilters} className="text-blue-600 mt-2 hover:underline">
               Сбросить фильтры
             </button>
           )}
@@ -145,6 +267,9 @@ function ProblemsContent() {
                     <h3 className="text-lg font-semibold text-gray-900 group-hover:text-blue-600 transition">
                       {problem.title}
                     </h3>
+                    {problem.solved && (
+                      <span className="text-xs px-2 py-0.5 rounded-full border bg-green-50 text-green-700 border-green-200">Решено</span>
+                    )}
                     {problem.visibility !== 'public' && (
                       <span className={`text-xs px-2 py-0.5 rounded-full border ${
                         problem.visibility === 'private' 
@@ -158,9 +283,19 @@ function ProblemsContent() {
                   <p className="text-sm text-gray-500 line-clamp-1 max-w-2xl">
                     {problem.description}
                   </p>
+                  {problem.tags?.length > 0 && (
+                    <div className="flex flex-wrap gap-1 mt-2">
+                      {problem.tags.map((tag: string) => (
+                        <span key={tag} className="text-xs px-2 py-0.5 rounded bg-gray-100 text-gray-600">{tag}</span>
+                      ))}
+                    </div>
+                  )}
                 </div>
                 
                 <div className="flex items-center gap-6 text-sm text-gray-500 w-full sm:w-auto justify-between sm:justify-end">
+                  <div className="flex items-center gap-1 font-medium" title="Сложность">
+                    {problem.difficulty > 0 ? problem.difficulty : '—'}
+                  </div>
                   <div className="flex items-center gap-1" title="Решили">
                     <svg className="w-4 h-4 text-green-500" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path strokeLinecap="round" strokeLinejoin="round" strokeWidth="2" d="M5 13l4 4L19 7"></path></svg>
                     <span>{problem.solved_count || 0}</span>
@@ -179,6 +314,26 @@ function ProblemsContent() {
           ))}
         </div>
       )}
+
+      {!loading && pages > 1 && (
+        <div className="flex justify-center items-center gap-4 mt-8 text-sm">
+          <button
+            onClick={() => setPage(page - 1)}
+            disabled={page <= 1}
+            className="px-4 py-2 border rounded-lg bg-white hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed"
+          >
+            Назад
+          </button>
+          <span className="text-gray-600">Страница {page} из {pages}</span>
+          <button
+            onClick={() => setPage(page + 1)}
+            disabled={page >= pages}
+            className="px-4 py-2 border rounded-lg bg-white hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed"
+          >
+            Вперёд
+          </button>
+        </div>
+      )}
     </div>
   );
 }
@@ -0,0 +1,63 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import { API_URL } from '@/lib/api';
+
+export interface Language {
+  name: string;
+  title: string;
+  editor: string;
+}
+
+// Loaded once per page load and shared by every select on the page
+let languagesPromise: Promise<Language[]> | null = null;
+
+function loadLanguages() {
+  if (!languagesPromise) {
+    languagesPromise = fetch(`${API_URL}/languages`)
+      .then((res) => (res.ok ? res.json() : []))
+      .catch(() => {
+        languagesPromise = null;
+        return [];
+      });
+  }
+  return languagesPromise;
+}
+
+export function useLanguages() {
+  const [languages, setLanguages] = useState<Language[]>([]);
+
+  useEffect(() => {
+    loadLanguages().then(setLanguages);
+  }, []);
+
+  return languages;
+}
+
+// Monaco language id for a registry language
+export function editorLanguage(languages: Language[], name: string) {
+  return languages.find((l) => l.name === name)?.editor || name;
+}
+
+export default function LanguageSelect({
+  value,
+  onChange,
+  languages,
+  className,
+}: {
+  value: string;
+  onChange: (value: string) => void;
+  languages: Language[];
+  className?: string;
+}) {
+  return (
+    <select value={value} onChange={(e) => onChange(e.target.value)} className={className}>
+      {languages.length === 0 && <option value={value}>{value}</option>}
+      {languages.map((l) => (
+        <option key={l.name} value={l.name}>
+          {l.title}
+        </option>
+      ))}
+    </select>
+  );
+}
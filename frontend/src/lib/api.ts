@@ -25,8 +25,9 @@ function getAuthHeaders() {
   return headers;
 }
 
-export async function getProblems() {
-  const url = `${getBaseUrl()}/problems`;
+// Returns a page of problems: { problems, total, page, page_size }
+export async function getProblems(query = '') {
+  const url = `${getBaseUrl()}/problems${query ? `?${query}` : ''}`;
   console.log('Fetching problems from:', url); // Debug log
   
   const res = await fetch(url, {
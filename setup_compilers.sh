@@ -26,7 +26,8 @@ check_and_install() {
 # 1. Python (Көбүнчө орнотулган болот)
 check_and_install python3 python3
 
-# 2. C++ (G++)
+# 2. C жана C++ (GCC)
+check_and_install gcc gcc
 check_and_install g++ g++
 
 # 3. Java (Default JDK)
@@ -54,4 +55,16 @@ else
     echo -e "${GREEN}✔ Go ийгиликтүү орнотулду!${NC}"
 fi
 
+# 6. PyPy
+check_and_install pypy3 pypy3
+
+# 7. Rust
+check_and_install rustc rustc
+
+# 8. C# (Mono)
+check_and_install mono-devel mcs
+
+# 9. Kotlin
+check_and_install kotlin kotlinc
+
 echo -e "\n${GREEN}Бардык текшерүүлөр бүттү!${NC}"